@@ -3,6 +3,7 @@ package asana
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -88,8 +89,14 @@ func (asana *Client) SetWorkspace(workspace string) {
 }
 
 func (asana *Client) Request(method string, uri string, strbody string) ([]byte, error) {
+	return asana.RequestContext(context.Background(), method, uri, strbody)
+}
+
+// RequestContext is Request, cancelled when `ctx` is done. The other
+// methods have Context variants as well.
+func (asana *Client) RequestContext(ctx context.Context, method string, uri string, strbody string) ([]byte, error) {
 
-	req, err := http.NewRequest(method, uri, bytes.NewBufferString(strbody))
+	req, err := http.NewRequestWithContext(ctx, method, uri, bytes.NewBufferString(strbody))
 
 	if method == "PUT" {
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -132,6 +139,10 @@ func (asana *Client) Request(method string, uri string, strbody string) ([]byte,
 }
 
 func (asana *Client) GetTasksByAssignee(user User) ([]Task, error) {
+	return asana.GetTasksByAssigneeContext(context.Background(), user)
+}
+
+func (asana *Client) GetTasksByAssigneeContext(ctx context.Context, user User) ([]Task, error) {
 	var data struct {
 		Data []Task
 	}
@@ -140,7 +151,7 @@ func (asana *Client) GetTasksByAssignee(user User) ([]Task, error) {
 	url := fmt.Sprintf("https://app.asana.com/api/1.0/workspaces/%s/tasks?assignee=%v",
 		workspace, user.ID)
 
-	body, err := asana.Request("GET", url, "")
+	body, err := asana.RequestContext(ctx, "GET", url, "")
 	if err != nil {
 		log.WithError(err).Error()
 		return nil, err
@@ -152,13 +163,17 @@ func (asana *Client) GetTasksByAssignee(user User) ([]Task, error) {
 }
 
 func (asana *Client) GetTasksByTag(tagID string) ([]Task, error) {
+	return asana.GetTasksByTagContext(context.Background(), tagID)
+}
+
+func (asana *Client) GetTasksByTagContext(ctx context.Context, tagID string) ([]Task, error) {
 	var data struct {
 		Data []Task
 	}
 
 	url := fmt.Sprintf("https://app.asana.com/api/1.0/tags/%s/tasks", tagID)
 
-	body, err := asana.Request("GET", url, "")
+	body, err := asana.RequestContext(ctx, "GET", url, "")
 	if err != nil {
 		log.WithError(err).Error()
 		return nil, err
@@ -170,13 +185,17 @@ func (asana *Client) GetTasksByTag(tagID string) ([]Task, error) {
 }
 
 func (asana *Client) GetTaskStories(taskID int64) ([]Story, error) {
+	return asana.GetTaskStoriesContext(context.Background(), taskID)
+}
+
+func (asana *Client) GetTaskStoriesContext(ctx context.Context, taskID int64) ([]Story, error) {
 	var data struct {
 		Data []Story
 	}
 
 	url := fmt.Sprintf("https://app.asana.com/api/1.0/tasks/%v/stories", taskID)
 
-	body, err := asana.Request("GET", url, "")
+	body, err := asana.RequestContext(ctx, "GET", url, "")
 	if err != nil {
 		log.WithError(err).Error()
 		return nil, err
@@ -188,13 +207,17 @@ func (asana *Client) GetTaskStories(taskID int64) ([]Story, error) {
 }
 
 func (asana *Client) GetUser(userID int64) (*User, error) {
+	return asana.GetUserContext(context.Background(), userID)
+}
+
+func (asana *Client) GetUserContext(ctx context.Context, userID int64) (*User, error) {
 	var data struct {
 		Data User
 	}
 
 	url := fmt.Sprintf("https://app.asana.com/api/1.0/users/%v", userID)
 
-	body, err := asana.Request("GET", url, "")
+	body, err := asana.RequestContext(ctx, "GET", url, "")
 	if err != nil {
 		log.WithError(err).Error()
 		return nil, err
@@ -206,13 +229,17 @@ func (asana *Client) GetUser(userID int64) (*User, error) {
 }
 
 func (asana *Client) GetUsers() ([]User, error) {
+	return asana.GetUsersContext(context.Background())
+}
+
+func (asana *Client) GetUsersContext(ctx context.Context) ([]User, error) {
 	var data struct {
 		Data []User
 	}
 
 	url := "https://app.asana.com/api/1.0/users/"
 
-	body, err := asana.Request("GET", url, "")
+	body, err := asana.RequestContext(ctx, "GET", url, "")
 	if err != nil {
 		log.WithError(err).Error()
 		return nil, err
@@ -224,13 +251,17 @@ func (asana *Client) GetUsers() ([]User, error) {
 }
 
 func (asana *Client) GetTaskByID(taskID int64) (*Task, error) {
+	return asana.GetTaskByIDContext(context.Background(), taskID)
+}
+
+func (asana *Client) GetTaskByIDContext(ctx context.Context, taskID int64) (*Task, error) {
 	var data struct {
 		Data Task
 	}
 
 	url := fmt.Sprintf("https://app.asana.com/api/1.0/tasks/%v", taskID)
 
-	body, err := asana.Request("GET", url, "")
+	body, err := asana.RequestContext(ctx, "GET", url, "")
 	if err != nil {
 		log.WithError(err).Error()
 		return nil, err
@@ -244,13 +275,17 @@ func (asana *Client) GetTaskByID(taskID int64) (*Task, error) {
 }
 
 func (asana *Client) GetTagsOnTask(tagID int64) ([]Tag, error) {
+	return asana.GetTagsOnTaskContext(context.Background(), tagID)
+}
+
+func (asana *Client) GetTagsOnTaskContext(ctx context.Context, tagID int64) ([]Tag, error) {
 	var data struct {
 		Data []Tag
 	}
 
 	url := fmt.Sprintf("https://app.asana.com/api/1.0/tasks/%v/tags", tagID)
 
-	body, err := asana.Request("GET", url, "")
+	body, err := asana.RequestContext(ctx, "GET", url, "")
 	if err != nil {
 		log.WithError(err).Error()
 		return nil, err
@@ -262,6 +297,10 @@ func (asana *Client) GetTagsOnTask(tagID int64) ([]Tag, error) {
 }
 
 func (asana *Client) UpdateTask(updateStr string, task Task) (*Task, error) {
+	return asana.UpdateTaskContext(context.Background(), updateStr, task)
+}
+
+func (asana *Client) UpdateTaskContext(ctx context.Context, updateStr string, task Task) (*Task, error) {
 
 	var data struct {
 		Data Task
@@ -269,7 +308,7 @@ func (asana *Client) UpdateTask(updateStr string, task Task) (*Task, error) {
 
 	url := fmt.Sprintf("https://app.asana.com/api/1.0/tasks/%v", task.ID)
 
-	body, err := asana.Request("PUT", url, updateStr)
+	body, err := asana.RequestContext(ctx, "PUT", url, updateStr)
 	if err != nil {
 		log.WithError(err).Error()
 		return nil, err
@@ -0,0 +1,595 @@
+package slick
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// Audit actions recorded by the bot itself.
+const (
+	AuditCommand          = "command"
+	AuditPermissionDenied = "permission_denied"
+	AuditSend             = "send"
+	AuditUpdate           = "update"
+	AuditDelete           = "delete"
+	AuditReactionAdd      = "reaction_add"
+	AuditReactionRemove   = "reaction_remove"
+)
+
+// Audit outcomes.
+const (
+	AuditOK     = "ok"
+	AuditDenied = "denied"
+	AuditFailed = "failed"
+)
+
+// PermissionReadAudit is needed for the `!audit` command. Until the
+// config defines roles, the command only works in the `admin_channel`.
+const PermissionReadAudit = "audit.read"
+
+// AuditPath is the route of the audit log on the WebServer's private
+// router.
+const AuditPath = "/audit"
+
+// auditNamespace is the storage namespace of the audit log.
+const auditNamespace = "audit"
+
+// auditQueueSize is how many entries wait for the audit writer before
+// Audit blocks.
+const auditQueueSize = 1000
+
+// auditDayFormat prefixes the keys of the audit log with their UTC
+// day, so searches only list the days they cover.
+const auditDayFormat = "20060102"
+
+// AuditEntry is a record of the audit log.
+type AuditEntry struct {
+	Time time.Time `json:"time"`
+
+	// Actor is the ID of the user who acted, or the bot's own ID for
+	// the messages it sends, updates, deletes and reacts with.
+	Actor string `json:"actor"`
+
+	// Action is what happened, like AuditCommand or AuditSend.
+	Action string `json:"action"`
+
+	// Command is the name of the command run, without the
+	// CommandPrefix.
+	Command string `json:"command,omitempty"`
+
+	Channel string `json:"channel,omitempty"`
+
+	// Target is what the action is about: the timestamp of a message,
+	// the emoji of a reaction, or the arguments of a command.
+	Target string `json:"target,omitempty"`
+
+	// Outcome is AuditOK, AuditDenied or AuditFailed.
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+
+	RequestID string `json:"request_id,omitempty"`
+
+	// Team is the name of the team the action happened in, empty for
+	// the primary team.
+	Team string `json:"team,omitempty"`
+}
+
+// AuditQuery filters the audit log, see `Bot.SearchAudit`. Empty
+// fields match everything.
+type AuditQuery struct {
+	// Team is the name of the team whose entries are searched, empty
+	// for the primary team. Each team has its own audit log.
+	Team string
+
+	Actor   string
+	Action  string
+	Command string
+	Channel string
+	Outcome string
+	Since   time.Time
+
+	// Limit keeps the most recent entries, 50 if zero.
+	Limit int
+}
+
+func (q AuditQuery) matches(entry *AuditEntry) bool {
+	switch {
+	case q.Actor != "" && entry.Actor != q.Actor,
+		q.Action != "" && entry.Action != q.Action,
+		q.Command != "" && entry.Command != q.Command,
+		q.Channel != "" && entry.Channel != q.Channel,
+		q.Outcome != "" && entry.Outcome != q.Outcome,
+		!q.Since.IsZero() && entry.Time.Before(q.Since):
+		return false
+	}
+	return true
+}
+
+// auditCounter orders the entries recorded in the same nanosecond.
+var auditCounter uint64
+
+// auditWriter appends the entries of the audit log to storage on its
+// own goroutine, so the event loop doesn't wait for the disk.
+type auditWriter struct {
+	once  sync.Once
+	queue chan auditWrite
+}
+
+// auditWrite is an entry to append, or a flush request, closing
+// `flushed` once the entries queued before it are written.
+type auditWrite struct {
+	team    *Team
+	key     string
+	entry   AuditEntry
+	flushed chan struct{}
+}
+
+// Audit appends an entry to the audit log of `entry.Team`, in storage.
+// The time and request ID are filled from `ctx` when missing. The bot
+// records its commands and the messages it sends, updates, deletes and
+// reacts with; plugins can record their own actions.
+//
+// Entries are written in the background, in order. Audit only blocks
+// when auditQueueSize entries are already waiting.
+func (bot *Bot) Audit(ctx context.Context, entry AuditEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.RequestID == "" && ctx != nil {
+		entry.RequestID = RequestID(ctx)
+	}
+
+	if bot.StorageBackend == nil {
+		return
+	}
+	team := bot.TeamByName(entry.Team)
+	if team == nil {
+		team = bot.Team
+	}
+
+	// Keys sort in time order, and are never written again
+	key := fmt.Sprintf("%s/%020d-%08d", entry.Time.UTC().Format(auditDayFormat), entry.Time.UnixNano(), atomic.AddUint64(&auditCounter, 1)%100000000)
+	bot.auditQueue() <- auditWrite{team: team, key: key, entry: entry}
+}
+
+// auditQueue returns the queue of the audit writer, starting it on
+// first use.
+func (bot *Bot) auditQueue() chan auditWrite {
+	bot.audit.once.Do(func() {
+		bot.audit.queue = make(chan auditWrite, auditQueueSize)
+		go bot.writeAudit(bot.audit.queue)
+	})
+	return bot.audit.queue
+}
+
+// writeAudit appends the queued entries, all those waiting in one
+// transaction per team.
+func (bot *Bot) writeAudit(queue chan auditWrite) {
+	for first := range queue {
+		batch := []auditWrite{first}
+	drain:
+		for len(batch) < auditQueueSize {
+			select {
+			case w := <-queue:
+				batch = append(batch, w)
+			default:
+				break drain
+			}
+		}
+
+		var teams []*Team
+		byTeam := make(map[*Team][]auditWrite)
+		var flushes []chan struct{}
+		for _, w := range batch {
+			if w.flushed != nil {
+				flushes = append(flushes, w.flushed)
+				continue
+			}
+			if byTeam[w.team] == nil {
+				teams = append(teams, w.team)
+			}
+			byTeam[w.team] = append(byTeam[w.team], w)
+		}
+
+		for _, team := range teams {
+			writes := byTeam[team]
+			err := team.Storage(auditNamespace).Update(func(tx StoreTx) error {
+				for _, w := range writes {
+					if err := tx.Put(w.key, w.entry); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				log.WithFields(log.Fields{
+					"Type":    "Audit",
+					"Team":    team.Name,
+					"Entries": len(writes),
+				}).WithError(err).Error("Couldn't write to the audit log.")
+			}
+		}
+
+		for _, flushed := range flushes {
+			close(flushed)
+		}
+	}
+}
+
+// flushAudit waits for the entries recorded so far to be written, or
+// for `ctx` to be done.
+func (bot *Bot) flushAudit(ctx context.Context) error {
+	if bot.StorageBackend == nil {
+		return nil
+	}
+	flushed := make(chan struct{})
+	select {
+	case bot.auditQueue() <- auditWrite{flushed: flushed}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// auditRetention returns how long the entries of the audit log are
+// kept, zero for ever.
+func (bot *Bot) auditRetention() time.Duration {
+	return time.Duration(bot.Config().AuditRetentionDays) * 24 * time.Hour
+}
+
+// scheduleAuditRetention schedules the daily removal of the entries
+// older than `audit_retention_days`, when set.
+func (bot *Bot) scheduleAuditRetention() {
+	bot.Scheduler.Cron("audit-retention", "@daily", func() {
+		retention := bot.auditRetention()
+		if retention <= 0 {
+			return
+		}
+		for _, team := range bot.Teams() {
+			if err := team.purgeAudit(time.Now().Add(-retention)); err != nil {
+				log.WithFields(log.Fields{
+					"Type": "Audit",
+					"Team": team.Name,
+				}).WithError(err).Error("Couldn't remove the old entries of the audit log.")
+			}
+		}
+	})
+}
+
+// purgeAudit removes the entries of the days before `oldest`.
+func (team *Team) purgeAudit(oldest time.Time) error {
+	firstKept := oldest.UTC().Format(auditDayFormat)
+	return team.Storage(auditNamespace).Update(func(tx StoreTx) error {
+		var old []string
+		err := tx.List("", func(key string, value StoredValue) error {
+			if key < firstKept {
+				old = append(old, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range old {
+			if err := tx.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// auditMessage records an action of the bot on a message of the team.
+func (team *Team) auditMessage(ctx context.Context, action, channel, target string, err error) {
+	entry := AuditEntry{
+		Actor:   team.Myself.ID,
+		Action:  action,
+		Channel: channel,
+		Target:  target,
+		Outcome: AuditOK,
+		Team:    team.Name,
+	}
+	if err != nil {
+		entry.Outcome = AuditFailed
+		entry.Error = err.Error()
+	}
+	team.bot.Audit(ctx, entry)
+}
+
+// SearchAudit returns the most recent entries of the audit log of
+// `query.Team` matching `query`, oldest first, once the entries
+// recorded so far are written. With `query.Since` or a retention, only
+// the days they cover are listed, most recent first, until
+// `query.Limit` entries are found.
+func (bot *Bot) SearchAudit(query AuditQuery) ([]AuditEntry, error) {
+	team := bot.TeamByName(query.Team)
+	if team == nil {
+		return nil, fmt.Errorf("no team %q", query.Team)
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	if err := bot.flushAudit(bot.ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var oldest time.Time
+	if retention := bot.auditRetention(); retention > 0 {
+		oldest = now.Add(-retention)
+	}
+	if query.Since.After(oldest) {
+		oldest = query.Since.UTC()
+	}
+
+	store := team.Storage(auditNamespace)
+	var entries []AuditEntry
+	collect := func(found *[]AuditEntry) func(string, StoredValue) error {
+		return func(key string, value StoredValue) error {
+			var entry AuditEntry
+			if err := value.Decode(&entry); err != nil {
+				return err
+			}
+			if query.matches(&entry) {
+				*found = append(*found, entry)
+			}
+			return nil
+		}
+	}
+
+	// Without a start, the whole log is searched
+	if oldest.IsZero() {
+		err := store.List("", collect(&entries))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	firstDay := time.Date(oldest.Year(), oldest.Month(), oldest.Day(), 0, 0, 0, 0, time.UTC)
+	for day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC); !oldest.IsZero() && !day.Before(firstDay) && len(entries) < limit; day = day.AddDate(0, 0, -1) {
+		var dayEntries []AuditEntry
+		if err := store.List(day.Format(auditDayFormat)+"/", collect(&dayEntries)); err != nil {
+			return nil, err
+		}
+		entries = append(dayEntries, entries...)
+	}
+
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, nil
+}
+
+// auditTransport records the actions of the bot on messages in the
+// audit log, and counts their errors in the metrics. Sent messages are
+// recorded when acknowledged, see `Bot.auditReply`.
+type auditTransport struct {
+	Transport
+	team *Team
+}
+
+func (t *auditTransport) UpdateMessage(ctx context.Context, channel, timestamp, text string) error {
+	err := t.team.bot.countAPICall("chat.update", t.Transport.UpdateMessage(ctx, channel, timestamp, text))
+	t.team.auditMessage(ctx, AuditUpdate, channel, timestamp, err)
+	return err
+}
+
+func (t *auditTransport) DeleteMessage(ctx context.Context, channel, timestamp string) error {
+	err := t.team.bot.countAPICall("chat.delete", t.Transport.DeleteMessage(ctx, channel, timestamp))
+	t.team.auditMessage(ctx, AuditDelete, channel, timestamp, err)
+	return err
+}
+
+func (t *auditTransport) AddReaction(ctx context.Context, name string, item slack.ItemRef) error {
+	err := t.team.bot.countAPICall("reactions.add", t.Transport.AddReaction(ctx, name, item))
+	t.team.auditMessage(ctx, AuditReactionAdd, item.Channel, name+" "+item.Timestamp, err)
+	return err
+}
+
+func (t *auditTransport) RemoveReaction(ctx context.Context, name string, item slack.ItemRef) error {
+	err := t.team.bot.countAPICall("reactions.remove", t.Transport.RemoveReaction(ctx, name, item))
+	t.team.auditMessage(ctx, AuditReactionRemove, item.Channel, name+" "+item.Timestamp, err)
+	return err
+}
+
+func (t *auditTransport) OpenIMChannel(ctx context.Context, user string) (string, error) {
+	channelID, err := t.Transport.OpenIMChannel(ctx, user)
+	return channelID, t.team.bot.countAPICall("im.open", err)
+}
+
+// auditReply records a sent message, with the timestamp Slack gave it,
+// or the error which made it fail.
+func (team *Team) auditReply(r *Reply, timestamp string, err error) {
+	team.auditMessage(team.bot.ctx, AuditSend, r.Channel, timestamp, err)
+}
+
+// auditCommand records a command run, or denied.
+func (bot *Bot) auditCommand(msg *Message, cmd *Command, args, outcome string, err error) {
+	entry := AuditEntry{
+		Action:  AuditCommand,
+		Command: cmd.Name,
+		Channel: msg.Channel,
+		Target:  args,
+		Outcome: outcome,
+		Team:    msg.Workspace().Name,
+	}
+	if msg.FromUser != nil {
+		entry.Actor = msg.FromUser.ID
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	bot.Audit(msg.Context(), entry)
+}
+
+// listenAuditCommand registers the `!audit` command.
+func (bot *Bot) listenAuditCommand() {
+	bot.Command(&Command{
+		Name:       "audit",
+		Args:       []CommandArg{{Name: "filters", Type: ArgRest, Optional: true}},
+		Usage:      "searches the audit log, with filters like `user:@bob action:command command:todo channel:#general outcome:denied since:2h limit:20`",
+		Permission: PermissionReadAudit,
+		// The audit log holds the commands run in private
+		Restricted:  true,
+		HandlerFunc: bot.searchAuditCommand,
+	})
+}
+
+// parseAuditQuery builds an AuditQuery on the audit log of the team
+// from filters like "user" => "@bob", from the `!audit` command or the
+// query string of AuditPath.
+func (team *Team) parseAuditQuery(filters map[string]string) (AuditQuery, error) {
+	query := AuditQuery{Team: team.Name}
+	for key, value := range filters {
+		switch key {
+		case "user", "actor":
+			user, err := team.parseCommandArg(CommandArg{Name: key, Type: ArgUser}, value)
+			if err != nil {
+				return query, err
+			}
+			query.Actor = user.(*slack.User).ID
+		case "channel":
+			channel, err := team.parseCommandArg(CommandArg{Name: key, Type: ArgChannel}, value)
+			if err != nil {
+				return query, err
+			}
+			query.Channel = channel.(*Channel).ID
+		case "action":
+			query.Action = value
+		case "command":
+			query.Command = strings.TrimPrefix(value, CommandPrefix)
+		case "outcome":
+			query.Outcome = value
+		case "since":
+			if d, err := time.ParseDuration(value); err == nil {
+				query.Since = time.Now().Add(-d)
+			} else if t, err := time.Parse(time.RFC3339, value); err == nil {
+				query.Since = t
+			} else {
+				return query, fmt.Errorf("invalid since %q (ex: 2h, or 2006-01-02T15:04:05Z)", value)
+			}
+		case "limit":
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 0 {
+				return query, fmt.Errorf("invalid limit %q", value)
+			}
+			query.Limit = limit
+		default:
+			return query, fmt.Errorf("unknown filter %q", key)
+		}
+	}
+	return query, nil
+}
+
+func (bot *Bot) searchAuditCommand(cmd *Command, msg *Message, args CommandArgs) {
+	filters := make(map[string]string)
+	for _, word := range strings.Fields(args.String("filters")) {
+		parts := strings.SplitN(word, ":", 2)
+		if len(parts) != 2 {
+			msg.ReplyMention("invalid filter %q, use `name:value`\nusage: `%s`", word, cmd.Synopsis())
+			return
+		}
+		filters[parts[0]] = parts[1]
+	}
+	if _, ok := filters["limit"]; !ok {
+		filters["limit"] = "20"
+	}
+
+	query, err := msg.Workspace().parseAuditQuery(filters)
+	if err != nil {
+		msg.ReplyMention("%s", err)
+		return
+	}
+	entries, err := bot.SearchAudit(query)
+	if err != nil {
+		msg.ReplyMention("couldn't search the audit log: %s", err)
+		return
+	}
+	if len(entries) == 0 {
+		msg.ReplyMention("nothing in the audit log")
+		return
+	}
+
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		line := fmt.Sprintf("%s %s %s", entry.Time.Format("2006-01-02 15:04:05"), MentionUser(entry.Actor), entry.Action)
+		if entry.Command != "" {
+			line += " " + CommandPrefix + entry.Command
+		}
+		if entry.Target != "" {
+			line += " " + entry.Target
+		}
+		if entry.Channel != "" {
+			line += " in " + LinkChannel(entry.Channel)
+		}
+		line += ": " + entry.Outcome
+		if entry.Error != "" {
+			line += " (" + entry.Error + ")"
+		}
+		lines = append(lines, line)
+	}
+	msg.Reply(strings.Join(lines, "\n"))
+}
+
+// initAuditRoutes mounts the audit log on the WebServer's private
+// router.
+func (bot *Bot) initAuditRoutes() {
+	if bot.WebServer == nil {
+		return
+	}
+	bot.WebServer.PrivateRouter().HandleFunc(AuditPath, bot.handleAudit).Methods("GET")
+}
+
+// handleAudit returns the entries of the audit log as JSON, filtered
+// like the `!audit` command, with the filters in the query string. The
+// `team` filter picks the audit log of a team of the Teams section.
+func (bot *Bot) handleAudit(w http.ResponseWriter, r *http.Request) {
+	filters := make(map[string]string)
+	for key := range r.URL.Query() {
+		filters[key] = r.URL.Query().Get(key)
+	}
+
+	team := bot.TeamByName(filters["team"])
+	if team == nil {
+		http.Error(w, fmt.Sprintf("no team %q", filters["team"]), http.StatusBadRequest)
+		return
+	}
+	delete(filters, "team")
+
+	query, err := team.parseAuditQuery(filters)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entries, err := bot.SearchAudit(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []AuditEntry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Entries []AuditEntry `json:"entries"`
+	}{entries})
+}
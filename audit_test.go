@@ -0,0 +1,173 @@
+package slick
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLog(t *testing.T) {
+	bot := newCommandTestBot()
+	bot.StorageBackend = NewMemoryStorage()
+	bot.Myself = slack.UserDetails{ID: "USLICK"}
+	bot.Transport = &auditTransport{Transport: newFakeTransport(), team: bot.Team}
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	bot.Transport.AddReaction(ctx, "tada", slack.NewRefToMessage("C1", "1.000"))
+	bot.Transport.DeleteMessage(ctx, "C1", "2.000")
+
+	var ran bool
+	bot.Command(&Command{
+		Name:        "deploy",
+		Args:        []CommandArg{{Name: "env", Type: ArgWord}},
+		HandlerFunc: func(*Command, *Message, CommandArgs) { ran = true },
+	})
+	msg := &Message{Msg: &slack.Msg{Text: "!deploy prod", Channel: "C1"}, team: bot.Team}
+	msg.FromUser = &slack.User{ID: "U1", Name: "bob"}
+	bot.runCommand(bot.GetCommand("deploy"), msg, "prod")
+	assert.True(t, ran)
+
+	entries, err := bot.SearchAudit(AuditQuery{})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, AuditReactionAdd, entries[0].Action)
+		assert.Equal(t, "tada 1.000", entries[0].Target)
+		assert.Equal(t, "USLICK", entries[0].Actor)
+		assert.Equal(t, "req-1", entries[0].RequestID)
+		assert.Equal(t, AuditDelete, entries[1].Action)
+		assert.Equal(t, AuditEntry{
+			Time:    entries[2].Time,
+			Actor:   "U1",
+			Action:  AuditCommand,
+			Command: "deploy",
+			Channel: "C1",
+			Target:  "prod",
+			Outcome: AuditOK,
+		}, entries[2])
+	}
+
+	bot.setRoles(map[string]Role{"deployers": {Permissions: []string{"deploy"}, Users: []string{"UALICE"}}})
+	bot.GetCommand("deploy").Permission = "deploy"
+	ran = false
+	bot.runCommand(bot.GetCommand("deploy"), msg, "prod")
+	assert.False(t, ran)
+
+	entries, err = bot.SearchAudit(AuditQuery{Actor: "U1", Outcome: AuditDenied})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	entries, err = bot.SearchAudit(AuditQuery{Limit: 2})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, AuditOK, entries[0].Outcome, "the most recent entries are kept")
+		assert.Equal(t, AuditDenied, entries[1].Outcome)
+	}
+
+	entries, err = bot.SearchAudit(AuditQuery{Since: time.Now().Add(time.Hour)})
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	query, err := bot.Team.parseAuditQuery(map[string]string{"user": "@bob", "channel": "#general", "command": "!deploy", "limit": "5"})
+	assert.NoError(t, err)
+	assert.Equal(t, AuditQuery{Actor: "U1", Channel: "C1", Command: "deploy", Limit: 5}, query)
+	_, err = bot.Team.parseAuditQuery(map[string]string{"color": "blue"})
+	assert.Error(t, err)
+
+	w := httptest.NewRecorder()
+	bot.handleAudit(w, httptest.NewRequest("GET", AuditPath+"?action=command&outcome=denied", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), `"outcome":"denied"`), w.Body.String())
+
+	w = httptest.NewRecorder()
+	bot.handleAudit(w, httptest.NewRequest("GET", AuditPath+"?since=tomorrow", nil))
+	assert.Equal(t, 400, w.Code)
+
+	bot.auditReply(&Reply{OutgoingMessage: &slack.OutgoingMessage{Channel: "C1"}}, "", errors.New("channel_not_found"))
+	entries, err = bot.SearchAudit(AuditQuery{Action: AuditSend})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, AuditFailed, entries[0].Outcome)
+		assert.Equal(t, "channel_not_found", entries[0].Error)
+	}
+
+	// Entries are kept for ever, unless a retention is set
+	bot.Audit(context.Background(), AuditEntry{Time: time.Now().Add(-72 * time.Hour), Action: "old"})
+	bot.Audit(context.Background(), AuditEntry{Time: time.Now().AddDate(0, 0, -31), Action: "forgotten"})
+	entries, err = bot.SearchAudit(AuditQuery{Action: "forgotten"})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// Searches list the days they cover, within the retention
+	bot.setConfig(SlackConfig{AuditRetentionDays: 30})
+	entries, err = bot.SearchAudit(AuditQuery{Action: "old"})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	entries, err = bot.SearchAudit(AuditQuery{Action: "old", Since: time.Now().Add(-time.Hour)})
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+	entries, err = bot.SearchAudit(AuditQuery{Action: "forgotten"})
+	assert.NoError(t, err)
+	assert.Empty(t, entries, "entries older than the retention aren't searched")
+
+	assert.NoError(t, bot.Team.purgeAudit(time.Now().AddDate(0, 0, -30)))
+	bot.setConfig(SlackConfig{})
+	entries, err = bot.SearchAudit(AuditQuery{})
+	assert.NoError(t, err)
+	for _, entry := range entries {
+		assert.NotEqual(t, "forgotten", entry.Action, "entries older than the retention are removed")
+	}
+	entries, err = bot.SearchAudit(AuditQuery{Action: "old"})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// Each team has its own audit log
+	bot.teams = append(bot.teams, newTeam(bot, "acme"))
+	bot.Audit(context.Background(), AuditEntry{Action: "elsewhere", Team: "acme"})
+	entries, err = bot.SearchAudit(AuditQuery{Action: "elsewhere"})
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+	entries, err = bot.SearchAudit(AuditQuery{Action: "elsewhere", Team: "acme"})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	_, err = bot.SearchAudit(AuditQuery{Team: "globex"})
+	assert.Error(t, err)
+
+	w = httptest.NewRecorder()
+	bot.handleAudit(w, httptest.NewRequest("GET", AuditPath+"?team=acme&action=elsewhere", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), `"team":"acme"`), w.Body.String())
+}
+
+func TestAuditCommandAccess(t *testing.T) {
+	bot := newCommandTestBot()
+	bot.StorageBackend = NewMemoryStorage()
+	bot.Transport = newFakeTransport()
+	bot.Channels.Set(Channel{ID: "C2", Name: "admin", IsChannel: true})
+	bot.setConfig(SlackConfig{AdminChannel: "admin"})
+	bot.listenAuditCommand()
+
+	run := func(channel string) string {
+		msg := &Message{Msg: &slack.Msg{Text: "!audit", Channel: channel, User: "U1"}, bot: bot, team: bot.Team}
+		msg.resolve()
+		bot.runCommand(bot.GetCommand("audit"), msg, "")
+
+		entries, err := bot.SearchAudit(AuditQuery{Command: "audit", Limit: 1})
+		assert.NoError(t, err)
+		if assert.Len(t, entries, 1) {
+			return entries[0].Outcome
+		}
+		return ""
+	}
+
+	assert.Equal(t, AuditDenied, run("C1"), "without roles, only the admin channel reads the audit log")
+	assert.Equal(t, AuditOK, run("C2"))
+
+	bot.setRoles(map[string]Role{"auditors": {Permissions: []string{PermissionReadAudit}, Users: []string{"U1"}}})
+	assert.Equal(t, AuditOK, run("C1"), "roles grant it anywhere")
+}
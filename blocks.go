@@ -0,0 +1,356 @@
+package slick
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+// Block is a Block Kit layout block, sent with `Bot.SendBlocks` or
+// `Message.ReplyBlocks`. See https://api.slack.com/block-kit
+type Block interface {
+	blockType() string
+}
+
+// BlockElement is an interactive element, placed in an ActionsBlock,
+// as a SectionBlock accessory or in an InputBlock.
+type BlockElement interface {
+	elementType() string
+}
+
+// TextObject is a piece of text in a block, either "mrkdwn" or
+// "plain_text".
+type TextObject struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	Emoji bool   `json:"emoji,omitempty"`
+}
+
+// MarkdownText returns a "mrkdwn" TextObject.
+func MarkdownText(text string) *TextObject {
+	return &TextObject{Type: "mrkdwn", Text: text}
+}
+
+// PlainText returns a "plain_text" TextObject.
+func PlainText(text string) *TextObject {
+	return &TextObject{Type: "plain_text", Text: text, Emoji: true}
+}
+
+// OptionObject is one of the choices of a StaticSelectElement.
+type OptionObject struct {
+	Text  *TextObject `json:"text"`
+	Value string      `json:"value"`
+}
+
+// NewOption returns an OptionObject displaying `text`, and sending
+// `value` when picked.
+func NewOption(text, value string) *OptionObject {
+	return &OptionObject{Text: PlainText(text), Value: value}
+}
+
+// SectionBlock displays text, with an optional accessory element.
+type SectionBlock struct {
+	BlockID   string        `json:"block_id,omitempty"`
+	Text      *TextObject   `json:"text,omitempty"`
+	Fields    []*TextObject `json:"fields,omitempty"`
+	Accessory BlockElement  `json:"accessory,omitempty"`
+}
+
+// DividerBlock is a horizontal line.
+type DividerBlock struct {
+	BlockID string `json:"block_id,omitempty"`
+}
+
+// ImageBlock displays an image.
+type ImageBlock struct {
+	BlockID  string      `json:"block_id,omitempty"`
+	ImageURL string      `json:"image_url"`
+	AltText  string      `json:"alt_text"`
+	Title    *TextObject `json:"title,omitempty"`
+}
+
+// ContextBlock displays small text and images. Elements are
+// `*TextObject`s or `*ImageElement`s.
+type ContextBlock struct {
+	BlockID  string        `json:"block_id,omitempty"`
+	Elements []interface{} `json:"elements"`
+}
+
+// ActionsBlock holds interactive elements, like buttons.
+type ActionsBlock struct {
+	BlockID  string         `json:"block_id,omitempty"`
+	Elements []BlockElement `json:"elements"`
+}
+
+// InputBlock collects input in a modal.
+type InputBlock struct {
+	BlockID  string       `json:"block_id,omitempty"`
+	Label    *TextObject  `json:"label"`
+	Element  BlockElement `json:"element"`
+	Hint     *TextObject  `json:"hint,omitempty"`
+	Optional bool         `json:"optional,omitempty"`
+}
+
+func (SectionBlock) blockType() string { return "section" }
+func (DividerBlock) blockType() string { return "divider" }
+func (ImageBlock) blockType() string   { return "image" }
+func (ContextBlock) blockType() string { return "context" }
+func (ActionsBlock) blockType() string { return "actions" }
+func (InputBlock) blockType() string   { return "input" }
+
+// ButtonElement is a button. Clicks are dispatched to
+// InteractionListeners, with the button's ActionID and Value.
+type ButtonElement struct {
+	ActionID string      `json:"action_id,omitempty"`
+	Text     *TextObject `json:"text"`
+	Value    string      `json:"value,omitempty"`
+	URL      string      `json:"url,omitempty"`
+	// Style is empty, "primary" or "danger".
+	Style string `json:"style,omitempty"`
+}
+
+// StaticSelectElement is a drop-down menu with static options.
+type StaticSelectElement struct {
+	ActionID      string          `json:"action_id,omitempty"`
+	Placeholder   *TextObject     `json:"placeholder,omitempty"`
+	Options       []*OptionObject `json:"options"`
+	InitialOption *OptionObject   `json:"initial_option,omitempty"`
+}
+
+// PlainTextInputElement is a text field, for InputBlocks.
+type PlainTextInputElement struct {
+	ActionID     string      `json:"action_id,omitempty"`
+	Placeholder  *TextObject `json:"placeholder,omitempty"`
+	InitialValue string      `json:"initial_value,omitempty"`
+	Multiline    bool        `json:"multiline,omitempty"`
+}
+
+// ImageElement is a small image, for ContextBlocks and accessories.
+type ImageElement struct {
+	ImageURL string `json:"image_url"`
+	AltText  string `json:"alt_text"`
+}
+
+func (ButtonElement) elementType() string         { return "button" }
+func (StaticSelectElement) elementType() string   { return "static_select" }
+func (PlainTextInputElement) elementType() string { return "plain_text_input" }
+func (ImageElement) elementType() string          { return "image" }
+
+// The MarshalJSON methods add the "type" Slack expects to each block
+// and element.
+
+func (b SectionBlock) MarshalJSON() ([]byte, error) {
+	type alias SectionBlock
+	return marshalTyped(b.blockType(), alias(b))
+}
+
+func (b DividerBlock) MarshalJSON() ([]byte, error) {
+	type alias DividerBlock
+	return marshalTyped(b.blockType(), alias(b))
+}
+
+func (b ImageBlock) MarshalJSON() ([]byte, error) {
+	type alias ImageBlock
+	return marshalTyped(b.blockType(), alias(b))
+}
+
+func (b ContextBlock) MarshalJSON() ([]byte, error) {
+	type alias ContextBlock
+	return marshalTyped(b.blockType(), alias(b))
+}
+
+func (b ActionsBlock) MarshalJSON() ([]byte, error) {
+	type alias ActionsBlock
+	return marshalTyped(b.blockType(), alias(b))
+}
+
+func (b InputBlock) MarshalJSON() ([]byte, error) {
+	type alias InputBlock
+	return marshalTyped(b.blockType(), alias(b))
+}
+
+func (e ButtonElement) MarshalJSON() ([]byte, error) {
+	type alias ButtonElement
+	return marshalTyped(e.elementType(), alias(e))
+}
+
+func (e StaticSelectElement) MarshalJSON() ([]byte, error) {
+	type alias StaticSelectElement
+	return marshalTyped(e.elementType(), alias(e))
+}
+
+func (e PlainTextInputElement) MarshalJSON() ([]byte, error) {
+	type alias PlainTextInputElement
+	return marshalTyped(e.elementType(), alias(e))
+}
+
+func (e ImageElement) MarshalJSON() ([]byte, error) {
+	type alias ImageElement
+	return marshalTyped(e.elementType(), alias(e))
+}
+
+// marshalTyped marshals `v`, which must be a struct, with an
+// additional "type" field.
+func marshalTyped(typ string, v interface{}) ([]byte, error) {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return nil, err
+	}
+	fields["type"], _ = json.Marshal(typ)
+
+	return json.Marshal(fields)
+}
+
+// ModalView is a modal dialog, opened with `Bot.OpenModal`. Its
+// submission is dispatched to InteractionListeners with the same
+// CallbackID.
+type ModalView struct {
+	CallbackID      string      `json:"callback_id,omitempty"`
+	Title           *TextObject `json:"title"`
+	Submit          *TextObject `json:"submit,omitempty"`
+	Close           *TextObject `json:"close,omitempty"`
+	Blocks          []Block     `json:"blocks"`
+	PrivateMetadata string      `json:"private_metadata,omitempty"`
+	NotifyOnClose   bool        `json:"notify_on_close,omitempty"`
+}
+
+func (v ModalView) MarshalJSON() ([]byte, error) {
+	type alias ModalView
+	return marshalTyped("modal", alias(v))
+}
+
+// SendBlocks posts a Block Kit message to the channel `to`, through
+// the Web API whatever the Transport. `text` is the fallback shown in
+// notifications. It returns a Reply which can be listened on, like
+// `SendOutgoingMessage`, and `opts` tweak the message like there.
+func (team *Team) SendBlocks(to, text string, blocks []Block, opts ...ReplyOption) *Reply {
+	outMsg := team.Transport.NewOutgoingMessage(text, to)
+	for _, opt := range opts {
+		opt(outMsg)
+	}
+	return team.queueReply(outMsg, &ReplyOptions{Text: outMsg.Text, Blocks: blocks})
+}
+
+// UpdateBlocks replaces the content of a message previously sent
+// with SendBlocks, like to disable buttons after a click.
+func (team *Team) UpdateBlocks(channel, timestamp, text string, blocks []Block) error {
+	return team.UpdateBlocksContext(context.Background(), channel, timestamp, text, blocks)
+}
+
+// UpdateBlocksContext is UpdateBlocks, giving up when `ctx` is done.
+func (team *Team) UpdateBlocksContext(ctx context.Context, channel, timestamp, text string, blocks []Block) error {
+	values := url.Values{
+		"channel": {channel},
+		"ts":      {timestamp},
+		"text":    {text},
+	}
+	return team.callBlocksAPI(ctx, "chat.update", values, blocks, nil)
+}
+
+// OpenModal opens a modal in response to an interaction, with the
+// `triggerID` of the InteractionEvent.
+func (team *Team) OpenModal(triggerID string, view *ModalView) error {
+	return team.OpenModalContext(context.Background(), triggerID, view)
+}
+
+// OpenModalContext is OpenModal, giving up when `ctx` is done.
+func (team *Team) OpenModalContext(ctx context.Context, triggerID string, view *ModalView) error {
+	content, err := json.Marshal(view)
+	if err != nil {
+		return err
+	}
+
+	values := url.Values{
+		"trigger_id": {triggerID},
+		"view":       {string(content)},
+	}
+	return team.callWebAPI(ctx, "views.open", values, nil)
+}
+
+// ReplyBlocks replies with a Block Kit message to the source the
+// message came from.
+func (msg *Message) ReplyBlocks(text string, blocks ...Block) *Reply {
+	return msg.Workspace().SendBlocks(msg.replyTo(), text, blocks)
+}
+
+func (team *Team) callBlocksAPI(ctx context.Context, method string, values url.Values, blocks []Block, out interface{}) error {
+	if blocks == nil {
+		blocks = []Block{}
+	}
+	content, err := json.Marshal(blocks)
+	if err != nil {
+		return err
+	}
+	values.Set("blocks", string(content))
+
+	return team.callWebAPI(ctx, method, values, out)
+}
+
+// callWebAPI posts to a Web API method with the bot's token, for the
+// methods our Slack library doesn't support. `out` receives the
+// response, if not nil. The request is cancelled when `ctx` is done.
+// Rate limited calls return a `*slack.RateLimitedError`, honoring the
+// Retry-After header, so the outgoing queue retries them.
+func (team *Team) callWebAPI(ctx context.Context, method string, values url.Values, out interface{}) error {
+	bot := team.bot
+	values.Set("token", team.Config().ApiToken)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", slack.SLACK_API+method, strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		bot.countAPIError(method, "ratelimited")
+		retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+		if err != nil || retryAfter <= 0 {
+			retryAfter = 1
+		}
+		return &slack.RateLimitedError{RetryAfter: time.Duration(retryAfter) * time.Second}
+	}
+	if resp.StatusCode != http.StatusOK {
+		bot.countAPIError(method, "http_"+strconv.Itoa(resp.StatusCode))
+		return errors.New(method + ": " + resp.Status)
+	}
+
+	var content json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&content); err != nil {
+		return err
+	}
+
+	var status struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(content, &status); err != nil {
+		return err
+	}
+	if !status.Ok {
+		bot.countAPIError(method, status.Error)
+		return errors.New(method + ": " + status.Error)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(content, out)
+}
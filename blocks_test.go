@@ -0,0 +1,87 @@
+package slick
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlocksMarshalWithTypes(t *testing.T) {
+	blocks := []Block{
+		SectionBlock{
+			Text:      MarkdownText("*Lunch?*"),
+			Accessory: &ButtonElement{ActionID: "yes", Text: PlainText("Yes"), Value: "1", Style: "primary"},
+		},
+		DividerBlock{},
+		ActionsBlock{Elements: []BlockElement{
+			StaticSelectElement{ActionID: "place", Options: []*OptionObject{NewOption("Pizza", "pizza")}},
+		}},
+	}
+
+	content, err := json.Marshal(blocks)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"type": "section",
+		 "text": {"type": "mrkdwn", "text": "*Lunch?*"},
+		 "accessory": {"type": "button", "action_id": "yes", "text": {"type": "plain_text", "text": "Yes", "emoji": true}, "value": "1", "style": "primary"}},
+		{"type": "divider"},
+		{"type": "actions", "elements": [
+			{"type": "static_select", "action_id": "place", "options": [{"text": {"type": "plain_text", "text": "Pizza", "emoji": true}, "value": "pizza"}]}
+		]}
+	]`, string(content))
+}
+
+func TestSendBlocksAcknowledges(t *testing.T) {
+	var posted string
+	var form url.Values
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted = r.FormValue("blocks")
+		form = r.Form
+		fmt.Fprint(w, `{"ok":true,"channel":"C1","ts":"1234.5678"}`)
+	}))
+	defer api.Close()
+
+	defaultAPI := slack.SLACK_API
+	slack.SLACK_API = api.URL + "/"
+	defer func() { slack.SLACK_API = defaultAPI }()
+
+	bot := New("")
+	bot.Transport = newFakeTransport()
+	go bot.replyHandler()
+
+	reply := bot.SendBlocks("C1", "fallback", []Block{DividerBlock{}})
+
+	select {
+	case event := <-bot.internalEvents:
+		ack := event.Data.(*slack.AckMessage)
+		assert.Equal(t, reply.ID, ack.ReplyTo)
+		assert.Equal(t, "1234.5678", ack.Timestamp)
+		assert.JSONEq(t, `[{"type":"divider"}]`, posted)
+		// The event loop lets the next post of the channel go
+		bot.Team.handleRTMEvent(&event)
+	case <-time.After(time.Second):
+		t.Fatal("blocks not acknowledged")
+	}
+
+	// The ReplyOptions apply to the whole message
+	broadcast := func(msg *slack.OutgoingMessage) {
+		msg.ThreadBroadcast = true
+		msg.Text = "updated " + msg.Text
+	}
+	bot.SendBlocks("C1", "fallback", []Block{DividerBlock{}}, InThread("1234.0001"), broadcast)
+	select {
+	case <-bot.internalEvents:
+		assert.Equal(t, "1234.0001", form.Get("thread_ts"))
+		assert.Equal(t, "true", form.Get("reply_broadcast"))
+		assert.Equal(t, "updated fallback", form.Get("text"))
+	case <-time.After(time.Second):
+		t.Fatal("blocks not acknowledged")
+	}
+}
@@ -2,11 +2,12 @@
 package slick
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"os"
-	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -22,37 +23,88 @@ import (
 // Bot is the main slick bot instance. It is passed throughout, and
 // has references to most useful objects.
 type Bot struct {
-	// Global bot configuration
+	// The primary team, configured in the Slack section, with the
+	// global bot configuration in its `Config`, and its Slack
+	// connectivity, users and channels. See Team.
+	*Team
+	// teams are the other teams, from the Teams section.
+	teams []*Team
+
 	configFile string
-	Config     SlackConfig
+	// viper holds the config file read, for LoadConfig and the
+	// plugins' sections. A reload replaces it with a new one.
+	viper     *viper.Viper
+	viperLock sync.RWMutex
 
-	// Logging configuration
+	// Logging configuration. It is replaced by ReloadConfig, with the
+	// reloadLock held.
 	Logging Logging
 
-	// Slack connectivity
-	Slack             *slack.Client
-	rtm               *slack.RTM
-	Users             map[string]slack.User
-	Channels          map[string]Channel
-	channelUpdateLock sync.Mutex
-	Myself            slack.UserDetails
-
 	// Internal handling
 	listeners     []*Listener
+	commands      commandRegistry
 	addListenerCh chan *Listener
 	delListenerCh chan *Listener
-	outgoingMsgCh chan *slack.OutgoingMessage
-
-	// Storage
-	DB *bolt.DB
+	// internalEvents carries the events of the primary team which
+	// don't come from the Transport, like interactions. teamEvents
+	// carries all the events of the other teams.
+	internalEvents chan slack.RTMEvent
+	teamEvents     chan teamEvent
+	middlewares    []Middleware
+	dispatchChain  Handler
+	// listenerQueues holds the workers of the active listeners, for
+	// ListenerStats.
+	listenerQueues    map[*Listener]*listenerQueue
+	listenerStatsLock sync.Mutex
+
+	// Storage. StorageBackend defaults to BoltDB when left nil before
+	// `Run()`, see `Bot.Storage`. DB is the BoltDB database, when
+	// StorageBackend is a BoltStorage.
+	StorageBackend StorageBackend
+	DB             *bolt.DB
+
+	// Scheduler runs recurring and one-shot jobs.
+	Scheduler *Scheduler
 
 	// Inter-plugins communications. Use topics like
 	// "pluginName:eventType[:someOtherThing]"
 	PubSub *pubsub.PubSub
 
+	// Metrics is exported on the WebServer's `/metrics` route.
+	// Plugins can register their own.
+	Metrics *Metrics
+	metrics botMetrics
+
 	// Other features
 	WebServer WebServer
 	Mood      Mood
+
+	// Lifecycle, see Shutdown. ctx is cancelled on Shutdown, and the
+	// Listeners' contexts derive from it.
+	ctx             context.Context
+	cancel          context.CancelFunc
+	pluginsStarted  bool
+	handlersStarted bool
+	stopCh          chan bool
+	handlersDone    chan bool
+	connectDone     chan bool
+	shutdownOnce    sync.Once
+	shutdownErr     error
+
+	// audit writes the entries of the audit log, see Bot.Audit.
+	audit auditWriter
+
+	// reloadLock serializes the config reloads, see ReloadConfig.
+	reloadLock sync.Mutex
+
+	// The plugins enabled in the config, and their channel scopes by
+	// package path, see PluginConfig.
+	plugins      []Plugin
+	pluginScopes map[string]PluginConfig
+	pluginsLock  sync.RWMutex
+
+	// permissions holds the Roles of the config, see Bot.Can.
+	permissions permissions
 }
 
 // New returns a new bot instance, initialized with the provided config
@@ -62,15 +114,27 @@ type Bot struct {
 func New(configFile string) *Bot {
 	bot := &Bot{
 		configFile:    configFile,
-		outgoingMsgCh: make(chan *slack.OutgoingMessage, 500),
+		viper:         viper.New(),
 		addListenerCh: make(chan *Listener, 500),
 		delListenerCh: make(chan *Listener, 500),
 
-		Users:    make(map[string]slack.User),
-		Channels: make(map[string]Channel),
+		internalEvents: make(chan slack.RTMEvent, 100),
+		teamEvents:     make(chan teamEvent, 100),
+		listenerQueues: make(map[*Listener]*listenerQueue),
+
+		stopCh:       make(chan bool),
+		handlersDone: make(chan bool),
+		connectDone:  make(chan bool),
 
 		PubSub: pubsub.New(500),
+
+		plugins: registeredPlugins,
 	}
+	bot.Team = newTeam(bot, "")
+	bot.ctx, bot.cancel = context.WithCancel(context.Background())
+	bot.Scheduler = newScheduler(bot)
+	bot.Metrics = NewMetrics()
+	bot.registerMetrics()
 
 	http.DefaultClient = &http.Client{
 		Transport: &http.Transport{
@@ -81,10 +145,10 @@ func New(configFile string) *Bot {
 	return bot
 }
 
-// Run starts the bot
+// Run starts the bot, and returns once it is shut down. See Shutdown.
 func (bot *Bot) Run() {
 	// Config for Slack and logging are read in
-	bot.loadBaseConfig()
+	config := bot.loadBaseConfig()
 
 	// Configure logging
 	err := bot.setupLogging()
@@ -92,29 +156,44 @@ func (bot *Bot) Run() {
 		log.Fatal("Error setting up logging.")
 	}
 
+	// Check the config of the bot and the plugins, before starting
+	// anything
+	if err := checkConfig(bot.configViper(), false); err != nil {
+		log.Fatal(err)
+	}
+
 	// Write PID
 	err = bot.writePID()
 	if err != nil {
 		log.Fatal("Couldn't write PID file:", err)
 	}
 
-	db, err := bolt.Open(bot.Config.DBPath, 0600, nil)
-	if err != nil {
-		log.WithError(err).Fatalf("Could not initialize BoltDB key/value store: %s", err)
+	if bot.StorageBackend == nil {
+		bot.StorageBackend, err = bot.openStorage()
+		if err != nil {
+			log.WithError(err).Fatalf("Could not initialize the storage: %s", err)
+		}
+	}
+	if boltStorage, ok := bot.StorageBackend.(*BoltStorage); ok {
+		bot.DB = boltStorage.DB
+	}
+
+	if bot.Config().Timezone != "" {
+		loc, err := time.LoadLocation(bot.Config().Timezone)
+		if err != nil {
+			log.WithError(err).Fatalf("Invalid timezone %q", bot.Config().Timezone)
+		}
+		bot.Scheduler.Location = loc
 	}
-	defer func() {
-		log.Warnf("Database is closing")
-		db.Close()
-	}()
-	bot.DB = db
 
 	// Init all plugins
-	var enabledPlugins []string
 	for _, plugin := range registeredPlugins {
-		pluginType := reflect.TypeOf(plugin)
-		if pluginType.Kind() == reflect.Ptr {
-			pluginType = pluginType.Elem()
+		if !bot.pluginEnabled(plugin) {
+			log.Printf("Plugin %s is disabled", pluginName(plugin))
 		}
+	}
+	var enabledPlugins []string
+	for _, plugin := range bot.plugins {
 		var typeList []string
 		if _, ok := plugin.(PluginInitializer); ok {
 			typeList = append(typeList, "Plugin")
@@ -129,36 +208,89 @@ func (bot *Bot) Run() {
 			typeList = append(typeList, "WebPlugin")
 		}
 
-		log.Printf("Plugin %s implements %s", pluginType.String(),
+		if _, ok := plugin.(PluginStopper); ok {
+			typeList = append(typeList, "PluginStopper")
+		}
+		if _, ok := plugin.(ConfigSchema); ok {
+			typeList = append(typeList, "ConfigSchema")
+		}
+		if _, ok := plugin.(ConfigReloader); ok {
+			typeList = append(typeList, "ConfigReloader")
+		}
+
+		log.Printf("Plugin %s implements %s", pluginName(plugin),
 			strings.Join(typeList, ", "))
-		enabledPlugins = append(enabledPlugins, strings.Replace(pluginType.String(), ".", "_", -1))
+		enabledPlugins = append(enabledPlugins, strings.Replace(pluginName(plugin), ".", "_", -1))
 	}
 
+	bot.Slack = slack.New(bot.Config().ApiToken)
+	bot.Slack.SetDebug(bot.Config().Debug)
+
+	if bot.Transport == nil {
+		bot.Transport = bot.newTransport()
+	}
+	bot.setupTeams(config.Teams)
+	bot.setRates(bot.Config().MessageRate, bot.Config().ChannelMessageRate)
+
 	initWebServer(bot, enabledPlugins)
 	initWebPlugins(bot)
 
+	bot.initSlackRoutes()
+	bot.initAuditRoutes()
+
+	// After initSlackRoutes, which checks if the Transport receives
+	// events over HTTP.
+	bot.Transport = &auditTransport{Transport: bot.Transport, team: bot.Team}
+
 	if bot.WebServer != nil {
 		go bot.WebServer.RunServer()
 	}
 
+	bot.listenCommands()
+	bot.listenReloadCommand()
+	bot.listenRoleCommands()
+	bot.listenAuditCommand()
+	bot.Scheduler.listenJobsCommand()
+	bot.scheduleStorageExpiry()
+	bot.scheduleAuditRetention()
+	bot.pluginsStarted = true
 	initChatPlugins(bot)
 
-	bot.Slack = slack.New(bot.Config.ApiToken)
-	bot.Slack.SetDebug(bot.Config.Debug)
-
-	rtm := bot.Slack.NewRTM()
-	bot.rtm = rtm
+	bot.Scheduler.start()
 
 	bot.setupHandlers()
+	go bot.handleSignals()
+	go bot.watchConfig()
+
+	bot.connectTeams()
+	bot.Transport.Connect()
+	close(bot.connectDone)
+
+	// The Transport disconnected: shut down, or wait for the Shutdown
+	// in progress.
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
+	defer cancel()
+	bot.Shutdown(ctx)
+}
 
-	bot.rtm.ManageConnection()
+// initSlackRoutes mounts the endpoints Slack posts to on the
+// WebServer's public router.
+func (bot *Bot) initSlackRoutes() {
+	if handler, ok := bot.Transport.(http.Handler); ok {
+		if bot.WebServer == nil {
+			log.Fatalln("The Events API needs a WebServer plugin to receive events.")
+		}
+		bot.WebServer.PublicRouter().Handle(EventsAPIPath, handler).Methods("POST")
+	}
+
+	if bot.WebServer != nil && bot.Config().SigningSecret != "" {
+		bot.WebServer.PublicRouter().HandleFunc(InteractionsPath, bot.handleInteractions).Methods("POST")
+	}
 }
 
 func (bot *Bot) writePID() error {
 	var serverConf struct {
-		Server struct {
-			Pidfile string `mapstructure:"pid_file"`
-		}
+		Server ServerConfig
 	}
 
 	err := bot.LoadConfig(&serverConf)
@@ -185,6 +317,9 @@ func (bot *Bot) writePID() error {
 // Explore the Listener for more details.
 func (bot *Bot) Listen(listen *Listener) error {
 	listen.Bot = bot
+	if listen.plugin == "" {
+		listen.plugin = callerPlugin()
+	}
 
 	err := listen.checkParams()
 	if err != nil {
@@ -197,10 +332,11 @@ func (bot *Bot) Listen(listen *Listener) error {
 	return nil
 }
 
-// ListenReaction will dispatch the listener with matching incoming reactions.
-// `item` can be a timestamp or a file ID.
-func (bot *Bot) ListenReaction(item string, reactListen *ReactionListener) {
+// ListenReaction will dispatch the listener with matching incoming reactions
+// of the team. `item` can be a timestamp or a file ID.
+func (team *Team) ListenReaction(item string, reactListen *ReactionListener) {
 	listen := reactListen.newListener()
+	listen.team = team
 	listen.EventHandlerFunc = func(_ *Listener, event interface{}) {
 		re := ParseReactionEvent(event)
 		if re == nil {
@@ -211,7 +347,7 @@ func (bot *Bot) ListenReaction(item string, reactListen *ReactionListener) {
 			return
 		}
 
-		if re.User == bot.Myself.ID {
+		if re.User == team.Myself.ID {
 			return
 		}
 
@@ -223,7 +359,7 @@ func (bot *Bot) ListenReaction(item string, reactListen *ReactionListener) {
 
 		reactListen.HandlerFunc(reactListen, re)
 	}
-	bot.Listen(listen)
+	team.bot.Listen(listen)
 }
 
 func (bot *Bot) addListener(listen *Listener) {
@@ -234,66 +370,161 @@ func (bot *Bot) addListener(listen *Listener) {
 	bot.addListenerCh <- listen
 }
 
-func (bot *Bot) Notify(room, color, format, msg string, notify bool) error {
-	log.Println("DEPRECATED. Please use the Slack API with .PostMessage")
-	// bot.api.PostMessage(room, msg, slack.PostMessageParameters{
-	// 	Attachments: []slack.Attachment{
-	// 		{
-	// 			Color: color,
-	// 			Text: msg,
-	// 		},
-	// 	},
-	// })
-	return nil
-}
-
 func (bot *Bot) setupHandlers() {
-	go bot.replyHandler()
+	bot.handlersStarted = true
+	for _, team := range bot.Teams() {
+		go team.replyHandler()
+	}
 	go bot.messageHandler()
 	log.Println("Bot ready")
 }
 
-func (bot *Bot) cacheUsers(users []slack.User) {
-	bot.Users = make(map[string]slack.User)
-	for _, user := range users {
-		bot.Users[user.ID] = user
+func (team *Team) cacheUsers(users []slack.User) {
+	team.Users.Replace(users)
+}
+
+func (team *Team) cacheChannels(conversations []slack.Channel) {
+	log.Debugf("Conversations: %v", len(conversations))
+	channels := make([]Channel, 0, len(conversations))
+	for _, conversation := range conversations {
+		channels = append(channels, ChannelFromSlackConversation(conversation))
 	}
+	team.Channels.Replace(channels)
 }
 
-func (bot *Bot) cacheChannels(channels []slack.Channel, groups []slack.Group, ims []slack.IM) {
-	log.Debugf("Channels: %v", len(channels))
-	log.Debugf("Groups: %v", len(groups))
-	log.Debugf("DM's: %v", len(ims))
-	bot.Channels = make(map[string]Channel)
-	for _, channel := range channels {
-		bot.updateChannel(ChannelFromSlackChannel(channel))
+// loadDirectory fills `Users` and `Channels` from the Web API. On
+// failure, what was previously loaded is kept.
+func (team *Team) loadDirectory() {
+	users, err := team.Slack.GetUsers()
+	if team.bot.countAPICall("users.list", err) != nil {
+		log.WithError(err).Error("Couldn't load the users list.")
+	} else {
+		team.cacheUsers(users)
 	}
 
-	for _, group := range groups {
-		bot.updateChannel(ChannelFromSlackGroup(group))
+	conversations, err := team.getConversations()
+	if err != nil {
+		log.WithError(err).Error("Couldn't load the conversations list.")
+	} else {
+		team.cacheChannels(conversations)
 	}
+}
 
-	for _, im := range ims {
-		bot.updateChannel(ChannelFromSlackIM(im))
+// getConversations pages through `conversations.list` for all the
+// channels, private groups and IMs visible to the bot.
+func (team *Team) getConversations() ([]slack.Channel, error) {
+	params := &slack.GetConversationsParameters{
+		ExcludeArchived: "false",
+		Limit:           200,
+		Types:           []string{"public_channel", "private_channel", "mpim", "im"},
+	}
+
+	var conversations []slack.Channel
+	for {
+		page, cursor, err := team.Slack.GetConversations(params)
+		team.bot.countAPICall("conversations.list", err)
+		if rateLimited, ok := err.(*slack.RateLimitedError); ok {
+			time.Sleep(rateLimited.RetryAfter)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		conversations = append(conversations, page...)
+		if cursor == "" {
+			return conversations, nil
+		}
+		params.Cursor = cursor
 	}
 }
 
-func (bot *Bot) loadBaseConfig() {
+// directoryFetchTimeout bounds the Web API calls fetching the users and
+// channels missing from the directory.
+const directoryFetchTimeout = 10 * time.Second
 
-	bot.readInConfig() // Find and parse the config file
+// fetchUser gets a user missing from `Users` from the Web API.
+func (team *Team) fetchUser(id string) (*slack.User, error) {
+	if team.Slack == nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(team.bot.ctx, directoryFetchTimeout)
+	defer cancel()
+	user, err := team.Slack.GetUserInfoContext(ctx, id)
+	return user, team.bot.countAPICall("users.info", err)
+}
+
+// fetchChannel gets a channel from the Web API, with its members, for
+// the `Channels` directory.
+func (team *Team) fetchChannel(id string) (*Channel, error) {
+	if team.Slack == nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(team.bot.ctx, directoryFetchTimeout)
+	defer cancel()
+	conversation, err := team.Slack.GetConversationInfoContext(ctx, id, false)
+	if team.bot.countAPICall("conversations.info", err) != nil {
+		return nil, err
+	}
+	channel := ChannelFromSlackConversation(*conversation)
+	if channel.IsIM {
+		return &channel, nil
+	}
 
-	var config struct {
-		Logging Logging
-		Slack   SlackConfig
+	// conversations.info doesn't list the members
+	params := &slack.GetUsersInConversationParameters{ChannelID: id, Limit: 200}
+	channel.Members = nil
+	for {
+		members, cursor, err := team.Slack.GetUsersInConversationContext(ctx, params)
+		if team.bot.countAPICall("conversations.members", err) != nil {
+			return nil, err
+		}
+		channel.Members = append(channel.Members, members...)
+		if cursor == "" {
+			return &channel, nil
+		}
+		params.Cursor = cursor
 	}
+}
+
+// hydrateChannel fetches the members, topic, purpose and the rest of a
+// channel which its events lack, without holding the event loop.
+func (team *Team) hydrateChannel(id string) {
+	go func() {
+		if err := team.Channels.Refresh(id); err != nil {
+			log.WithFields(log.Fields{
+				"Type":    "ChannelDirectory",
+				"Channel": id,
+			}).WithError(err).Warn("Couldn't fetch the channel's info.")
+		}
+	}()
+}
+
+// baseConfig holds the sections of the config file read by the bot
+// itself.
+type baseConfig struct {
+	Logging Logging
+	Slack   SlackConfig
+	Plugins map[string]PluginConfig
+	Roles   map[string]Role
+	Teams   map[string]SlackConfig
+}
 
+func (bot *Bot) loadBaseConfig() baseConfig {
+
+	bot.readInConfig() // Find and parse the config file
+
+	var config baseConfig
 	err := bot.LoadConfig(&config)
 	if err != nil {
 		log.WithError(err).Fatalln("Error loading config file.")
 	}
 
-	bot.Config = config.Slack
+	bot.setConfig(config.Slack)
 	bot.Logging = config.Logging
+	bot.setPluginConfigs(config.Plugins)
+	bot.setRoles(config.Roles)
+	return config
 }
 
 // readInConfig reads the config file, unmarshals the given format (JSON, YAML or TOML)
@@ -301,29 +532,50 @@ func (bot *Bot) loadBaseConfig() {
 func (bot *Bot) readInConfig() {
 
 	// Use viper to find a default config file, or open the provided file is set
+	v := viper.New()
 	if bot.configFile == "" {
-		viper.SetConfigName("config")
-		viper.AddConfigPath(".")            // Look for config in the working directory
-		viper.AddConfigPath("$HOME/.slick") // Look for config in .slick folder in home directory
+		v.SetConfigName("config")
+		v.AddConfigPath(".")            // Look for config in the working directory
+		v.AddConfigPath("$HOME/.slick") // Look for config in .slick folder in home directory
 	} else {
 		// Ensure the config file cannot be read of write by others
 		if err := checkPermission(bot.configFile); err != nil {
 			log.WithError(err).Fatal("Error checking permissions.")
 		}
 
-		viper.SetConfigFile(bot.configFile)
+		v.SetConfigFile(bot.configFile)
 	}
 
-	err := viper.ReadInConfig() // Find and read the config file
-	if err != nil {             // Return an error if the config file cannot be parsed
+	err := v.ReadInConfig() // Find and read the config file
+	if err != nil {         // Return an error if the config file cannot be parsed
 		log.WithError(err).Fatalf("fatal error config file: %s", err)
 	}
+
+	// Apply the SLICK_ environment variables and the secret files
+	if err := applyConfigOverrides(v); err != nil {
+		log.WithError(err).Fatalf("Error reading config overrides: %s", err)
+	}
+	bot.setConfigViper(v)
+}
+
+// configViper returns the viper holding the config file read. Each bot
+// has its own, which is never changed once read: a reload replaces it.
+func (bot *Bot) configViper() *viper.Viper {
+	bot.viperLock.RLock()
+	defer bot.viperLock.RUnlock()
+	return bot.viper
+}
+
+func (bot *Bot) setConfigViper(v *viper.Viper) {
+	bot.viperLock.Lock()
+	defer bot.viperLock.Unlock()
+	bot.viper = v
 }
 
 // LoadConfig populates a given struct with the values from the config file
 func (bot *Bot) LoadConfig(config interface{}) (err error) {
 
-	err = viper.Unmarshal(&config)
+	err = bot.configViper().Unmarshal(&config)
 	if err != nil {
 		log.WithError(err).Errorf("unable to decode into struct, %v", err)
 		return err
@@ -332,22 +584,9 @@ func (bot *Bot) LoadConfig(config interface{}) (err error) {
 	return nil
 }
 
-func (bot *Bot) replyHandler() {
-	for {
-		outMsg := <-bot.outgoingMsgCh
-		if outMsg == nil {
-			continue
-		}
-
-		bot.rtm.SendMessage(outMsg)
-
-		time.Sleep(50 * time.Millisecond)
-	}
-}
-
 // SendToChannel sends a message to a given channel
-func (bot *Bot) SendToChannel(channelName string, message string) *Reply {
-	channel := bot.GetChannelByName(channelName)
+func (team *Team) SendToChannel(channelName string, message string) *Reply {
+	channel := team.GetChannelByName(channelName)
 
 	if channel == nil {
 		log.WithFields(log.Fields{
@@ -364,27 +603,34 @@ func (bot *Bot) SendToChannel(channelName string, message string) *Reply {
 		"Message": message,
 	}).Debug("Sending message to channel.")
 
-	return bot.SendOutgoingMessage(message, channel.ID)
+	return team.SendOutgoingMessage(message, channel.ID)
 }
 
 // SendOutgoingMessage schedules the message for departure and returns
-// a Reply which can be listened on. See type `Reply`.
-func (bot *Bot) SendOutgoingMessage(text string, to string) *Reply {
+// a Reply which can be listened on. See type `Reply`, and the
+// ReplyOptions like `InThread`.
+func (team *Team) SendOutgoingMessage(text string, to string, opts ...ReplyOption) *Reply {
+	bot := team.bot
 	log.WithFields(log.Fields{
 		"Type":      "SendingMessage",
 		"Recipient": to,
 		"Message":   text,
 	}).Debug("Sending outgoing message.")
 
-	outMsg := bot.rtm.NewOutgoingMessage(text, to)
-	bot.outgoingMsgCh <- outMsg
+	outMsg := team.Transport.NewOutgoingMessage(text, to)
+	for _, opt := range opts {
+		opt(outMsg)
+	}
 
-	return &Reply{outMsg, bot}
+	reply := &Reply{OutgoingMessage: outMsg, bot: bot, team: team}
+	team.outgoingMsgCh <- reply
+	return reply
 }
 
 // SendPrivateMessage sends a message to a user
-func (bot *Bot) SendPrivateMessage(username, message string) *Reply {
-	user := bot.GetUser(username)
+func (team *Team) SendPrivateMessage(username, message string) *Reply {
+	bot := team.bot
+	user := team.GetUser(username)
 	if user == nil {
 		log.WithFields(log.Fields{
 			"Type":      "UserDoesNotExist",
@@ -395,7 +641,7 @@ func (bot *Bot) SendPrivateMessage(username, message string) *Reply {
 		return nil
 	}
 
-	imChannel := bot.OpenIMChannelWith(user)
+	imChannel := team.OpenIMChannelWith(user)
 	if imChannel == nil {
 		log.WithFields(log.Fields{
 			"Type":         "IMChannelDoesNotExist",
@@ -413,10 +659,11 @@ func (bot *Bot) SendPrivateMessage(username, message string) *Reply {
 		"Message":    message,
 	}).Info("Sending private message.")
 
-	outMsg := bot.rtm.NewOutgoingMessage(message, imChannel.ID)
-	bot.outgoingMsgCh <- outMsg
+	outMsg := team.Transport.NewOutgoingMessage(message, imChannel.ID)
 
-	return &Reply{outMsg, bot}
+	reply := &Reply{OutgoingMessage: outMsg, bot: bot, team: team}
+	team.outgoingMsgCh <- reply
+	return reply
 }
 
 func (bot *Bot) removeListener(listen *Listener) {
@@ -426,23 +673,40 @@ func (bot *Bot) removeListener(listen *Listener) {
 			copy(bot.listeners[i:], bot.listeners[i+1:])
 			bot.listeners[len(bot.listeners)-1] = nil
 			bot.listeners = bot.listeners[:len(bot.listeners)-1]
+			listen.cancelContext()
+			bot.stopListener(listen)
 			return
 		}
 	}
 }
 
 func (bot *Bot) messageHandler() {
+	defer close(bot.handlersDone)
+
 	for {
 	nextMessages:
 		select {
+		case <-bot.stopCh:
+			bot.stopListeners()
+			return
+
 		case listen := <-bot.addListenerCh:
 			bot.listeners = append(bot.listeners, listen)
 
 		case listen := <-bot.delListenerCh:
 			bot.removeListener(listen)
 
-		case event := <-bot.rtm.IncomingEvents:
+		case event := <-bot.Transport.IncomingEvents():
+			bot.metrics.eventsReceived.Inc(event.Type)
 			bot.handleRTMEvent(&event)
+
+		case event := <-bot.internalEvents:
+			bot.metrics.eventsReceived.Inc(event.Type)
+			bot.handleRTMEvent(&event)
+
+		case te := <-bot.teamEvents:
+			bot.metrics.eventsReceived.Inc(te.event.Type)
+			te.team.handleRTMEvent(&te.event)
 		}
 
 		// Always flush listeners deletions between messages, so a
@@ -458,9 +722,9 @@ func (bot *Bot) messageHandler() {
 	}
 }
 
-func (bot *Bot) handleRTMEvent(event *slack.RTMEvent) {
+func (team *Team) handleRTMEvent(event *slack.RTMEvent) {
+	bot := team.bot
 	var msg *Message
-	var client = bot.Slack
 	//var reaction interface{}
 
 	switch ev := event.Data.(type) {
@@ -472,48 +736,25 @@ func (bot *Bot) handleRTMEvent(event *slack.RTMEvent) {
 			"Type":    "LatencyReport",
 			"Latency": ev.Value,
 		}).Debug("Latency Report.")
+		bot.metrics.latency.Set(ev.Value.Seconds())
 	case *slack.RTMError:
 		log.WithFields(log.Fields{
 			"Type":      "RTMError",
 			"ErrorCode": ev.Code,
 			"Message":   ev.Msg,
 		}).Error("Real Time Messenger Error.")
+		bot.countAPIError("rtm", strconv.Itoa(ev.Code))
 	case *slack.ConnectedEvent:
-		// Replacing ev.Info.Channels
-		channels, err := client.GetChannels(false)
-		if err != nil {
-			panic("SLACK DEPRECATED ANOTHER API LOL")
-		}
-
-		// Replacing ev.Info.Groups
-		groups, err := client.GetGroups(false)
-		if err != nil {
-			panic("SLACK DEPRECATED ANOTHER API LOL")
-		}
-
-		// Replacing ev.Info.IMs
-		ims, err := client.GetIMChannels()
-		if err != nil {
-			panic("SLACK DEPRECATED ANOTHER API LOL")
-		}
-
-		// Replacing ev.Info.Users
-		users, err := client.GetUsers()
-		if err != nil {
-			panic("SLACK DEPRECATED ANOTHER API LOL")
-		}
-
 		log.Printf("Bot connected, connection_count=%d", ev.ConnectionCount)
-		bot.Myself = *ev.Info.User
-		bot.cacheUsers(users)                    // Info.Users is deprecated
-		bot.cacheChannels(channels, groups, ims) // Info.Channels is deprecated
-
-		for _, channelName := range bot.Config.JoinChannels {
-			channel := bot.GetChannelByName(channelName)
-			if channel != nil && !channel.IsMember {
-				bot.Slack.JoinChannel(channel.ID)
-			}
+		bot.countConnection(team)
+		team.Myself = *ev.Info.User
+		if ev.Info.Team != nil {
+			team.ID = ev.Info.Team.ID
 		}
+		team.loadDirectory()
+		team.resolvePluginScopes()
+
+		team.joinChannels()
 
 	case *slack.DisconnectedEvent:
 		log.Println("Bot disconnected")
@@ -536,6 +777,7 @@ func (bot *Bot) handleRTMEvent(event *slack.RTMEvent) {
 			Msg:        &ev.Msg,
 			SubMessage: ev.SubMessage,
 			bot:        bot,
+			team:       team,
 		}
 
 		userID := ev.User
@@ -545,59 +787,45 @@ func (bot *Bot) handleRTMEvent(event *slack.RTMEvent) {
 			msg.Msg.Text = ev.SubMessage.Text
 			msg.IsEdit = true
 		case "channel_topic":
-			if channel, ok := bot.Channels[ev.Channel]; ok {
+			team.Channels.Update(ev.Channel, func(channel *Channel) {
 				channel.Topic = slack.Topic{
 					Value:   ev.Topic,
 					Creator: ev.User,
 					LastSet: unixFromTimestamp(ev.Timestamp),
 				}
-				bot.Channels[ev.Channel] = channel
-			}
+			})
 		case "channel_purpose":
-			if channel, ok := bot.Channels[ev.Channel]; ok {
+			team.Channels.Update(ev.Channel, func(channel *Channel) {
 				channel.Purpose = slack.Purpose{
 					Value:   ev.Purpose,
 					Creator: ev.User,
 					LastSet: unixFromTimestamp(ev.Timestamp),
 				}
-				bot.Channels[ev.Channel] = channel
-			}
+			})
 		}
 
-		// We do some heavy logging here because this is troublesome
-		// to find when it breaks and Slack breaks it by deprecating API's
-
-		// Find FromUser by ID if possible, or log error if it's not a bot message
-		user, ok := bot.Users[userID]
-		if ok {
-			log.Debug("User map is ok.")
-			msg.FromUser = &user
-		} else if ev.Msg.SubType != "bot_message" {
-			log.WithFields(log.Fields{
-				"Type":  "BrokenUserMap",
-				"Users": len(bot.Users),
-				"User": userID,
-			}).Error("User map is broken.")
+		// The users and channels missing from the directory, like
+		// those created since the bot connected, are fetched in the
+		// background: the event loop doesn't wait for the Web API,
+		// the Listeners' workers do, see Message.resolve. Bot
+		// messages may have no user.
+		if userID != "" {
+			if user, ok := team.Users.peek(userID); ok {
+				msg.FromUser = &user
+			}
 		}
-
-		channel, ok := bot.Channels[ev.Channel]
-		if ok {
-			log.Debug("Channel map is ok.")
+		if channel, ok := team.Channels.peek(ev.Channel); ok {
 			msg.FromChannel = &channel
-		} else {
-			log.WithFields(log.Fields{
-				"Type":     "BrokenChannelMap",
-				"Channels": len(bot.Channels),
-			}).Error("Channel map is broken.")
 		}
 
-		msg.applyMentionsMe(bot)
-		msg.applyFromMe(bot)
+		msg.applyMentionsMe(team)
+		msg.applyFromMe(team)
 
 	case *slack.PresenceChangeEvent:
-		user := bot.Users[ev.User]
-		log.Printf("User %q is now %q", user.Name, ev.Presence)
-		user.Presence = ev.Presence
+		team.Users.Update(ev.User, func(user *slack.User) {
+			log.Printf("User %q is now %q", user.Name, ev.Presence)
+			user.Presence = ev.Presence
+		})
 
 	// TODO: manage im_open, im_close, and im_created ?
 
@@ -610,77 +838,103 @@ func (bot *Bot) handleRTMEvent(event *slack.RTMEvent) {
 	 * User changes
 	 */
 	case *slack.UserChangeEvent:
-		bot.Users[ev.User.ID] = ev.User
+		team.Users.Set(ev.User)
 
 	/**
 	 * Handle slack Channel changes
 	 */
 	case *slack.ChannelRenameEvent:
-		channel := bot.Channels[ev.Channel.ID]
-		channel.Name = ev.Channel.Name
-		bot.updateChannel(channel)
+		team.Channels.Update(ev.Channel.ID, func(channel *Channel) {
+			channel.Name = ev.Channel.Name
+		})
+		team.resolvePluginScopes()
 
 	case *slack.ChannelJoinedEvent:
-		bot.updateChannel(ChannelFromSlackChannel(ev.Channel))
+		team.updateChannel(ChannelFromSlackChannel(ev.Channel))
 
 	case *slack.ChannelCreatedEvent:
 		c := Channel{}
 		c.ID = ev.Channel.ID
 		c.Name = ev.Channel.Name
 		c.Creator = ev.Channel.Creator
+		c.Created = time.Unix(int64(ev.Channel.Created), 0)
 		c.IsChannel = true
-		bot.updateChannel(c)
+		team.updateChannel(c)
+		team.hydrateChannel(c.ID)
 
-		// NICE TODO: poll the API to get a full Channel object ? many
-		// things are missing here
+	case *slack.ChannelLeftEvent:
+		team.leftChannel(ev.Channel)
 
 	case *slack.ChannelDeletedEvent:
-		bot.deleteChannel(ev.Channel)
+		team.deleteChannel(ev.Channel)
 
 	case *slack.ChannelArchiveEvent:
-		channel := bot.Channels[ev.Channel]
-		channel.IsArchived = true
-		bot.updateChannel(channel)
+		team.Channels.Update(ev.Channel, func(channel *Channel) {
+			channel.IsArchived = true
+		})
 
 	case *slack.ChannelUnarchiveEvent:
-		channel := bot.Channels[ev.Channel]
-		channel.IsArchived = false
-		bot.updateChannel(channel)
+		team.Channels.Update(ev.Channel, func(channel *Channel) {
+			channel.IsArchived = false
+		})
 
 	/**
 	 * Handle slack Group changes
 	 */
 	case *slack.GroupRenameEvent:
-		group := bot.Channels[ev.Group.ID]
-		group.Name = ev.Group.Name
-		bot.updateChannel(group)
+		team.Channels.Update(ev.Group.ID, func(group *Channel) {
+			group.Name = ev.Group.Name
+		})
+		team.resolvePluginScopes()
 
 	case *slack.GroupJoinedEvent:
-		bot.updateChannel(ChannelFromSlackChannel(ev.Channel))
+		team.updateChannel(ChannelFromSlackChannel(ev.Channel))
 
 	case *slack.GroupCreatedEvent:
 		c := Channel{}
 		c.ID = ev.Channel.ID
 		c.Name = ev.Channel.Name
 		c.Creator = ev.Channel.Creator
+		c.Created = time.Unix(int64(ev.Channel.Created), 0)
 		c.IsGroup = true
-		bot.updateChannel(c)
+		team.updateChannel(c)
+		team.hydrateChannel(c.ID)
 
-		// NICE: poll the API to get a full Group object ? many
-		// things are missing here
+	case *slack.GroupLeftEvent:
+		team.leftChannel(ev.Channel)
+
+	/**
+	 * Handle membership changes
+	 */
+	case *slack.MemberJoinedChannelEvent:
+		if !team.Channels.AddMember(ev.Channel, ev.User) {
+			// Private channels the bot couldn't see before joining
+			go team.Channels.Get(ev.Channel)
+		}
+		if ev.User == team.Myself.ID {
+			team.Channels.Update(ev.Channel, func(channel *Channel) {
+				channel.IsMember = true
+			})
+		}
+
+	case *slack.MemberLeftChannelEvent:
+		team.Channels.RemoveMember(ev.Channel, ev.User)
+		if ev.User == team.Myself.ID {
+			team.leftChannel(ev.Channel)
+		}
 
 	case *slack.GroupCloseEvent:
-		bot.deleteChannel(ev.Channel)
+		team.deleteChannel(ev.Channel)
 
 	case *slack.GroupArchiveEvent:
-		group := bot.Channels[ev.Channel]
-		group.IsArchived = true
-		bot.updateChannel(group)
+		team.Channels.Update(ev.Channel, func(group *Channel) {
+			group.IsArchived = true
+		})
 
 	case *slack.GroupUnarchiveEvent:
-		group := bot.Channels[ev.Channel]
-		group.IsArchived = false
-		bot.updateChannel(group)
+		team.Channels.Update(ev.Channel, func(group *Channel) {
+			group.IsArchived = false
+		})
 
 	/**
 	 * Handle slack IM changes
@@ -690,17 +944,17 @@ func (bot *Bot) handleRTMEvent(event *slack.RTMEvent) {
 		c.ID = ev.Channel.ID
 		c.User = ev.User
 		c.IsIM = true
-		bot.updateChannel(c)
+		team.updateChannel(c)
 
 	case *slack.IMOpenEvent:
 		c := Channel{}
 		c.ID = ev.Channel
 		c.User = ev.User
 		c.IsIM = true
-		bot.updateChannel(c)
+		team.updateChannel(c)
 
 	case *slack.IMCloseEvent:
-		bot.deleteChannel(ev.Channel)
+		team.deleteChannel(ev.Channel)
 
 	/**
 	 * Errors
@@ -708,26 +962,77 @@ func (bot *Bot) handleRTMEvent(event *slack.RTMEvent) {
 	case *slack.AckErrorEvent:
 		jsonCnt, _ := json.MarshalIndent(ev, "", "  ")
 		log.Warnf("AckErrorEvent: %s", jsonCnt)
+		var sendErr *SendError
+		if !errors.As(ev.ErrorObj, &sendErr) {
+			bot.countAPIError("ack", ev.Error())
+		} else if sendErr.Method != "" {
+			bot.countAPIError(sendErr.Method, apiErrorCode(sendErr.Err))
+		}
+		team.outgoing.failed(ev.ErrorObj)
+
+	case *slack.RateLimitEvent:
+		log.Warnf("RateLimitEvent: too many messages sent")
+		bot.countAPIError("ack", errRateLimited.Error())
+		team.outgoing.failed(errRateLimited)
+
+	case *slack.AckMessage:
+		if reply := team.outgoing.acked(ev.ReplyTo); reply != nil {
+			team.outgoing.posted(reply)
+			team.auditReply(reply, ev.Timestamp, nil)
+		}
 
 	case *slack.ConnectionErrorEvent:
 		log.Warnf("ConnectionErrorEvent: %s", ev)
 
+	/**
+	 * Interactions, from the web server
+	 */
+	case *InteractionEvent:
+		log.WithFields(log.Fields{
+			"Type":     ev.Type,
+			"User":     ev.User,
+			"ActionID": ev.ActionID,
+		}).Debug("Interaction received.")
+
 	default:
 		log.Warnf("Event: %T", ev)
 	}
 
 	// Dispatch listeners
+	// Each Listener gets its own copy of the Message, and handles it
+	// on its own workers, with a context derived from the Listener's.
+	// Plugins restricted to some channels don't get the messages of
+	// the others, see PluginConfig.
+	requestID := newRequestID()
 	for _, listen := range bot.listeners {
+		if listen.isClosed() {
+			continue
+		}
+		if msg != nil && !bot.listenerInScope(listen, team, msg.Channel) {
+			continue
+		}
+		if listen.team != nil && listen.team != team {
+			continue
+		}
+		ctx := WithRequestID(listen.Context(), requestID)
+
 		if msg != nil && listen.MessageHandlerFunc != nil {
-			listen.filterAndDispatchMessage(msg)
+			listenMsg := msg.copy()
+			listenMsg.ctx = ctx
+			if listen.filterMessage(listenMsg) {
+				bot.enqueue(&Dispatch{Listener: listen, Message: listenMsg, Event: listenMsg, Context: ctx})
+			}
 		}
 
 		if listen.EventHandlerFunc != nil {
 			var handleEvent interface{} = event.Data
+			var listenMsg *Message
 			if msg != nil {
-				handleEvent = msg
+				listenMsg = msg.copy()
+				listenMsg.ctx = ctx
+				handleEvent = listenMsg
 			}
-			listen.EventHandlerFunc(listen, handleEvent)
+			bot.enqueue(&Dispatch{Listener: listen, Message: listenMsg, Event: handleEvent, Context: ctx})
 		}
 	}
 
@@ -737,54 +1042,44 @@ func (bot *Bot) handleRTMEvent(event *slack.RTMEvent) {
 func (bot *Bot) Disconnect() {
 	// FIXME: implement a Reconnect() method.. calling the RTM method of the same name.
 	// QUERYME: do we need that, really ?
-	bot.rtm.Disconnect()
+	bot.Transport.Disconnect()
 }
 
 // GetUser returns a *slack.User by ID, Name, RealName or Email
-func (bot *Bot) GetUser(find string) *slack.User {
-	for _, user := range bot.Users {
-		//log.Printf("Hmmmm, %#v", user)
-		if user.Profile.Email == find || user.ID == find || user.Name == find || user.RealName == find {
-			return &user
-		}
+func (team *Team) GetUser(find string) *slack.User {
+	if user, ok := team.Users.Find(find); ok {
+		return &user
 	}
 	return nil
 }
 
 // GetChannelByName returns a *slack.Channel by Name
-func (bot *Bot) GetChannelByName(name string) *Channel {
-	name = strings.TrimLeft(name, "#")
-	for _, channel := range bot.Channels {
-		if channel.Name == name {
-			return &channel
-		}
+func (team *Team) GetChannelByName(name string) *Channel {
+	if channel, ok := team.Channels.ByName(name); ok {
+		return &channel
 	}
 	return nil
 }
 
 // GetIMChannelWith returns the channel used to communicate with the
 // specified slack user
-func (bot *Bot) GetIMChannelWith(user *slack.User) *Channel {
-	for _, channel := range bot.Channels {
-		if !channel.IsIM {
-			continue
-		}
-		if channel.User == user.ID {
-			return &channel
-		}
+func (team *Team) GetIMChannelWith(user *slack.User) *Channel {
+	if channel, ok := team.Channels.IMWith(user.ID); ok {
+		return &channel
 	}
 	return nil
 }
 
 // OpenIMChannelWith opens a conversation with the given slack User
-func (bot *Bot) OpenIMChannelWith(user *slack.User) *Channel {
-	dmChannel := bot.GetIMChannelWith(user)
+func (team *Team) OpenIMChannelWith(user *slack.User) *Channel {
+	bot := team.bot
+	dmChannel := team.GetIMChannelWith(user)
 	if dmChannel != nil {
 		return dmChannel
 	}
 
 	log.Printf("Opening a new IM conversation with %q (%s)", user.ID, user.Name)
-	_, _, chanID, err := bot.Slack.OpenIMChannel(user.ID)
+	chanID, err := team.Transport.OpenIMChannel(bot.ctx, user.ID)
 	if err != nil {
 		return nil
 	}
@@ -794,19 +1089,23 @@ func (bot *Bot) OpenIMChannelWith(user *slack.User) *Channel {
 		IsIM: true,
 		User: user.ID,
 	}
-	bot.updateChannel(c)
+	team.updateChannel(c)
 
 	return &c
 }
 
-func (bot *Bot) updateChannel(channel Channel) {
-	bot.channelUpdateLock.Lock()
-	bot.Channels[channel.ID] = channel
-	bot.channelUpdateLock.Unlock()
+func (team *Team) updateChannel(channel Channel) {
+	team.Channels.Set(channel)
+}
+
+func (team *Team) deleteChannel(id string) {
+	team.Channels.Delete(id)
 }
 
-func (bot *Bot) deleteChannel(id string) {
-	bot.channelUpdateLock.Lock()
-	delete(bot.Channels, id)
-	bot.channelUpdateLock.Unlock()
+// leftChannel records that the bot left a channel.
+func (team *Team) leftChannel(id string) {
+	team.Channels.Update(id, func(channel *Channel) {
+		channel.IsMember = false
+	})
+	team.Channels.RemoveMember(id, team.Myself.ID)
 }
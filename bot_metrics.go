@@ -0,0 +1,120 @@
+package slick
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/nlopes/slack"
+)
+
+// botMetrics are the metrics of the bot's internals, exported with
+// the plugins' in `Bot.Metrics`.
+type botMetrics struct {
+	eventsReceived  *CounterVec
+	reconnects      *CounterVec
+	latency         *GaugeVec
+	apiErrors       *CounterVec
+	handlerDuration *HistogramVec
+}
+
+func (bot *Bot) registerMetrics() {
+	m := bot.Metrics
+
+	bot.metrics = botMetrics{
+		eventsReceived:  m.Counter("slick_events_received_total", "Events received from Slack, by type.", "type"),
+		reconnects:      m.Counter("slick_reconnects_total", "Reconnections to Slack after the first connection."),
+		latency:         m.Gauge("slick_rtm_latency_seconds", "Latency of the RTM websocket, from the last latency report."),
+		apiErrors:       m.Counter("slick_slack_api_errors_total", "Errors returned by Slack, by method and error code.", "method", "error"),
+		handlerDuration: m.Histogram("slick_listener_handler_seconds", "Time spent in listener handlers.", nil, "plugin", "listener"),
+	}
+
+	m.GaugeFunc("slick_outgoing_queue_depth", "Messages waiting to be sent, by all the teams.", func() float64 {
+		depth := 0
+		for _, team := range bot.Teams() {
+			depth += len(team.outgoingMsgCh) + team.outgoing.queued()
+		}
+		return float64(depth)
+	})
+	m.GaugeFunc("slick_listeners", "Active listeners.", func() float64 {
+		return float64(len(bot.ListenerStats()))
+	})
+	m.collectFunc("slick_listener_queue_depth", "Events queued or being handled, by listener.", "gauge", func() []sample {
+		var samples []sample
+		for _, stats := range bot.ListenerStats() {
+			samples = append(samples, sample{labels: []string{"plugin", stats.Plugin, "listener", stats.Name}, value: float64(stats.Queued)})
+		}
+		return samples
+	})
+	m.collectFunc("slick_listener_dropped_events_total", "Events dropped because the listener's queue was full.", "counter", func() []sample {
+		var samples []sample
+		for _, stats := range bot.ListenerStats() {
+			samples = append(samples, sample{labels: []string{"plugin", stats.Plugin, "listener", stats.Name}, value: float64(stats.Dropped)})
+		}
+		return samples
+	})
+	m.GaugeFunc("slick_db_size_bytes", "Size of the BoltDB database.", func() float64 {
+		if bot.DB == nil {
+			return 0
+		}
+		var size int64
+		bot.DB.View(func(tx *bolt.Tx) error {
+			size = tx.Size()
+			return nil
+		})
+		return float64(size)
+	})
+}
+
+// observeDispatch records the duration of a listener's handler. It is
+// deferred around each dispatch.
+func (bot *Bot) observeDispatch(d *Dispatch, start time.Time) {
+	name, plugin := d.Listener.identity()
+	bot.metrics.handlerDuration.Observe(time.Since(start).Seconds(), plugin, name)
+}
+
+// countConnection counts the reconnections, given a ConnectedEvent of
+// `team`.
+func (bot *Bot) countConnection(team *Team) {
+	if team.connected {
+		bot.metrics.reconnects.Inc()
+	}
+	team.connected = true
+}
+
+func (bot *Bot) countAPIError(method, code string) {
+	bot.metrics.apiErrors.Inc(method, code)
+}
+
+// countAPICall counts the error of a call to the Web API `method`, if
+// any, and returns it.
+func (bot *Bot) countAPICall(method string, err error) error {
+	if err != nil {
+		bot.countAPIError(method, apiErrorCode(err))
+	}
+	return err
+}
+
+// apiErrorCodeFormat matches the error codes of the Web API, like
+// "channel_not_found".
+var apiErrorCodeFormat = regexp.MustCompile(`^[a-z_]+$`)
+
+// apiErrorCode returns the Slack error code of `err`, or the kind of
+// failure when Slack didn't answer one, so the metric's labels stay
+// few.
+func apiErrorCode(err error) string {
+	var rateLimited *slack.RateLimitedError
+	switch {
+	case errors.As(err, &rateLimited):
+		return "ratelimited"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case apiErrorCodeFormat.MatchString(err.Error()):
+		return err.Error()
+	}
+	return "request_failed"
+}
@@ -21,7 +21,7 @@ func (r *bugReporter) printReport(days int) (report string) {
 	dayheader := fmt.Sprintf(" BUG REPORT FOR LAST %d DAYS ", days) // 20 spaces
 	bar := "************************"
 
-	report = fmt.Sprintf("/quote " + bar + dayheader + bar + "\n")
+	report = "/quote " + bar + dayheader + bar + "\n"
 
 	report += fmt.Sprintf("|%-45s|%-7s|%-18s|\n", "bug title", "number", "squasher")
 	title := ""
@@ -42,7 +42,7 @@ func (r *bugReporter) printCount(days int) (count string) {
 	dayheader := fmt.Sprintf(" BUG COUNT FOR LAST %d DAYS ", days) // 20 spaces
 	bar := "***"
 
-	count = fmt.Sprintf("/quote " + bar + dayheader + bar + "\n")
+	count = "/quote " + bar + dayheader + bar + "\n"
 	count += fmt.Sprintf("|%-20s|%-10s|\n", "team member", "# squashed")
 
 	bugcount := make(map[string]int)
@@ -1,11 +1,10 @@
 package bugger
 
 import (
+	"context"
 	"fmt"
 	"time"
 
-	log "github.com/sirupsen/logrus"
-
 	"github.com/CapstoneLabs/slick"
 	"github.com/CapstoneLabs/slick/github"
 	"github.com/CapstoneLabs/slick/util"
@@ -22,8 +21,12 @@ type Bugger struct {
 	ghclient github.Client
 }
 
-func (bugger *Bugger) makeBugReporter(days int) (reporter bugReporter) {
+func (bugger *Bugger) makeBugReporter(ctx context.Context, days int) (reporter bugReporter) {
 
+	if len(bugger.ghclient.Conf.Repos) == 0 {
+		slick.Log(ctx).Error("bugger: no github.repos configured")
+		return
+	}
 	repo := bugger.ghclient.Conf.Repos[0]
 
 	query := github.SearchQuery{
@@ -32,9 +35,9 @@ func (bugger *Bugger) makeBugReporter(days int) (reporter bugReporter) {
 		ClosedSince: time.Now().Add(-time.Duration(days) * (24 * time.Hour)).Format("2006-01-02"),
 	}
 
-	issueList, err := bugger.ghclient.DoSearchQuery(query)
+	issueList, err := bugger.ghclient.DoSearchQueryContext(ctx, query)
 	if err != nil {
-		log.Print(err)
+		slick.Log(ctx).Print(err)
 		return
 	}
 
@@ -42,7 +45,7 @@ func (bugger *Bugger) makeBugReporter(days int) (reporter bugReporter) {
 	 * Get an array of issues matching Filters
 	 */
 	issueChan := make(chan github.IssueItem, 1)
-	go bugger.ghclient.DoEventQuery(issueList, repo, issueChan)
+	go bugger.ghclient.DoEventQueryContext(ctx, issueList, repo, issueChan)
 
 	reporter.Git2Hip = bugger.ghclient.Conf.Github2Hipchat
 
@@ -53,6 +56,12 @@ func (bugger *Bugger) makeBugReporter(days int) (reporter bugReporter) {
 	return
 }
 
+// ConfigSchema declares the Github section of the config, loaded before
+// InitPlugin. See `slick.ConfigSchema`.
+func (bugger *Bugger) ConfigSchema() (string, interface{}) {
+	return "Github", &bugger.ghclient.Conf
+}
+
 func (bugger *Bugger) InitPlugin(bot *slick.Bot) {
 
 	/*
@@ -60,16 +69,6 @@ func (bugger *Bugger) InitPlugin(bot *slick.Bot) {
 	 */
 	bugger.bot = bot
 
-	var conf struct {
-		Github github.Conf
-	}
-
-	bot.LoadConfig(&conf)
-
-	bugger.ghclient = github.Client{
-		Conf: conf.Github,
-	}
-
 	bot.Listen(&slick.Listener{
 		MessageHandlerFunc: bugger.ChatHandler,
 	})
@@ -91,7 +90,7 @@ func (bugger *Bugger) ChatHandler(listen *slick.Listener, msg *slick.Message) {
 		} else {
 			report = "bug count"
 		}
-		mention := bugger.bot.Config.Nickname
+		mention := bugger.bot.Config().Nickname
 
 		msg.Reply(fmt.Sprintf(
 			`Usage: %s, [give me a | insert demand]  <%s>  [from the | syntax filler] [last | past] [n] [days | weeks]
@@ -104,7 +103,7 @@ examples: %s, please give me a %s over the last 5 days
 
 		days := util.GetDaysFromQuery(msg.Text)
 		bugger.messageReport(days, msg, listen, func() string {
-			reporter := bugger.makeBugReporter(days)
+			reporter := bugger.makeBugReporter(msg.Context(), days)
 			return reporter.printReport(days)
 		})
 
@@ -112,7 +111,7 @@ examples: %s, please give me a %s over the last 5 days
 
 		days := util.GetDaysFromQuery(msg.Text)
 		bugger.messageReport(days, msg, listen, func() string {
-			reporter := bugger.makeBugReporter(days)
+			reporter := bugger.makeBugReporter(msg.Context(), days)
 			return reporter.printCount(days)
 		})
 
@@ -86,3 +86,35 @@ func ChannelFromSlackIM(im slack.IM) Channel {
 		IsIM:          true,
 	}
 }
+
+// ChannelFromSlackConversation converts a conversation, as returned by
+// `conversations.list`, to a Channel Struct
+func ChannelFromSlackConversation(conversation slack.Channel) Channel {
+	c := Channel{
+		ID:         conversation.ID,
+		Created:    conversation.Created.Time(),
+		IsOpen:     conversation.IsOpen,
+		LastRead:   conversation.LastRead,
+		Name:       conversation.Name,
+		Creator:    conversation.Creator,
+		Members:    conversation.Members,
+		IsMember:   conversation.IsMember,
+		IsArchived: conversation.IsArchived,
+		Topic:      conversation.Topic,
+		Purpose:    conversation.Purpose,
+	}
+
+	switch {
+	case conversation.IsIM:
+		c.IsIM = true
+		c.Name = conversation.User
+		c.User = conversation.User
+	case conversation.IsPrivate, conversation.IsGroup, conversation.IsMpIM:
+		c.IsGroup = true
+	default:
+		c.IsChannel = true
+		c.IsGeneral = conversation.IsGeneral
+	}
+
+	return c
+}
@@ -154,3 +154,26 @@ func TestChannelFromSlackIM(t *testing.T) {
 
 	assertChannelFromSlackIM(t, *slackIM, channel)
 }
+
+func TestChannelFromSlackConversation(t *testing.T) {
+	for _, tc := range []struct {
+		json                       string
+		isChannel, isGroup, isIM   bool
+		expectedName, expectedUser string
+	}{
+		{simpleChannel, true, false, false, "fun", ""},
+		{simpleGroup, false, true, false, "secretplans", ""},
+		{simpleIM, false, false, true, "U024BE7LH", "U024BE7LH"},
+	} {
+		conversation, err := unmarshalChannel(tc.json)
+		assert.Nil(t, err)
+		channel := ChannelFromSlackConversation(*conversation)
+
+		assert.Equal(t, conversation.ID, channel.ID)
+		assert.Equal(t, tc.isChannel, channel.IsChannel)
+		assert.Equal(t, tc.isGroup, channel.IsGroup)
+		assert.Equal(t, tc.isIM, channel.IsIM)
+		assert.Equal(t, tc.expectedName, channel.Name)
+		assert.Equal(t, tc.expectedUser, channel.User)
+	}
+}
@@ -0,0 +1,454 @@
+package slick
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandPrefix starts every command typed in chat, as in `!help`.
+const CommandPrefix = "!"
+
+// ArgType is the kind of value expected by a CommandArg.
+type ArgType int
+
+const (
+	// ArgWord is a single word, without spaces.
+	ArgWord ArgType = iota
+	// ArgUser is a user, as a mention (`@bob`), a username, or an ID.
+	// It is parsed as a `*slack.User`.
+	ArgUser
+	// ArgChannel is a channel, as a link (`#general`), a name or an
+	// ID. It is parsed as a `*Channel`.
+	ArgChannel
+	// ArgDuration is a duration like `5m` or `1h30m`, parsed as a
+	// `time.Duration`.
+	ArgDuration
+	// ArgInt is an integer.
+	ArgInt
+	// ArgRest is the rest of the line, spaces included. It can only be
+	// the last argument.
+	ArgRest
+)
+
+// CommandArg describes one argument of a Command.
+type CommandArg struct {
+	Name string
+	Type ArgType
+
+	// Optional arguments can be omitted, as long as no required
+	// arguments follow them.
+	Optional bool
+}
+
+func (arg CommandArg) String() string {
+	name := arg.Name
+	if arg.Type == ArgRest {
+		name += "..."
+	}
+	if arg.Optional {
+		return "[" + name + "]"
+	}
+	return "<" + name + ">"
+}
+
+// Command is a chat command, like `!vote Food Place`, registered with
+// `Bot.Command`. The arguments are parsed before calling HandlerFunc,
+// and the user gets the usage in reply when they don't parse.
+type Command struct {
+	// Name is what follows the CommandPrefix, like "vote" for `!vote`.
+	Name string
+
+	// Aliases are other names triggering the same command.
+	Aliases []string
+
+	// Args describes the arguments, in order.
+	Args []CommandArg
+
+	// Usage is a short description of the command, shown by `!help`.
+	Usage string
+
+	// PrivateOnly and PublicOnly restrict where the command can be
+	// used, like on a Listener.
+	PrivateOnly bool
+	PublicOnly  bool
+
+	// Permission, when set, is needed to run the command, see
+	// `Bot.Can`. Others get a reply saying so.
+	Permission string
+
+	// Restricted commands need a role granting their Permission: until
+	// the config defines roles, they only work in the `admin_channel`.
+	Restricted bool
+
+	// HandlerFunc is called with the parsed arguments when the command
+	// is typed.
+	HandlerFunc func(*Command, *Message, CommandArgs)
+
+	// Bot is a reference to the bot instance, populated by
+	// `Bot.Command`.
+	Bot *Bot
+
+	// plugin is the package which registered the Command, see
+	// `callerPlugin`.
+	plugin string
+
+	queueOnce sync.Once
+	queue     *commandQueue
+
+	// panics counts the panics of the handler, see `recoverCommand`.
+	panics int32
+	// disabled is set once the handler panicked `max_listener_panics`
+	// times.
+	disabled int32
+}
+
+// Synopsis returns the command line, like "!vote <choice...>".
+func (cmd *Command) Synopsis() string {
+	parts := []string{CommandPrefix + cmd.Name}
+	for _, arg := range cmd.Args {
+		parts = append(parts, arg.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+func (cmd *Command) checkParams() error {
+	if cmd.Name == "" {
+		return fmt.Errorf("`Name` is required")
+	}
+	if cmd.HandlerFunc == nil {
+		return fmt.Errorf("`HandlerFunc` is required")
+	}
+	if cmd.PrivateOnly && cmd.PublicOnly {
+		return fmt.Errorf("`PrivateOnly` and `PublicOnly` are mutually exclusive")
+	}
+
+	seenOptional := false
+	for i, arg := range cmd.Args {
+		if arg.Type == ArgRest && i != len(cmd.Args)-1 {
+			return fmt.Errorf("argument %q: ArgRest must be the last argument", arg.Name)
+		}
+		if seenOptional && !arg.Optional {
+			return fmt.Errorf("argument %q: required arguments can't follow optional ones", arg.Name)
+		}
+		seenOptional = seenOptional || arg.Optional
+	}
+
+	return nil
+}
+
+// CommandArgs holds the parsed arguments of a Command, by name.
+// Omitted optional arguments are absent.
+type CommandArgs map[string]interface{}
+
+// Has tells whether the argument was provided.
+func (args CommandArgs) Has(name string) bool {
+	_, ok := args[name]
+	return ok
+}
+
+// String returns an ArgWord or ArgRest argument.
+func (args CommandArgs) String(name string) string {
+	s, _ := args[name].(string)
+	return s
+}
+
+// Int returns an ArgInt argument.
+func (args CommandArgs) Int(name string) int {
+	i, _ := args[name].(int)
+	return i
+}
+
+// Duration returns an ArgDuration argument.
+func (args CommandArgs) Duration(name string) time.Duration {
+	d, _ := args[name].(time.Duration)
+	return d
+}
+
+// User returns an ArgUser argument.
+func (args CommandArgs) User(name string) *slack.User {
+	u, _ := args[name].(*slack.User)
+	return u
+}
+
+// Channel returns an ArgChannel argument.
+func (args CommandArgs) Channel(name string) *Channel {
+	c, _ := args[name].(*Channel)
+	return c
+}
+
+// commandRegistry indexes the registered commands by name and alias.
+type commandRegistry struct {
+	lock     sync.RWMutex
+	commands []*Command
+	byName   map[string]*Command
+}
+
+// Command registers a chat command. It fails if the command is
+// malformed, or if its name or one of its aliases is already taken.
+func (bot *Bot) Command(cmd *Command) error {
+	cmd.Bot = bot
+	cmd.plugin = callerPlugin()
+
+	if err := cmd.checkParams(); err != nil {
+		log.Println("Bot.Command(): Invalid Command: ", err)
+		return err
+	}
+
+	reg := &bot.commands
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+
+	if reg.byName == nil {
+		reg.byName = make(map[string]*Command)
+	}
+
+	names := append([]string{cmd.Name}, cmd.Aliases...)
+	for _, name := range names {
+		if _, taken := reg.byName[strings.ToLower(name)]; taken {
+			err := fmt.Errorf("command %q is already registered", name)
+			log.Println("Bot.Command(): ", err)
+			return err
+		}
+	}
+
+	for _, name := range names {
+		reg.byName[strings.ToLower(name)] = cmd
+	}
+	reg.commands = append(reg.commands, cmd)
+
+	return nil
+}
+
+// Commands returns the registered commands, sorted by name.
+func (bot *Bot) Commands() []*Command {
+	reg := &bot.commands
+	reg.lock.RLock()
+	defer reg.lock.RUnlock()
+
+	commands := make([]*Command, len(reg.commands))
+	copy(commands, reg.commands)
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	return commands
+}
+
+// GetCommand finds a command by name or alias.
+func (bot *Bot) GetCommand(name string) *Command {
+	reg := &bot.commands
+	reg.lock.RLock()
+	defer reg.lock.RUnlock()
+
+	return reg.byName[strings.ToLower(strings.TrimPrefix(name, CommandPrefix))]
+}
+
+// listenCommands registers the Listener dispatching commands, and the
+// built-in `!help`.
+func (bot *Bot) listenCommands() {
+	bot.Command(&Command{
+		Name:        "help",
+		Args:        []CommandArg{{Name: "command", Type: ArgWord, Optional: true}},
+		Usage:       "lists the commands, or explains one of them",
+		HandlerFunc: bot.helpCommand,
+	})
+
+	bot.Listen(&Listener{
+		MessageHandlerFunc: bot.dispatchCommand,
+	})
+}
+
+// dispatchCommand finds the command typed in `msg`, and queues it on
+// the Command's workers, so a slow command doesn't hold the others
+// back.
+func (bot *Bot) dispatchCommand(listen *Listener, msg *Message) {
+	if !strings.HasPrefix(msg.Text, CommandPrefix) {
+		return
+	}
+
+	line := strings.TrimPrefix(msg.Text, CommandPrefix)
+	name := line
+	rest := ""
+	if idx := strings.IndexAny(line, " \t\n"); idx != -1 {
+		name, rest = line[:idx], line[idx+1:]
+	}
+
+	cmd := bot.GetCommand(name)
+	if cmd == nil {
+		return
+	}
+
+	cmd.enqueue(msg.Channel, func() { bot.runCommand(cmd, msg, rest) })
+}
+
+// runCommand checks where and by whom `cmd` can be run, parses its
+// arguments `rest` and calls its HandlerFunc, recovering from its
+// panics.
+func (bot *Bot) runCommand(cmd *Command, msg *Message, rest string) {
+	if atomic.LoadInt32(&cmd.disabled) != 0 {
+		return
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			bot.recoverCommand(cmd, msg, p)
+		}
+	}()
+
+	if cmd.PrivateOnly && !msg.IsPrivate() {
+		return
+	}
+	if cmd.PublicOnly && msg.IsPrivate() {
+		return
+	}
+	if !bot.inScope(msg.Workspace(), cmd.pluginPackage(), msg.Channel) {
+		return
+	}
+	if !bot.Can(msg.FromUser, cmd.Permission) {
+		bot.denyPermission(msg, cmd.Permission)
+		bot.auditCommand(msg, cmd, rest, AuditDenied, nil)
+		return
+	}
+	if cmd.Restricted && !bot.hasRoles() && !bot.inAdminChannel(msg) {
+		msg.ReplyMention("`%s%s` only works in the admin channel, until roles grant `%s`", CommandPrefix, cmd.Name, cmd.Permission)
+		bot.auditCommand(msg, cmd, rest, AuditDenied, nil)
+		return
+	}
+
+	args, err := msg.Workspace().parseCommandArgs(cmd, rest)
+	if err != nil {
+		bot.auditCommand(msg, cmd, rest, AuditFailed, err)
+		msg.ReplyMention("%s\nusage: `%s`", err, cmd.Synopsis())
+		return
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			bot.auditCommand(msg, cmd, rest, AuditFailed, fmt.Errorf("panic: %v", p))
+			panic(p)
+		}
+		bot.auditCommand(msg, cmd, rest, AuditOK, nil)
+	}()
+	cmd.HandlerFunc(cmd, msg, args)
+}
+
+func (team *Team) parseCommandArgs(cmd *Command, text string) (CommandArgs, error) {
+	args := make(CommandArgs)
+	text = strings.TrimSpace(text)
+
+	for _, arg := range cmd.Args {
+		if text == "" {
+			if arg.Optional {
+				break
+			}
+			return nil, fmt.Errorf("missing %s", arg)
+		}
+
+		if arg.Type == ArgRest {
+			args[arg.Name] = text
+			text = ""
+			break
+		}
+
+		word := text
+		text = ""
+		if idx := strings.IndexAny(word, " \t\n"); idx != -1 {
+			word, text = word[:idx], strings.TrimSpace(word[idx+1:])
+		}
+
+		value, err := team.parseCommandArg(arg, word)
+		if err != nil {
+			return nil, err
+		}
+		args[arg.Name] = value
+	}
+
+	if text != "" {
+		return nil, fmt.Errorf("too many arguments: %q", text)
+	}
+
+	return args, nil
+}
+
+var (
+	reUserArg    = regexp.MustCompile(`^<@([A-Z0-9]+)(\|[^>]*)?>$`)
+	reChannelArg = regexp.MustCompile(`^<#([A-Z0-9]+)(\|[^>]*)?>$`)
+)
+
+func (team *Team) parseCommandArg(arg CommandArg, word string) (interface{}, error) {
+	switch arg.Type {
+	case ArgUser:
+		if match := reUserArg.FindStringSubmatch(word); match != nil {
+			word = match[1]
+		}
+		user := team.GetUser(strings.TrimPrefix(word, "@"))
+		if user == nil {
+			return nil, fmt.Errorf("unknown user %q for %s", word, arg)
+		}
+		return user, nil
+
+	case ArgChannel:
+		if match := reChannelArg.FindStringSubmatch(word); match != nil {
+			word = match[1]
+		}
+		if channel, ok := team.Channels.Get(word); ok {
+			return &channel, nil
+		}
+		channel := team.GetChannelByName(word)
+		if channel == nil {
+			return nil, fmt.Errorf("unknown channel %q for %s", word, arg)
+		}
+		return channel, nil
+
+	case ArgDuration:
+		d, err := time.ParseDuration(word)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q (ex: 5m, 1h30m)", arg, word)
+		}
+		return d, nil
+
+	case ArgInt:
+		i, err := strconv.Atoi(word)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", arg, word)
+		}
+		return i, nil
+	}
+
+	return word, nil
+}
+
+func (bot *Bot) helpCommand(_ *Command, msg *Message, args CommandArgs) {
+	if args.Has("command") {
+		cmd := bot.GetCommand(args.String("command"))
+		if cmd == nil {
+			msg.ReplyMention("I don't know the command %q, try `%shelp`", args.String("command"), CommandPrefix)
+			return
+		}
+
+		out := []string{fmt.Sprintf("`%s`", cmd.Synopsis())}
+		if cmd.Usage != "" {
+			out = append(out, cmd.Usage)
+		}
+		if len(cmd.Aliases) != 0 {
+			out = append(out, fmt.Sprintf("aliases: %s%s", CommandPrefix, strings.Join(cmd.Aliases, ", "+CommandPrefix)))
+		}
+		msg.Reply(strings.Join(out, "\n"))
+		return
+	}
+
+	out := []string{"Here's what I understand:"}
+	for _, cmd := range bot.Commands() {
+		line := fmt.Sprintf("• `%s`", cmd.Synopsis())
+		if cmd.Usage != "" {
+			line += " - " + cmd.Usage
+		}
+		out = append(out, line)
+	}
+	msg.Reply(strings.Join(out, "\n"))
+}
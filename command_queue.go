@@ -0,0 +1,52 @@
+package slick
+
+import (
+	"hash/fnv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandWorkers is the number of goroutines running each Command.
+// The commands typed in a given channel run in order, on the same
+// worker.
+const CommandWorkers = 4
+
+// commandQueue runs the calls of a Command on its own workers, so a
+// slow command doesn't delay the others. Each worker has a bounded
+// queue: when it's full, calls are dropped.
+type commandQueue struct {
+	queues []chan func()
+}
+
+// enqueue queues `run` on the worker of `channel`, starting the
+// workers on first use.
+func (cmd *Command) enqueue(channel string, run func()) {
+	cmd.queueOnce.Do(func() {
+		cmd.queue = &commandQueue{}
+		for i := 0; i < CommandWorkers; i++ {
+			queue := make(chan func(), DefaultListenerQueueSize)
+			cmd.queue.queues = append(cmd.queue.queues, queue)
+			go cmd.worker(queue)
+		}
+	})
+
+	hash := fnv.New32a()
+	hash.Write([]byte(channel))
+	queue := cmd.queue.queues[hash.Sum32()%uint32(len(cmd.queue.queues))]
+
+	select {
+	case queue <- run:
+	default:
+		log.WithFields(log.Fields{
+			"Type":    "CommandQueueFull",
+			"Command": cmd.Name,
+			"Channel": channel,
+		}).Warn("Command queue is full, dropping command.")
+	}
+}
+
+func (cmd *Command) worker(queue chan func()) {
+	for run := range queue {
+		run()
+	}
+}
@@ -0,0 +1,107 @@
+package slick
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCommandTestBot() *Bot {
+	bot := New("")
+	bot.Users.Set(slack.User{ID: "U1", Name: "bob"})
+	bot.Channels.Set(Channel{ID: "C1", Name: "general", IsChannel: true})
+	return bot
+}
+
+func TestCommandParseArgs(t *testing.T) {
+	bot := newCommandTestBot()
+	cmd := &Command{
+		Name: "remind",
+		Args: []CommandArg{
+			{Name: "who", Type: ArgUser},
+			{Name: "where", Type: ArgChannel},
+			{Name: "in", Type: ArgDuration},
+			{Name: "times", Type: ArgInt},
+			{Name: "what", Type: ArgRest, Optional: true},
+		},
+	}
+	assert.Equal(t, "!remind <who> <where> <in> <times> [what...]", cmd.Synopsis())
+
+	args, err := bot.parseCommandArgs(cmd, "<@U1> <#C1|general> 5m 3 feed  the cat")
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", args.User("who").Name)
+	assert.Equal(t, "general", args.Channel("where").Name)
+	assert.Equal(t, 5*time.Minute, args.Duration("in"))
+	assert.Equal(t, 3, args.Int("times"))
+	assert.Equal(t, "feed  the cat", args.String("what"))
+
+	args, err = bot.parseCommandArgs(cmd, "@bob #general 1h 1")
+	assert.NoError(t, err)
+	assert.False(t, args.Has("what"))
+
+	_, err = bot.parseCommandArgs(cmd, "@bob #general")
+	assert.EqualError(t, err, "missing <in>")
+
+	_, err = bot.parseCommandArgs(cmd, "@alice #general 1h 1")
+	assert.Error(t, err)
+
+	_, err = bot.parseCommandArgs(cmd, "@bob #general soon 1")
+	assert.Error(t, err)
+
+	_, err = bot.parseCommandArgs(cmd, "@bob #general 1h one")
+	assert.Error(t, err)
+
+	_, err = bot.parseCommandArgs(&Command{Name: "ping"}, "pong")
+	assert.Error(t, err)
+}
+
+func TestCommandRegistration(t *testing.T) {
+	bot := newCommandTestBot()
+	handler := func(*Command, *Message, CommandArgs) {}
+
+	assert.NoError(t, bot.Command(&Command{Name: "vote", Aliases: []string{"v"}, HandlerFunc: handler}))
+	assert.Error(t, bot.Command(&Command{Name: "V", HandlerFunc: handler}))
+	assert.Error(t, bot.Command(&Command{Name: "nohandler"}))
+	assert.Error(t, bot.Command(&Command{
+		Name:        "badargs",
+		Args:        []CommandArg{{Name: "rest", Type: ArgRest}, {Name: "word"}},
+		HandlerFunc: handler,
+	}))
+
+	assert.Equal(t, "vote", bot.GetCommand("!v").Name)
+	assert.Nil(t, bot.GetCommand("nohandler"))
+	assert.Len(t, bot.Commands(), 1)
+}
+
+func TestCommandQueues(t *testing.T) {
+	bot := newCommandTestBot()
+	transport := newFakeTransport()
+	bot.Transport = transport
+	go bot.replyHandler()
+	defer close(bot.stopCh)
+	bot.listenCommands()
+
+	release := make(chan struct{})
+	defer close(release)
+	bot.Command(&Command{
+		Name:        "slow",
+		HandlerFunc: func(*Command, *Message, CommandArgs) { <-release },
+	})
+
+	post := func(text string) {
+		msg := &Message{Msg: &slack.Msg{Text: text, Channel: "C1", User: "U1"}, bot: bot, team: bot.Team}
+		msg.resolve()
+		bot.dispatchCommand(nil, msg)
+	}
+	post("!slow")
+	post("!help")
+
+	select {
+	case sent := <-transport.sent:
+		assert.Contains(t, sent.Text, "!slow")
+	case <-time.After(time.Second):
+		t.Error("a blocked command delayed !help")
+	}
+}
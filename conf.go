@@ -2,7 +2,9 @@ package slick
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"time"
 )
 
 // SlackConfig holds the configuration to connect with a given slack organization
@@ -14,10 +16,93 @@ type SlackConfig struct {
 	GeneralChannel string   `json:"general_channel" mapstructure:"general_channel"`
 	TeamDomain     string   `json:"team_domain" mapstructure:"team_domain"`
 	TeamID         string   `json:"team_id" mapstructure:"team_id"`
-	ApiToken       string   `json:"api_token" mapstructure:"api_token"`
+	ApiToken       string   `json:"api_token" mapstructure:"api_token" required:"true"`
 	WebBaseURL     string   `json:"web_base_url" mapstructure:"web_base_url"`
 	DBPath         string   `json:"db_path" mapstructure:"db_path"`
 	Debug          bool
+
+	// ConnectionMode is one of "rtm" (the default), "socket" for
+	// Socket Mode, or "events" for the Events API, served by the
+	// WebServer on /public/slack/events.
+	ConnectionMode string `json:"connection_mode" mapstructure:"connection_mode"`
+	// AppToken is the app-level token (xapp-...) used by Socket Mode.
+	AppToken string `json:"app_token" mapstructure:"app_token"`
+	// SigningSecret verifies the requests sent by Slack to the Events
+	// API and interactions endpoints. Each team verifies its own
+	// interactions.
+	SigningSecret string `json:"signing_secret" mapstructure:"signing_secret"`
+	// AdminChannel receives the reports of the bot's failures, like
+	// panicking listeners.
+	AdminChannel string `json:"admin_channel" mapstructure:"admin_channel"`
+	// AuditRetentionDays is how long the entries of the audit log are
+	// kept. Zero, the default, keeps them for ever.
+	AuditRetentionDays int `json:"audit_retention_days" mapstructure:"audit_retention_days"`
+	// MaxListenerPanics disables listeners and commands after that
+	// many panics. Zero means never.
+	MaxListenerPanics int `json:"max_listener_panics" mapstructure:"max_listener_panics"`
+	// Storage is the StorageBackend: "bolt" (the default, at
+	// `db_path`), "memory", or "sqlite" with `storage_dsn`.
+	Storage    string `json:"storage" mapstructure:"storage"`
+	StorageDSN string `json:"storage_dsn" mapstructure:"storage_dsn"`
+	// MessageRate and ChannelMessageRate limit the messages sent, in
+	// messages per second, for the workspace and for each channel.
+	// They default to DefaultMessageRate and DefaultChannelMessageRate.
+	MessageRate        float64 `json:"message_rate" mapstructure:"message_rate"`
+	ChannelMessageRate float64 `json:"channel_message_rate" mapstructure:"channel_message_rate"`
+	// Timezone is the default time zone of the Scheduler's jobs, like
+	// "America/Montreal". It defaults to the system's.
+	Timezone string `json:"timezone" mapstructure:"timezone"`
+}
+
+// Validate checks the settings depending on each other, see
+// ConfigValidator.
+func (c *SlackConfig) Validate() error {
+	switch c.ConnectionMode {
+	case "", "rtm":
+	case "socket":
+		if c.AppToken == "" {
+			return errors.New("Socket Mode needs an `app_token`")
+		}
+	case "events":
+		if c.SigningSecret == "" {
+			return errors.New("the Events API needs a `signing_secret`")
+		}
+	default:
+		return fmt.Errorf("unknown connection_mode %q, use one of \"rtm\", \"socket\" or \"events\"", c.ConnectionMode)
+	}
+
+	switch c.Storage {
+	case "", "bolt", "memory":
+	case "sqlite":
+		if c.StorageDSN == "" {
+			return errors.New("the sqlite storage needs a `storage_dsn`")
+		}
+		if err := checkSQLDriver(SQLiteDriver); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown storage %q, expected \"bolt\", \"memory\" or \"sqlite\"", c.Storage)
+	}
+
+	if c.AuditRetentionDays < 0 {
+		return errors.New("`audit_retention_days` can't be negative")
+	}
+
+	if c.MessageRate < 0 || c.ChannelMessageRate < 0 {
+		return errors.New("`message_rate` and `channel_message_rate` can't be negative")
+	}
+
+	if c.Timezone != "" {
+		if _, err := time.LoadLocation(c.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone: %s", err)
+		}
+	}
+	return nil
+}
+
+// ServerConfig holds the settings of the bot's process.
+type ServerConfig struct {
+	Pidfile string `mapstructure:"pid_file"`
 }
 
 type ChatPluginConfig struct {
@@ -0,0 +1,238 @@
+package slick
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// ConfigSchema describes the plugins declaring their section of the
+// config file. ConfigSchema returns the section's name, like
+// "Recognition", and a pointer to the struct it is loaded into.
+//
+// The section is loaded and checked before InitPlugin, along with the
+// other plugins' sections, and the bot refuses to start with a report
+// of all the errors found. The struct's fields can be tagged:
+//
+//	Channel string `mapstructure:"channel" required:"true"`
+//	Days    int    `mapstructure:"days" default:"7"`
+//
+// `default` is used when the field is left empty, and `required`
+// fields can't be. For other checks, the struct can implement
+// ConfigValidator.
+type ConfigSchema interface {
+	ConfigSchema() (section string, config interface{})
+}
+
+// ConfigValidator is implemented by config structs with checks beyond
+// the `required` and `default` tags. Validate is called once those are
+// applied.
+type ConfigValidator interface {
+	Validate() error
+}
+
+// ConfigErrors lists all the errors found in a config file.
+type ConfigErrors []error
+
+func (errs ConfigErrors) Error() string {
+	lines := []string{fmt.Sprintf("%d error(s) in the config file:", len(errs))}
+	for _, err := range errs {
+		lines = append(lines, "  - "+err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CheckConfig reads the config file, and checks the Slack section and
+// the sections of the plugins implementing ConfigSchema, without
+// connecting to Slack. It returns ConfigErrors.
+func (bot *Bot) CheckConfig() error {
+	bot.readInConfig()
+	return checkConfig(bot.configViper(), false)
+}
+
+// LoadPluginConfig loads the `section` of the config file into the
+// struct pointed to by `config`, and checks it like the ConfigSchema
+// sections. Use it in ConfigReloader plugins.
+func (bot *Bot) LoadPluginConfig(section string, config interface{}) error {
+	var errs ConfigErrors
+	errs = loadSection(bot.configViper(), section, config, errs)
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkConfig checks the config read by `v`. The plugins' sections are
+// loaded in their ConfigSchema structs, or in new ones if `fresh`, so
+// that a config can be checked without changing the plugins'.
+func checkConfig(v *viper.Viper, fresh bool) error {
+	var errs ConfigErrors
+
+	var slackConfig SlackConfig
+	errs = loadSection(v, "Slack", &slackConfig, errs)
+
+	// Disabled plugins don't need a valid config
+	var pluginConfigs map[string]PluginConfig
+	if err := decodeSection(v, "Plugins", &pluginConfigs); err != nil {
+		errs = append(errs, fmt.Errorf("plugins: %s", err))
+	}
+
+	var roles map[string]Role
+	if err := decodeSection(v, "Roles", &roles); err != nil {
+		errs = append(errs, fmt.Errorf("roles: %s", err))
+	}
+	errs = append(errs, checkRoles(roles)...)
+
+	var teams map[string]SlackConfig
+	if err := decodeSection(v, "Teams", &teams); err != nil {
+		errs = append(errs, fmt.Errorf("teams: %s", err))
+	}
+	errs = checkTeams(teams, errs)
+
+	for _, plugin := range pluginsEnabledBy(pluginConfigs) {
+		schema, ok := plugin.(ConfigSchema)
+		if !ok {
+			continue
+		}
+
+		section, config := schema.ConfigSchema()
+		if fresh {
+			config = reflect.New(reflect.TypeOf(config).Elem()).Interface()
+		}
+		errs = loadSection(v, section, config, errs)
+	}
+
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+// loadSection loads and checks a section of the config, and appends
+// the errors found to `errs`.
+func loadSection(v *viper.Viper, section string, config interface{}, errs ConfigErrors) ConfigErrors {
+	prefix := strings.ToLower(section)
+	if err := decodeSection(v, section, config); err != nil {
+		return append(errs, fmt.Errorf("%s: %s", prefix, err))
+	}
+
+	errs = checkFields(prefix, reflect.ValueOf(config).Elem(), errs)
+
+	if validator, ok := config.(ConfigValidator); ok {
+		if err := validator.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", prefix, err))
+		}
+	}
+	return errs
+}
+
+// decodeSection decodes a section of the config like
+// `viper.UnmarshalKey`, which misses the values overridden by the
+// environment, see `applyConfigOverrides`.
+func decodeSection(v *viper.Viper, section string, config interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           config,
+		WeaklyTypedInput: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(v.AllSettings()[strings.ToLower(section)])
+}
+
+// fieldKey returns the config key of a struct field, as viper sees it.
+func fieldKey(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("mapstructure"), ",")[0]
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// checkFields applies the `default` and `required` tags of a struct,
+// and of the structs it contains.
+func checkFields(prefix string, value reflect.Value, errs ConfigErrors) ConfigErrors {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := prefix + "." + fieldKey(field)
+
+		fieldValue := value.Field(i)
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}) {
+			errs = checkFields(name, fieldValue, errs)
+			continue
+		}
+
+		if def, ok := field.Tag.Lookup("default"); ok && fieldValue.IsZero() {
+			if err := setDefault(fieldValue, def); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid default %q: %s", name, def, err))
+			}
+		}
+
+		if field.Tag.Get("required") == "true" && fieldValue.IsZero() {
+			errs = append(errs, fmt.Errorf("%s is required", name))
+		}
+	}
+	return errs
+}
+
+func setDefault(value reflect.Value, def string) error {
+	if value.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(def)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(def)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(def)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(def, 10, 64)
+		if err != nil {
+			return err
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(def, 10, 64)
+		if err != nil {
+			return err
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(def, 64)
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", value.Type())
+		}
+		value.Set(reflect.ValueOf(strings.Split(def, ",")).Convert(value.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
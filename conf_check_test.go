@@ -0,0 +1,74 @@
+package slick
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+type schemaConfig struct {
+	Channel string        `mapstructure:"channel" required:"true"`
+	Days    int           `default:"7"`
+	Every   time.Duration `default:"1h"`
+	Rooms   []string      `default:"one,two"`
+	Remote  struct {
+		URL string `mapstructure:"url" required:"true"`
+	}
+}
+
+func (c *schemaConfig) Validate() error {
+	if c.Days > 31 {
+		return errors.New("that's too many days")
+	}
+	return nil
+}
+
+type schemaPlugin struct {
+	config schemaConfig
+}
+
+func (p *schemaPlugin) ConfigSchema() (string, interface{}) {
+	return "Schema", &p.config
+}
+
+func readTestConfig(t *testing.T, content string) *viper.Viper {
+	v := viper.New()
+	v.SetConfigType("json")
+	assert.NoError(t, v.ReadConfig(strings.NewReader(content)))
+	return v
+}
+
+func TestCheckConfig(t *testing.T) {
+	plugin := &schemaPlugin{}
+	defer func(plugins []Plugin) { registeredPlugins = plugins }(registeredPlugins)
+	registeredPlugins = []Plugin{plugin}
+
+	v := readTestConfig(t, `{
+		"Slack": {"api_token": "xoxb-1"},
+		"Schema": {"channel": "general", "remote": {"url": "http://example.com"}}
+	}`)
+	assert.NoError(t, checkConfig(v, false))
+	assert.Equal(t, "general", plugin.config.Channel)
+	assert.Equal(t, 7, plugin.config.Days)
+	assert.Equal(t, time.Hour, plugin.config.Every)
+	assert.Equal(t, []string{"one", "two"}, plugin.config.Rooms)
+
+	v = readTestConfig(t, `{
+		"Slack": {"connection_mode": "carrier-pigeon"},
+		"Schema": {"days": 90}
+	}`)
+	err := checkConfig(v, true)
+	assert.Equal(t, ConfigErrors{
+		errors.New("slack.api_token is required"),
+		errors.New(`slack: unknown connection_mode "carrier-pigeon", use one of "rtm", "socket" or "events"`),
+		errors.New("schema.channel is required"),
+		errors.New("schema.remote.url is required"),
+		errors.New("schema: that's too many days"),
+	}, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "5 error(s) in the config file:\n  - slack.api_token is required\n"))
+	assert.Equal(t, "general", plugin.config.Channel, "fresh checks don't change the plugins' config")
+}
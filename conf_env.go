@@ -0,0 +1,162 @@
+package slick
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix starts the environment variables overriding the config
+// file. The key is upper-cased, with its dots replaced by underscores:
+// SLICK_SLACK_API_TOKEN sets the `api_token` of the Slack section.
+// Keys which are neither in the config file nor declared by a
+// ConfigSchema use double underscores between their parts, like
+// SLICK_HOOKER__STRIPE_SECRET.
+const EnvPrefix = "SLICK_"
+
+// secretFileSuffix ends the keys naming a file to read a value from,
+// like `api_token_file` in the config file, or
+// SLICK_SLACK_API_TOKEN_FILE in the environment. It suits the secrets
+// mounted in containers.
+const secretFileSuffix = "_file"
+
+// applyConfigOverrides sets the values read from secret files and from
+// the environment in `v`. Environment variables take precedence over
+// secret files, which take precedence over the config file.
+func applyConfigOverrides(v *viper.Viper) error {
+	declared := declaredConfigKeys()
+	isSecretRef := func(key string) bool {
+		return strings.HasSuffix(key, secretFileSuffix) && !declared[key]
+	}
+
+	for _, key := range v.AllKeys() {
+		if !isSecretRef(key) || v.GetString(key) == "" {
+			continue
+		}
+		if err := setFromSecretFile(v, strings.TrimSuffix(key, secretFileSuffix), v.GetString(key)); err != nil {
+			return fmt.Errorf("%s: %s", key, err)
+		}
+	}
+
+	keys := make(map[string]string)
+	for key := range declared {
+		keys[envName(key)] = key
+	}
+	for _, key := range v.AllKeys() {
+		keys[envName(key)] = key
+	}
+
+	var names []string
+	for _, env := range os.Environ() {
+		if strings.HasPrefix(env, EnvPrefix) {
+			names = append(names, strings.SplitN(env, "=", 2)[0])
+		}
+	}
+	sort.Strings(names)
+
+	// Secret files first, so that a value set directly wins
+	var values []string
+	for _, name := range names {
+		key := envKey(name, keys)
+		if key != "" && !isSecretRef(key) {
+			values = append(values, name)
+			continue
+		}
+
+		target := envKey(strings.TrimSuffix(name, strings.ToUpper(secretFileSuffix)), keys)
+		if target == "" && key != "" {
+			target = strings.TrimSuffix(key, secretFileSuffix)
+		}
+		if target == "" {
+			log.WithField("Variable", name).Warn("Environment variable doesn't match any config key.")
+			continue
+		}
+		if err := setFromSecretFile(v, target, os.Getenv(name)); err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+	}
+
+	for _, name := range values {
+		key := envKey(name, keys)
+		v.Set(key, os.Getenv(name))
+		log.WithFields(log.Fields{
+			"Type":     "ConfigOverride",
+			"Key":      key,
+			"Variable": name,
+		}).Debug("Config key set from the environment.")
+	}
+	return nil
+}
+
+// envName returns the environment variable overriding `key`.
+func envName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(key, ".", "_", -1))
+}
+
+// envKey returns the config key set by the environment variable
+// `name`, given the known keys by variable name, or "".
+func envKey(name string, keys map[string]string) string {
+	if key, ok := keys[name]; ok {
+		return key
+	}
+
+	rest := strings.TrimPrefix(name, EnvPrefix)
+	if !strings.Contains(rest, "__") {
+		return ""
+	}
+	return strings.ToLower(strings.Replace(rest, "__", ".", -1))
+}
+
+func setFromSecretFile(v *viper.Viper, key, file string) error {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	v.Set(key, strings.TrimRight(string(content), "\r\n"))
+	log.WithFields(log.Fields{
+		"Type": "ConfigOverride",
+		"Key":  key,
+		"File": file,
+	}).Debug("Config key read from a secret file.")
+	return nil
+}
+
+// declaredConfigKeys returns the keys declared by the bot's config
+// structs and the ConfigSchema plugins.
+func declaredConfigKeys() map[string]bool {
+	keys := make(map[string]bool)
+	addStructKeys(keys, "slack", reflect.TypeOf(SlackConfig{}))
+	addStructKeys(keys, "logging", reflect.TypeOf(Logging{}))
+	addStructKeys(keys, "server", reflect.TypeOf(ServerConfig{}))
+	for _, plugin := range registeredPlugins {
+		if schema, ok := plugin.(ConfigSchema); ok {
+			section, config := schema.ConfigSchema()
+			addStructKeys(keys, strings.ToLower(section), reflect.TypeOf(config).Elem())
+		}
+	}
+	return keys
+}
+
+func addStructKeys(keys map[string]bool, prefix string, structType reflect.Type) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		key := prefix + "." + fieldKey(field)
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}) {
+			addStructKeys(keys, key, field.Type)
+			continue
+		}
+		keys[key] = true
+	}
+}
@@ -0,0 +1,57 @@
+package slick
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigOverrides(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slick")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeSecret := func(name, content string) string {
+		file := filepath.Join(dir, name)
+		assert.NoError(t, ioutil.WriteFile(file, []byte(content), 0600))
+		return file
+	}
+	signingSecret := writeSecret("signing_secret", "s3cr3t\n")
+	appToken := writeSecret("app_token", "xapp-1")
+
+	v := readTestConfig(t, `{
+		"Slack": {"api_token": "xoxb-file", "signing_secret_file": "`+signingSecret+`"},
+		"Server": {"pid_file": "/var/run/slick.pid"},
+		"Hooker": {"stripe_secret": "stripe"}
+	}`)
+
+	env := map[string]string{
+		"SLICK_SLACK_API_TOKEN":       "xoxb-env",
+		"SLICK_SLACK_APP_TOKEN_FILE":  appToken,
+		"SLICK_SLACK_JOIN_CHANNELS":   "general,random",
+		"SLICK_HOOKER__GITHUB_SECRET": "github",
+	}
+	for name, value := range env {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	assert.NoError(t, applyConfigOverrides(v))
+
+	var config SlackConfig
+	assert.NoError(t, decodeSection(v, "Slack", &config))
+	assert.Equal(t, "xoxb-env", config.ApiToken, "the environment wins over the config file")
+	assert.Equal(t, "s3cr3t", config.SigningSecret, "secret files referenced in the config file are read")
+	assert.Equal(t, "xapp-1", config.AppToken, "secret files referenced in the environment are read")
+	assert.Equal(t, []string{"general", "random"}, config.JoinChannels)
+
+	assert.Equal(t, "/var/run/slick.pid", v.GetString("server.pid_file"), "declared _file keys aren't secret files")
+	assert.Equal(t, "stripe", v.GetString("hooker.stripe_secret"))
+	assert.Equal(t, "github", v.GetString("hooker.github_secret"), "undeclared keys are set with double underscores")
+
+	os.Setenv("SLICK_SLACK_APP_TOKEN_FILE", filepath.Join(dir, "missing"))
+	assert.Error(t, applyConfigOverrides(v), "missing secret files are errors")
+}
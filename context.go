@@ -0,0 +1,56 @@
+package slick
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type contextKey int
+
+const requestIDKey contextKey = iota
+
+// requestCounter numbers the incoming events, after a random prefix
+// telling the bot's runs apart in the logs.
+var (
+	requestPrefix  = fmt.Sprintf("%04x", rand.Intn(0x10000))
+	requestCounter uint64
+)
+
+func newRequestID() string {
+	return fmt.Sprintf("%s-%d", requestPrefix, atomic.AddUint64(&requestCounter, 1))
+}
+
+// Context returns the bot's context, cancelled on Shutdown. Use it for
+// the work not tied to a Message, like in web handlers or jobs.
+func (bot *Bot) Context() context.Context {
+	return bot.ctx
+}
+
+// WithRequestID returns a copy of `ctx` carrying the request ID `id`.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID carried by `ctx`, or "" if none.
+// Each incoming event gets its own, shared by the Listeners handling
+// it.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// Log returns a log entry with the request ID carried by `ctx`, if any.
+//
+// Example:
+//
+//	slick.Log(msg.Context()).WithError(err).Error("Couldn't fetch the issues.")
+func Log(ctx context.Context) *log.Entry {
+	if id := RequestID(ctx); id != "" {
+		return log.WithField("RequestID", id)
+	}
+	return log.NewEntry(log.StandardLogger())
+}
@@ -0,0 +1,83 @@
+package slick
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	bot := New("")
+	bot.Users.Set(slack.User{ID: "U1", Name: "bob"})
+	bot.Channels.Set(Channel{ID: "C1", Name: "general", IsChannel: true})
+
+	var lock sync.Mutex
+	var ids []string
+	bot.Use(func(next Handler) Handler {
+		return func(d *Dispatch) {
+			lock.Lock()
+			ids = append(ids, RequestID(d.Context))
+			lock.Unlock()
+			next(d)
+		}
+	})
+
+	messages := make(chan *Message, 1)
+	bot.listeners = []*Listener{
+		{MessageHandlerFunc: func(_ *Listener, msg *Message) { messages <- msg }},
+		{EventHandlerFunc: func(*Listener, interface{}) {}},
+	}
+
+	bot.handleRTMEvent(&slack.RTMEvent{Type: "message", Data: &slack.MessageEvent{
+		Msg: slack.Msg{Type: "message", Channel: "C1", User: "U1", Text: "hello"},
+	}})
+	waitListeners(t, bot)
+
+	assert.Len(t, ids, 2)
+	assert.NotEqual(t, "", ids[0])
+	assert.Equal(t, ids[0], ids[1], "the listeners share the event's request ID")
+	assert.Equal(t, ids[0], RequestID((<-messages).Context()))
+
+	bot.handleRTMEvent(&slack.RTMEvent{Type: "hello", Data: &slack.HelloEvent{}})
+	waitListeners(t, bot)
+	assert.Len(t, ids, 3)
+	assert.NotEqual(t, ids[0], ids[2], "each event gets its own request ID")
+
+	assert.Equal(t, "", RequestID(context.Background()))
+}
+
+func TestListenerContextIsCancelled(t *testing.T) {
+	bot := New("")
+	bot.Users.Set(slack.User{ID: "U1", Name: "bob"})
+	bot.Channels.Set(Channel{ID: "C1", Name: "general", IsChannel: true})
+
+	messages := make(chan *Message, 1)
+	newListener := func() *Listener {
+		listen := &Listener{Bot: bot, MessageHandlerFunc: func(_ *Listener, msg *Message) { messages <- msg }}
+		listen.setupChannels()
+		bot.listeners = []*Listener{listen}
+		return listen
+	}
+
+	handle := func() context.Context {
+		bot.handleRTMEvent(&slack.RTMEvent{Type: "message", Data: &slack.MessageEvent{
+			Msg: slack.Msg{Type: "message", Channel: "C1", User: "U1", Text: "hello"},
+		}})
+		waitListeners(t, bot)
+		return (<-messages).Context()
+	}
+
+	listen := newListener()
+	ctx := handle()
+	assert.NoError(t, ctx.Err())
+	listen.Close()
+	assert.Equal(t, context.Canceled, ctx.Err(), "closing the listener cancels its messages")
+
+	newListener()
+	ctx = handle()
+	bot.cancel()
+	assert.Equal(t, context.Canceled, ctx.Err(), "shutting down cancels all the messages")
+}
@@ -0,0 +1,211 @@
+package slick
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule computes the run times of a recurring Job.
+type Schedule interface {
+	// Next returns the first run time strictly after `after`, or the
+	// zero Time if there is none.
+	Next(after time.Time) time.Time
+}
+
+// ParseSchedule parses a schedule specification, either:
+//
+//   - a standard 5-field cron expression: "minute hour day-of-month
+//     month day-of-week", with `*`, lists (`1,15`), ranges (`1-5`),
+//     steps (`*/10`) and names (`mon`, `jan`). Ex: "30 9 * * mon-fri"
+//   - a descriptor: "@hourly", "@daily" (or "@midnight"), "@weekly",
+//     "@monthly" or "@yearly" (or "@annually")
+//   - "@every <duration>", like "@every 10m"
+//
+// Cron expressions are evaluated in the time zone of the Time given to
+// `Next`.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if strings.HasPrefix(spec, "@every ") {
+		every, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid @every duration: %s", err)
+		}
+		if every < time.Second {
+			return nil, fmt.Errorf("@every duration must be at least 1s")
+		}
+		return everySchedule{every}, nil
+	}
+
+	switch spec {
+	case "@yearly", "@annually":
+		spec = "0 0 1 1 *"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case "@hourly":
+		spec = "0 * * * *"
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields in cron expression %q, got %d", spec, len(fields))
+	}
+
+	var sched cronSchedule
+	var err error
+	if sched.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %s", err)
+	}
+	if sched.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %s", err)
+	}
+	if sched.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of month: %s", err)
+	}
+	if sched.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("month: %s", err)
+	}
+	if sched.dow, err = parseCronField(fields[4], 0, 7, weekdayNames); err != nil {
+		return nil, fmt.Errorf("day of week: %s", err)
+	}
+
+	// Sunday is both 0 and 7
+	if sched.dow&(1<<7) != 0 {
+		sched.dow |= 1
+	}
+	sched.domStar = fields[2] == "*" || strings.HasPrefix(fields[2], "*/")
+	sched.dowStar = fields[4] == "*" || strings.HasPrefix(fields[4], "*/")
+
+	return sched, nil
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var weekdayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// parseCronField returns the bitset of the values matched by `field`.
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx != -1 {
+			var err error
+			step, err = strconv.Atoi(part[idx+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:idx]
+		}
+
+		low, high := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if low, err = parseCronValue(bounds[0], min, max, names); err != nil {
+				return 0, err
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = parseCronValue(bounds[1], min, max, names); err != nil {
+					return 0, err
+				}
+			} else if step != 1 {
+				high = max
+			}
+			if high < low {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		}
+
+		for i := low; i <= high; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits, nil
+}
+
+func parseCronValue(s string, min, max int, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("value %d out of range [%d-%d]", v, min, max)
+	}
+	return v, nil
+}
+
+// cronSchedule holds the values matched by each field of a cron
+// expression, as bitsets.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+func (s cronSchedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+
+	// Give up after 5 years, for impossible dates like Feb 30th.
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// matchDay applies the cron rule: when both the day of month and the
+// day of week are restricted, either one matching is enough.
+func (s cronSchedule) matchDay(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// everySchedule runs at a fixed interval.
+type everySchedule struct {
+	every time.Duration
+}
+
+func (s everySchedule) Next(after time.Time) time.Time {
+	return after.Add(s.every)
+}
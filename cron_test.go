@@ -0,0 +1,63 @@
+package slick
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScheduleNext(t *testing.T) {
+	montreal, err := time.LoadLocation("America/Montreal")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	// A Wednesday
+	from := time.Date(2019, time.March, 6, 10, 30, 0, 0, montreal)
+
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2019, time.March, 6, 10, 31, 0, 0, montreal)},
+		{"*/15 * * * *", time.Date(2019, time.March, 6, 10, 45, 0, 0, montreal)},
+		{"0 12 * * mon-fri", time.Date(2019, time.March, 6, 12, 0, 0, 0, montreal)},
+		{"0 9 * * 1,5", time.Date(2019, time.March, 8, 9, 0, 0, 0, montreal)},
+		{"0 16 * * thu", time.Date(2019, time.March, 7, 16, 0, 0, 0, montreal)},
+		{"0 0 * * 7", time.Date(2019, time.March, 10, 0, 0, 0, 0, montreal)},
+		{"30 2 1 jan *", time.Date(2020, time.January, 1, 2, 30, 0, 0, montreal)},
+		{"0 0 13 * fri", time.Date(2019, time.March, 8, 0, 0, 0, 0, montreal)},
+		{"@hourly", time.Date(2019, time.March, 6, 11, 0, 0, 0, montreal)},
+		{"@monthly", time.Date(2019, time.April, 1, 0, 0, 0, 0, montreal)},
+		{"@every 10s", from.Add(10 * time.Second)},
+		{"0 0 30 feb *", time.Time{}},
+	}
+
+	for _, test := range tests {
+		schedule, err := ParseSchedule(test.spec)
+		if assert.NoError(t, err, test.spec) {
+			assert.True(t, test.next.Equal(schedule.Next(from)), "%s: expected %s, got %s", test.spec, test.next, schedule.Next(from))
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * * someday",
+		"@every 10ms",
+		"@every never",
+		"@sometimes",
+	} {
+		_, err := ParseSchedule(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,510 @@
+package slick
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// missTTL is how long an ID the Web API didn't know is not fetched
+// again.
+const missTTL = time.Minute
+
+var (
+	reUserID    = regexp.MustCompile(`^[UW][A-Z0-9]+$`)
+	reChannelID = regexp.MustCompile(`^[CGD][A-Z0-9]+$`)
+)
+
+// misses remembers the IDs which couldn't be fetched, so a broken
+// reference doesn't call the Web API on every event.
+type misses map[string]time.Time
+
+func (m misses) recent(id string) bool {
+	at, ok := m[id]
+	return ok && time.Since(at) < missTTL
+}
+
+// flights are the fetches in progress, by ID, so that concurrent
+// misses of an ID share a single Web API call. Each channel is closed
+// when its fetch ends.
+type flights map[string]chan struct{}
+
+// join returns the channel closed when the fetch of `id` ends, and
+// whether another goroutine was fetching it already. Otherwise, the
+// caller fetches it and calls `end`.
+func (f flights) join(id string) (chan struct{}, bool) {
+	if done, ok := f[id]; ok {
+		return done, true
+	}
+	done := make(chan struct{})
+	f[id] = done
+	return done, false
+}
+
+func (f flights) end(id string) {
+	if done, ok := f[id]; ok {
+		close(done)
+		delete(f, id)
+	}
+}
+
+// UserDirectory holds the users of a team, indexed by ID, name, email
+// and real name. It is safe to use from several goroutines: the event
+// loop keeps it up to date while plugins read it.
+//
+// Users missing from it are fetched from the Web API, when the
+// directory belongs to a connected Team.
+type UserDirectory struct {
+	lock       sync.RWMutex
+	byID       map[string]slack.User
+	byName     map[string]string
+	byEmail    map[string]string
+	byRealName map[string]string
+
+	// fetch gets a user from the Web API, if set. It returns a nil
+	// user when it can't, like before connecting.
+	fetch   func(id string) (*slack.User, error)
+	misses  misses
+	flights flights
+}
+
+// NewUserDirectory returns a directory holding `users`.
+func NewUserDirectory(users ...slack.User) *UserDirectory {
+	d := &UserDirectory{}
+	d.Replace(users)
+	return d
+}
+
+// Replace replaces all the users of the directory.
+func (d *UserDirectory) Replace(users []slack.User) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.byID = make(map[string]slack.User, len(users))
+	d.byName = make(map[string]string, len(users))
+	d.byEmail = make(map[string]string, len(users))
+	d.byRealName = make(map[string]string, len(users))
+	d.misses = make(misses)
+	if d.flights == nil {
+		d.flights = make(flights)
+	}
+	for _, user := range users {
+		d.set(user)
+	}
+}
+
+// Set adds or replaces users.
+func (d *UserDirectory) Set(users ...slack.User) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for _, user := range users {
+		d.set(user)
+	}
+}
+
+func (d *UserDirectory) set(user slack.User) {
+	d.unindex(user.ID)
+	d.byID[user.ID] = user
+	delete(d.misses, user.ID)
+
+	if user.Name != "" {
+		d.byName[user.Name] = user.ID
+	}
+	if email := strings.ToLower(user.Profile.Email); email != "" {
+		d.byEmail[email] = user.ID
+	}
+	if user.RealName != "" {
+		d.byRealName[user.RealName] = user.ID
+	}
+}
+
+// unindex removes the previous entries of the user from the indexes.
+func (d *UserDirectory) unindex(id string) {
+	previous, ok := d.byID[id]
+	if !ok {
+		return
+	}
+	if d.byName[previous.Name] == id {
+		delete(d.byName, previous.Name)
+	}
+	if email := strings.ToLower(previous.Profile.Email); d.byEmail[email] == id {
+		delete(d.byEmail, email)
+	}
+	if d.byRealName[previous.RealName] == id {
+		delete(d.byRealName, previous.RealName)
+	}
+}
+
+// Delete removes a user.
+func (d *UserDirectory) Delete(id string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.unindex(id)
+	delete(d.byID, id)
+}
+
+// Update changes the user with ID `id` in place, and tells if it was
+// found.
+func (d *UserDirectory) Update(id string, f func(user *slack.User)) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	user, ok := d.byID[id]
+	if !ok {
+		return false
+	}
+	f(&user)
+	d.set(user)
+	return true
+}
+
+// Get returns the user with ID `id`, fetching it from the Web API when
+// it isn't known yet. It waits for the Web API: don't call it from the
+// event loop, see peek.
+func (d *UserDirectory) Get(id string) (slack.User, bool) {
+	d.lock.RLock()
+	user, ok := d.byID[id]
+	fetch := d.fetch
+	recent := d.misses.recent(id)
+	d.lock.RUnlock()
+
+	if ok || fetch == nil || recent || !reUserID.MatchString(id) {
+		return user, ok
+	}
+
+	d.lock.Lock()
+	done, fetching := d.flights.join(id)
+	d.lock.Unlock()
+	if fetching {
+		<-done
+		d.lock.RLock()
+		defer d.lock.RUnlock()
+		user, ok = d.byID[id]
+		return user, ok
+	}
+
+	fetched, err := fetch(id)
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	defer d.flights.end(id)
+
+	if err != nil || fetched == nil {
+		if err != nil {
+			log.WithFields(log.Fields{
+				"Type": "UserDirectory",
+				"User": id,
+			}).WithError(err).Warn("Couldn't fetch an unknown user.")
+		}
+		d.misses[id] = time.Now()
+		return user, false
+	}
+
+	d.set(*fetched)
+	return *fetched, true
+}
+
+// peek returns the user with ID `id` if it is known, without waiting
+// for the Web API: an unknown user is fetched in the background, for
+// the events to come.
+func (d *UserDirectory) peek(id string) (slack.User, bool) {
+	d.lock.RLock()
+	user, ok := d.byID[id]
+	fetchable := d.fetch != nil && !d.misses.recent(id) && d.flights[id] == nil
+	d.lock.RUnlock()
+
+	if !ok && fetchable && reUserID.MatchString(id) {
+		go d.Get(id)
+	}
+	return user, ok
+}
+
+// Find returns the user with `find` as ID, name, email or real name.
+func (d *UserDirectory) Find(find string) (slack.User, bool) {
+	d.lock.RLock()
+	id, ok := d.byName[find]
+	if !ok {
+		id, ok = d.byEmail[strings.ToLower(find)]
+	}
+	if !ok {
+		id, ok = d.byRealName[find]
+	}
+	if !ok {
+		id = find
+	}
+	d.lock.RUnlock()
+
+	return d.Get(id)
+}
+
+// Len returns the number of users.
+func (d *UserDirectory) Len() int {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return len(d.byID)
+}
+
+// Snapshot returns a copy of the users, by ID, to iterate over.
+func (d *UserDirectory) Snapshot() map[string]slack.User {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	users := make(map[string]slack.User, len(d.byID))
+	for id, user := range d.byID {
+		users[id] = user
+	}
+	return users
+}
+
+// ChannelDirectory holds the channels, groups and IMs of a team,
+// indexed by ID, name and IM user. Like UserDirectory, it is safe to
+// use from several goroutines, and fetches the channels it misses from
+// the Web API.
+type ChannelDirectory struct {
+	lock   sync.RWMutex
+	byID   map[string]Channel
+	byName map[string]string
+	byIM   map[string]string
+
+	// fetch gets a channel from the Web API, if set, like the users'
+	// fetch.
+	fetch   func(id string) (*Channel, error)
+	misses  misses
+	flights flights
+}
+
+// NewChannelDirectory returns a directory holding `channels`.
+func NewChannelDirectory(channels ...Channel) *ChannelDirectory {
+	d := &ChannelDirectory{}
+	d.Replace(channels)
+	return d
+}
+
+// Replace replaces all the channels of the directory.
+func (d *ChannelDirectory) Replace(channels []Channel) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.byID = make(map[string]Channel, len(channels))
+	d.byName = make(map[string]string, len(channels))
+	d.byIM = make(map[string]string)
+	d.misses = make(misses)
+	if d.flights == nil {
+		d.flights = make(flights)
+	}
+	for _, channel := range channels {
+		d.set(channel)
+	}
+}
+
+// Set adds or replaces channels.
+func (d *ChannelDirectory) Set(channels ...Channel) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for _, channel := range channels {
+		d.set(channel)
+	}
+}
+
+func (d *ChannelDirectory) set(channel Channel) {
+	d.unindex(channel.ID)
+	d.byID[channel.ID] = channel
+	delete(d.misses, channel.ID)
+
+	if channel.Name != "" {
+		d.byName[channel.Name] = channel.ID
+	}
+	if channel.IsIM && channel.User != "" {
+		d.byIM[channel.User] = channel.ID
+	}
+}
+
+func (d *ChannelDirectory) unindex(id string) {
+	previous, ok := d.byID[id]
+	if !ok {
+		return
+	}
+	if d.byName[previous.Name] == id {
+		delete(d.byName, previous.Name)
+	}
+	if d.byIM[previous.User] == id {
+		delete(d.byIM, previous.User)
+	}
+}
+
+// Delete removes a channel.
+func (d *ChannelDirectory) Delete(id string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.unindex(id)
+	delete(d.byID, id)
+}
+
+// Update changes the channel with ID `id` in place, and tells if it
+// was found.
+func (d *ChannelDirectory) Update(id string, f func(channel *Channel)) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	channel, ok := d.byID[id]
+	if !ok {
+		return false
+	}
+	f(&channel)
+	d.set(channel)
+	return true
+}
+
+// Get returns the channel with ID `id`, fetching it from the Web API
+// when it isn't known yet. Like the users' Get, it waits for the Web
+// API.
+func (d *ChannelDirectory) Get(id string) (Channel, bool) {
+	d.lock.RLock()
+	channel, ok := d.byID[id]
+	fetch := d.fetch
+	recent := d.misses.recent(id)
+	d.lock.RUnlock()
+
+	if ok || fetch == nil || recent || !reChannelID.MatchString(id) {
+		return channel, ok
+	}
+
+	d.lock.Lock()
+	done, fetching := d.flights.join(id)
+	d.lock.Unlock()
+	if fetching {
+		<-done
+		d.lock.RLock()
+		defer d.lock.RUnlock()
+		channel, ok = d.byID[id]
+		return channel, ok
+	}
+
+	fetched, err := fetch(id)
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	defer d.flights.end(id)
+
+	if err != nil || fetched == nil {
+		if err != nil {
+			log.WithFields(log.Fields{
+				"Type":    "ChannelDirectory",
+				"Channel": id,
+			}).WithError(err).Warn("Couldn't fetch an unknown channel.")
+		}
+		d.misses[id] = time.Now()
+		return channel, false
+	}
+
+	d.set(*fetched)
+	return *fetched, true
+}
+
+// peek returns the channel with ID `id` if it is known, fetching it in
+// the background otherwise, like the users' peek.
+func (d *ChannelDirectory) peek(id string) (Channel, bool) {
+	d.lock.RLock()
+	channel, ok := d.byID[id]
+	fetchable := d.fetch != nil && !d.misses.recent(id) && d.flights[id] == nil
+	d.lock.RUnlock()
+
+	if !ok && fetchable && reChannelID.MatchString(id) {
+		go d.Get(id)
+	}
+	return channel, ok
+}
+
+// Refresh fetches the channel with ID `id` from the Web API again, and
+// updates it unless it was deleted meanwhile.
+func (d *ChannelDirectory) Refresh(id string) error {
+	d.lock.RLock()
+	fetch := d.fetch
+	d.lock.RUnlock()
+	if fetch == nil {
+		return nil
+	}
+
+	fetched, err := fetch(id)
+	if err != nil || fetched == nil {
+		return err
+	}
+	d.Update(id, func(channel *Channel) {
+		*channel = *fetched
+	})
+	return nil
+}
+
+// AddMember adds a user to the members of a channel, and tells if the
+// channel is known.
+func (d *ChannelDirectory) AddMember(id, userID string) bool {
+	return d.Update(id, func(channel *Channel) {
+		for _, member := range channel.Members {
+			if member == userID {
+				return
+			}
+		}
+		channel.Members = append(channel.Members, userID)
+	})
+}
+
+// RemoveMember removes a user from the members of a channel, and tells
+// if the channel is known.
+func (d *ChannelDirectory) RemoveMember(id, userID string) bool {
+	return d.Update(id, func(channel *Channel) {
+		members := make([]string, 0, len(channel.Members))
+		for _, member := range channel.Members {
+			if member != userID {
+				members = append(members, member)
+			}
+		}
+		channel.Members = members
+	})
+}
+
+// ByName returns the channel named `name`, with or without its "#".
+func (d *ChannelDirectory) ByName(name string) (Channel, bool) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	id, ok := d.byName[strings.TrimLeft(name, "#")]
+	if !ok {
+		return Channel{}, false
+	}
+	return d.byID[id], true
+}
+
+// IMWith returns the IM channel with the user `userID`.
+func (d *ChannelDirectory) IMWith(userID string) (Channel, bool) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	id, ok := d.byIM[userID]
+	if !ok {
+		return Channel{}, false
+	}
+	return d.byID[id], true
+}
+
+// Len returns the number of channels.
+func (d *ChannelDirectory) Len() int {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return len(d.byID)
+}
+
+// Snapshot returns a copy of the channels, by ID, to iterate over.
+func (d *ChannelDirectory) Snapshot() map[string]Channel {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	channels := make(map[string]Channel, len(d.byID))
+	for id, channel := range d.byID {
+		channels[id] = channel
+	}
+	return channels
+}
@@ -0,0 +1,181 @@
+package slick
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserDirectory(t *testing.T) {
+	bob := slack.User{ID: "U1", Name: "bob", RealName: "Bob Smith"}
+	bob.Profile.Email = "Bob@example.com"
+	users := NewUserDirectory(bob, slack.User{ID: "U2", Name: "alice"})
+
+	for _, find := range []string{"U1", "bob", "bob@example.com", "Bob Smith"} {
+		user, ok := users.Find(find)
+		assert.True(t, ok, find)
+		assert.Equal(t, "U1", user.ID, find)
+	}
+
+	users.Update("U1", func(user *slack.User) { user.Name = "robert" })
+	_, ok := users.Find("bob")
+	assert.False(t, ok, "the old name is no longer indexed")
+	user, _ := users.Find("robert")
+	assert.Equal(t, "U1", user.ID)
+
+	snapshot := users.Snapshot()
+	users.Delete("U2")
+	assert.Len(t, snapshot, 2, "snapshots are copies")
+	assert.Equal(t, 1, users.Len())
+
+	// Unknown users are fetched once
+	var fetched []string
+	users.fetch = func(id string) (*slack.User, error) {
+		fetched = append(fetched, id)
+		if id == "U3" {
+			return &slack.User{ID: "U3", Name: "carol"}, nil
+		}
+		return nil, errors.New("user_not_found")
+	}
+	user, ok = users.Get("U3")
+	assert.True(t, ok)
+	assert.Equal(t, "carol", user.Name)
+	users.Get("U3")
+	users.Get("U4")
+	users.Get("U4")
+	users.Find("nobody")
+	assert.Equal(t, []string{"U3", "U4"}, fetched, "known users, recent misses and names aren't fetched")
+}
+
+func TestChannelDirectory(t *testing.T) {
+	channels := NewChannelDirectory(
+		Channel{ID: "C1", Name: "general", IsChannel: true},
+		Channel{ID: "D1", IsIM: true, User: "U1"},
+	)
+
+	channel, ok := channels.ByName("#general")
+	assert.True(t, ok)
+	assert.Equal(t, "C1", channel.ID)
+
+	channel, ok = channels.IMWith("U1")
+	assert.True(t, ok)
+	assert.Equal(t, "D1", channel.ID)
+
+	channels.Update("C1", func(channel *Channel) { channel.Name = "announcements" })
+	_, ok = channels.ByName("general")
+	assert.False(t, ok)
+	_, ok = channels.ByName("announcements")
+	assert.True(t, ok)
+
+	channels.Delete("D1")
+	_, ok = channels.IMWith("U1")
+	assert.False(t, ok)
+
+	channels.fetch = func(id string) (*Channel, error) {
+		return &Channel{ID: id, Name: "new", IsChannel: true}, nil
+	}
+	channel, ok = channels.Get("C2")
+	assert.True(t, ok)
+	assert.Equal(t, "new", channel.Name)
+	_, ok = channels.ByName("new")
+	assert.True(t, ok, "fetched channels are cached")
+}
+
+func TestChannelEvents(t *testing.T) {
+	bot := New("")
+	bot.Myself = slack.UserDetails{ID: "USLICK"}
+	bot.Users.Set(slack.User{ID: "U1", Name: "bob"})
+
+	bot.Channels.fetch = func(id string) (*Channel, error) {
+		return &Channel{
+			ID:        id,
+			Name:      "launch",
+			IsChannel: true,
+			Members:   []string{"U1"},
+			Topic:     slack.Topic{Value: "3, 2, 1"},
+		}, nil
+	}
+
+	bot.handleRTMEvent(&slack.RTMEvent{Data: &slack.ChannelCreatedEvent{
+		Channel: slack.ChannelCreatedInfo{ID: "C9", Name: "launch", Creator: "U1", Created: 1500000000},
+	}})
+	channel, ok := bot.Channels.Get("C9")
+	assert.True(t, ok, "created channels are known right away")
+	assert.Equal(t, int64(1500000000), channel.Created.Unix())
+
+	for i := 0; i < 100 && channel.Topic.Value == ""; i++ {
+		time.Sleep(10 * time.Millisecond)
+		channel, _ = bot.Channels.Get("C9")
+	}
+	assert.Equal(t, "3, 2, 1", channel.Topic.Value, "their info is fetched")
+	assert.Equal(t, []string{"U1"}, channel.Members)
+
+	bot.handleRTMEvent(&slack.RTMEvent{Data: &slack.MemberJoinedChannelEvent{Channel: "C9", User: "USLICK"}})
+	channel, _ = bot.Channels.Get("C9")
+	assert.Equal(t, []string{"U1", "USLICK"}, channel.Members)
+	assert.True(t, channel.IsMember)
+
+	bot.handleRTMEvent(&slack.RTMEvent{Data: &slack.MemberLeftChannelEvent{Channel: "C9", User: "U1"}})
+	bot.handleRTMEvent(&slack.RTMEvent{Data: &slack.ChannelLeftEvent{Channel: "C9"}})
+	channel, _ = bot.Channels.Get("C9")
+	assert.Empty(t, channel.Members)
+	assert.False(t, channel.IsMember)
+
+	bot.handleRTMEvent(&slack.RTMEvent{Data: &slack.PresenceChangeEvent{User: "U1", Presence: "away"}})
+	user, _ := bot.Users.Get("U1")
+	assert.Equal(t, "away", user.Presence)
+}
+
+func TestDirectoryFetchesOffTheEventLoop(t *testing.T) {
+	bot := New("")
+	bot.Channels.Set(Channel{ID: "C1", Name: "general", IsChannel: true})
+
+	release := make(chan bool)
+	var lock sync.Mutex
+	var fetches int
+	bot.Users.fetch = func(id string) (*slack.User, error) {
+		lock.Lock()
+		fetches++
+		lock.Unlock()
+		<-release
+		return &slack.User{ID: id, Name: "dave"}, nil
+	}
+
+	handled := make(chan *Message, 1)
+	bot.listeners = []*Listener{
+		{MessageHandlerFunc: func(_ *Listener, msg *Message) { handled <- msg }},
+	}
+
+	done := make(chan bool)
+	go func() {
+		bot.handleRTMEvent(messageEvent("C1", "hello"))
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the event loop waited for the Web API")
+	}
+
+	results := make(chan string, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			user, _ := bot.Users.Get("U1")
+			results <- user.Name
+		}()
+	}
+	close(release)
+
+	msg := <-handled
+	assert.Equal(t, "dave", msg.FromUser.Name, "listeners wait for the unknown users")
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "dave", <-results)
+	}
+	lock.Lock()
+	assert.Equal(t, 1, fetches, "concurrent misses share a single fetch")
+	lock.Unlock()
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"os"
 
 	"github.com/CapstoneLabs/slick"
 	_ "github.com/CapstoneLabs/slick/bugger"
@@ -26,10 +28,22 @@ import (
 // file is specified
 var configFile = flag.String("config", "", "config file")
 
+// Check the config file and exit, without connecting to Slack.
+var checkConfig = flag.Bool("check-config", false, "validate the config file and exit")
+
 func main() {
 	flag.Parse()
 
 	bot := slick.New(*configFile)
 
+	if *checkConfig {
+		if err := bot.CheckConfig(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println("Config OK.")
+		return
+	}
+
 	bot.Run()
 }
@@ -5,6 +5,7 @@ package faceoff
 // Start with !faceoff in any channel and let the fun begin.
 
 import (
+	"context"
 	_ "image/jpeg"
 	"regexp"
 	"sync"
@@ -29,12 +30,14 @@ type Faceoff struct {
 
 const faceoffKey = "/faceoff/users/stats"
 
-// InitPlugin establishes the regex and listeners
+// InitPlugin establishes the regex and listeners. The game and its
+// scores are those of the primary team's users, so it only listens
+// there.
 func (p *Faceoff) InitPlugin(bot *slick.Bot) {
 	p.bot = bot
 
 	faceoffRE := regexp.MustCompile("^!face[ _-]?off")
-	bot.Listen(&slick.Listener{
+	bot.Team.Listen(&slick.Listener{
 		PublicOnly:     true,
 		Matches:        faceoffRE,
 		ListenForEdits: true,
@@ -47,14 +50,14 @@ func (p *Faceoff) InitPlugin(bot *slick.Bot) {
 				Channel:         msg.FromChannel,
 				Started:         time.Now(),
 			}
-			msg.Reply("Ok, are you ready ?")
+			msg.ReplyInThread("Ok, are you ready ?")
 			go func() {
 				g.Launch()
 			}()
 		},
 	})
 
-	bot.Listen(&slick.Listener{
+	bot.Team.Listen(&slick.Listener{
 		PrivateOnly: true,
 		Matches:     faceoffRE,
 		MessageHandlerFunc: func(listen *slick.Listener, msg *slick.Message) {
@@ -66,7 +69,7 @@ func (p *Faceoff) InitPlugin(bot *slick.Bot) {
 		},
 	})
 
-	bot.Listen(&slick.Listener{
+	bot.Team.Listen(&slick.Listener{
 		PrivateOnly: true,
 		Matches:     faceoffRE,
 		EventHandlerFunc: func(listen *slick.Listener, ev interface{}) {
@@ -89,12 +92,20 @@ func (p *Faceoff) InitPlugin(bot *slick.Bot) {
 	})
 }
 
+// StopPlugin saves the scores before the bot exits.
+func (p *Faceoff) StopPlugin(ctx context.Context) error {
+	if p.users == nil {
+		return nil
+	}
+	return p.bot.PutDBKey(faceoffKey, p.users)
+}
+
 func (p *Faceoff) updateUsersFromSlack() {
 	if p.users == nil {
 		p.users = make(map[string]*User)
 	}
 
-	for _, slackUser := range p.bot.Users {
+	for _, slackUser := range p.bot.Users.Snapshot() {
 		if slackUser.IsBot || slackUser.Deleted || slackUser.IsUltraRestricted || slackUser.IsRestricted || slackUser.RealName == "slackbot" {
 			delete(p.users, slackUser.ID)
 			continue
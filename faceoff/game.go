@@ -43,10 +43,10 @@ func (g *Game) Launch() {
 	var profileURLs []string
 	var lookedForUser slack.User
 	for idx, userID := range c.UsersShown {
-		u := g.Faceoff.bot.Users[userID]
+		u, _ := g.Faceoff.bot.Users.Get(userID)
 		if u.ID == "" {
 			log.Println("faceoff: error finding user with ID", userID)
-			g.OriginalMessage.Reply("error finding user with ID %q", userID)
+			g.OriginalMessage.ReplyInThread("error finding user with ID %q", userID)
 			return
 		}
 
@@ -69,17 +69,18 @@ func (g *Game) Launch() {
 	// Trigger a line with who we're looking for..
 	// Add the reactions, slowly, in order..
 	// Send the image, after a good second..
-	prepared := g.OriginalMessage.Reply(fmt.Sprintf("---\nBe prepared! We're looking for *%s* in the next image:", lookedForUser.RealName))
+	prepared := g.OriginalMessage.ReplyInThread(fmt.Sprintf("---\nBe prepared! We're looking for *%s* in the next image:", lookedForUser.RealName))
 	prepared.OnAck(func(ev *slack.AckMessage) {
 		go func() {
+			ctx := g.OriginalMessage.Context()
 			delay := 750 * time.Millisecond
-			g.Faceoff.bot.Slack.AddReaction("one", slack.NewRefToMessage(prepared.Channel, ev.Timestamp))
+			g.Faceoff.bot.Transport.AddReaction(ctx, "one", slack.NewRefToMessage(prepared.Channel, ev.Timestamp))
 			time.Sleep(delay)
-			g.Faceoff.bot.Slack.AddReaction("two", slack.NewRefToMessage(prepared.Channel, ev.Timestamp))
+			g.Faceoff.bot.Transport.AddReaction(ctx, "two", slack.NewRefToMessage(prepared.Channel, ev.Timestamp))
 			time.Sleep(delay)
-			g.Faceoff.bot.Slack.AddReaction("three", slack.NewRefToMessage(prepared.Channel, ev.Timestamp))
+			g.Faceoff.bot.Transport.AddReaction(ctx, "three", slack.NewRefToMessage(prepared.Channel, ev.Timestamp))
 			time.Sleep(delay)
-			g.Faceoff.bot.Slack.AddReaction("four", slack.NewRefToMessage(prepared.Channel, ev.Timestamp))
+			g.Faceoff.bot.Transport.AddReaction(ctx, "four", slack.NewRefToMessage(prepared.Channel, ev.Timestamp))
 
 			g.showChallenge(c, lookedForUser, pngContent, ev.Timestamp)
 		}()
@@ -90,20 +91,21 @@ func (g *Game) Launch() {
 func (g *Game) showChallenge(c *Challenge, lookedForUser slack.User, pngContent []byte, ts string) {
 	err := ioutil.WriteFile("/tmp/faceoff.png", pngContent, 0644)
 	if err != nil {
-		g.OriginalMessage.Reply("error writing temp faceoff image: %s", err)
+		g.OriginalMessage.ReplyInThread("error writing temp faceoff image: %s", err)
 		return
 	}
 
 	fmt.Println("*************************** before")
 
 	_, err = g.Faceoff.bot.Slack.UploadFile(slack.FileUploadParameters{
-		File:     "/tmp/faceoff.png",
-		Filetype: "png",
-		Title:    fmt.Sprintf("Find: %s", lookedForUser.RealName),
-		Channels: []string{g.Channel.ID},
+		File:            "/tmp/faceoff.png",
+		Filetype:        "png",
+		Title:           fmt.Sprintf("Find: %s", lookedForUser.RealName),
+		Channels:        []string{g.Channel.ID},
+		ThreadTimestamp: g.OriginalMessage.ThreadRoot(),
 	})
 	if err != nil {
-		g.OriginalMessage.Reply("error uploading faceoff image: %s", err)
+		g.OriginalMessage.ReplyInThread("error uploading faceoff image: %s", err)
 		return
 	}
 
@@ -141,7 +143,7 @@ func (g *Game) showChallenge(c *Challenge, lookedForUser slack.User, pngContent
 			defer listen.Close()
 
 			if len(c.Replies) == 0 {
-				g.OriginalMessage.Reply("oh well, I guess no one wanted to play!")
+				g.OriginalMessage.ReplyInThread("oh well, I guess no one wanted to play!")
 				// TODO: remove the original image
 				return
 			}
@@ -161,9 +163,9 @@ func (g *Game) showChallenge(c *Challenge, lookedForUser slack.User, pngContent
 						whowaswho = append(whowaswho, fmt.Sprintf("%s was *%s*", numbers[i], user.RealName))
 					}
 				}
-				g.OriginalMessage.Reply("Congrats <@%s> ! You found <@%s> the fastest.\n%s\nYour scores: `%s`", c.FirstCorrectReply, c.UsersShown[c.RightAnswerIndex], strings.Join(whowaswho, ", "), user.ScoreLine())
+				g.OriginalMessage.ReplyInThread("Congrats %s ! You found %s the fastest.\n%s\nYour scores: `%s`", slick.MentionUser(c.FirstCorrectReply), slick.MentionUser(c.UsersShown[c.RightAnswerIndex]), strings.Join(whowaswho, ", "), user.ScoreLine())
 			} else {
-				g.OriginalMessage.Reply("No one found out !? Try again !")
+				g.OriginalMessage.ReplyInThread("No one found out !? Try again !")
 			}
 
 			go g.Launch()
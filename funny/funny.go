@@ -93,18 +93,18 @@ func (funny *Funny) ChatHandler(listen *slick.Listener, msg *slick.Message) {
 			msg.Reply(bot.WithMood("my pleasure", "any time, just ask, I'm here for you, ffiieeewww!get a life"))
 
 		} else if msg.Contains("how are you") && msg.MentionsMe {
-			msg.ReplyMention(bot.WithMood("good, and you ?", "I'm wild today!! wadabout you ?"))
+			msg.ReplyInThread(bot.WithMood("good, and you ?", "I'm wild today!! wadabout you ?"))
 			bot.Listen(&slick.Listener{
 				ListenDuration: 60 * time.Second,
 				FromUser:       msg.FromUser,
 				FromChannel:    msg.FromChannel,
-				MentionsMeOnly: true,
+				InThread:       msg.ThreadRoot(),
 				MessageHandlerFunc: func(listen *slick.Listener, msg *slick.Message) {
-					msg.ReplyMention(bot.WithMood("glad to hear it!", "zwweeeeeeeeet !"))
+					msg.ReplyInThread(bot.WithMood("glad to hear it!", "zwweeeeeeeeet !"))
 					listen.Close()
 				},
 				TimeoutFunc: func(listen *slick.Listener) {
-					msg.ReplyMention("well, we can catch up later")
+					msg.ReplyInThread("well, we can catch up later")
 					listen.Close()
 				},
 			})
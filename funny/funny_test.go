@@ -0,0 +1,22 @@
+package funny
+
+import (
+	"testing"
+
+	"github.com/CapstoneLabs/slick/slicktest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHowAreYouContinuesInThread(t *testing.T) {
+	h := slicktest.New(t)
+	defer h.Close()
+
+	ts := h.Post("alice", "#general", "<@USLICK> how are you ?")
+	reply := h.ExpectMessage("#general", "you ?")
+	assert.Equal(t, ts, reply.ThreadTS)
+
+	h.Post("alice", "#general", "pretty good")
+	h.PostInThread("alice", "#general", ts, "pretty good")
+	reply = h.ExpectMessage("#general", "")
+	assert.Equal(t, ts, reply.ThreadTS)
+}
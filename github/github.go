@@ -2,6 +2,7 @@
 package github
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -47,7 +48,7 @@ type IssueEvent struct {
 
 type Conf struct {
 	Authtoken      string            `mapstructure:"authtoken"`
-	Repos          []string          `mapstructure:"repos"`
+	Repos          []string          `mapstructure:"repos" required:"true"`
 	Github2Hipchat map[string]string `mapstructure:"github2Hipchat"`
 }
 
@@ -89,8 +90,13 @@ func (issue *IssueItem) LastClosedBy() string {
 }
 
 func (ghclient *Client) Get(url string) (body []byte, err error) {
+	return ghclient.GetContext(context.Background(), url)
+}
+
+// GetContext is Get, cancelled when `ctx` is done.
+func (ghclient *Client) GetContext(ctx context.Context, url string) (body []byte, err error) {
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return
 	}
@@ -114,9 +120,14 @@ func (ghclient *Client) Get(url string) (body []byte, err error) {
 }
 
 func (ghclient *Client) DoSearchQuery(query SearchQuery) ([]IssueItem, error) {
+	return ghclient.DoSearchQueryContext(context.Background(), query)
+}
+
+// DoSearchQueryContext is DoSearchQuery, cancelled when `ctx` is done.
+func (ghclient *Client) DoSearchQueryContext(ctx context.Context, query SearchQuery) ([]IssueItem, error) {
 
 	url := query.Url()
-	body, err := ghclient.Get(url)
+	body, err := ghclient.GetContext(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -128,15 +139,24 @@ func (ghclient *Client) DoSearchQuery(query SearchQuery) ([]IssueItem, error) {
 }
 
 func (ghclient *Client) DoEventQuery(issueList []IssueItem, repo string, issueChan chan IssueItem) {
+	ghclient.DoEventQueryContext(context.Background(), issueList, repo, issueChan)
+}
+
+// DoEventQueryContext is DoEventQuery, stopping early when `ctx` is
+// done.
+func (ghclient *Client) DoEventQueryContext(ctx context.Context, issueList []IssueItem, repo string, issueChan chan IssueItem) {
 
 	defer close(issueChan)
 
 	for _, issue := range issueList {
 
 		url := "https://api.github.com/repos/" + repo + "/issues/" + strconv.Itoa(issue.Number) + "/events"
-		body, err := ghclient.Get(url)
+		body, err := ghclient.GetContext(ctx, url)
 		if err != nil {
 			log.Print(err)
+			if ctx.Err() != nil {
+				return
+			}
 		}
 
 		events := make([]IssueEvent, 0)
@@ -145,7 +165,11 @@ func (ghclient *Client) DoEventQuery(issueList []IssueItem, repo string, issueCh
 		issue.Events = events
 		issueChan <- issue
 
-		time.Sleep(500 * time.Millisecond)
+		select {
+		case <-time.After(500 * time.Millisecond):
+		case <-ctx.Done():
+			return
+		}
 
 	}
 
@@ -1,24 +1,100 @@
 module github.com/CapstoneLabs/slick
 
+go 1.27.1
+
 require (
 	github.com/boltdb/bolt v1.3.1
 	github.com/codegangsta/negroni v1.0.0
 	github.com/cskr/pubsub v1.0.1
-	github.com/gobuffalo/envy v1.6.5 // indirect
-	github.com/gohugoio/hugo v0.49.2 // indirect
+	github.com/fsnotify/fsnotify v1.4.7
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/gorilla/context v1.1.1
 	github.com/gorilla/mux v1.6.2
 	github.com/gorilla/sessions v1.1.3
+	github.com/gorilla/websocket v1.4.0
 	github.com/jmcvetta/napping v3.2.0+incompatible
 	github.com/kr/pty v1.1.3
-	github.com/lusis/slack-test v0.0.0-20180109053238-3c758769bfa6 // indirect
+	github.com/mitchellh/mapstructure v1.0.0
 	github.com/nlopes/slack v0.4.0
-	github.com/pkg/errors v0.8.0 // indirect
 	github.com/sirupsen/logrus v1.1.1
 	github.com/spf13/viper v1.2.0
-	github.com/stretchr/objx v0.1.1 // indirect
 	github.com/stretchr/testify v1.2.2
 	golang.org/x/net v0.0.0-20181011144130-49bb7cea24b1
 	golang.org/x/oauth2 v0.0.0-20181003184128-c57b0facaced
+)
+
+require (
+	github.com/BurntSushi/locker v0.0.0-20171006230638-a6e239ea1c69 // indirect
+	github.com/BurntSushi/toml v0.0.0-20170626110600-a368813c5e64 // indirect
+	github.com/PuerkitoBio/purell v1.1.0 // indirect
+	github.com/PuerkitoBio/urlesc v0.0.0-20170810143723-de5bf2ad4578 // indirect
+	github.com/alecthomas/assert v0.0.0-20170929043011-405dbfeb8e38 // indirect
+	github.com/alecthomas/chroma v0.5.0 // indirect
+	github.com/alecthomas/colour v0.0.0-20160524082231-60882d9e2721 // indirect
+	github.com/alecthomas/repr v0.0.0-20180818092828-117648cd9897 // indirect
+	github.com/bep/debounce v1.1.0 // indirect
+	github.com/bep/gitmap v1.0.0 // indirect
+	github.com/bep/go-tocss v0.5.0 // indirect
+	github.com/chaseadamsio/goorgeous v1.1.0 // indirect
+	github.com/cpuguy83/go-md2man v1.0.8 // indirect
+	github.com/danwakefield/fnmatch v0.0.0-20160403171240-cbb64ac3d964 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/disintegration/imaging v1.5.0 // indirect
+	github.com/dlclark/regexp2 v1.1.6 // indirect
+	github.com/eknkc/amber v0.0.0-20171010120322-cdade1c07385 // indirect
+	github.com/fortytw2/leaktest v1.2.0 // indirect
+	github.com/gobuffalo/envy v1.6.5 // indirect
+	github.com/gobwas/glob v0.2.3 // indirect
+	github.com/gohugoio/hugo v0.49.2 // indirect
+	github.com/gorilla/securecookie v1.1.1 // indirect
+	github.com/hashicorp/go-immutable-radix v1.0.0 // indirect
+	github.com/hashicorp/go-uuid v1.0.0 // indirect
+	github.com/hashicorp/golang-lru v0.5.0 // indirect
+	github.com/hashicorp/hcl v1.0.0 // indirect
+	github.com/inconshreveable/mousetrap v1.0.0 // indirect
+	github.com/jdkato/prose v1.1.0 // indirect
+	github.com/joho/godotenv v1.3.0 // indirect
+	github.com/konsorten/go-windows-terminal-sequences v0.0.0-20180402223658-b729f2633dfe // indirect
+	github.com/kr/pretty v0.1.0 // indirect
+	github.com/kr/text v0.1.0 // indirect
+	github.com/kyokomi/emoji v1.5.1 // indirect
+	github.com/lusis/slack-test v0.0.0-20180109053238-3c758769bfa6 // indirect
+	github.com/magefile/mage v1.4.0 // indirect
+	github.com/magiconair/properties v1.8.0 // indirect
+	github.com/markbates/inflect v1.0.0 // indirect
+	github.com/mattn/go-isatty v0.0.4 // indirect
+	github.com/mattn/go-runewidth v0.0.3 // indirect
+	github.com/miekg/mmark v1.3.6 // indirect
+	github.com/mitchellh/hashstructure v1.0.0 // indirect
+	github.com/muesli/smartcrop v0.0.0-20180228075044-f6ebaa786a12 // indirect
+	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646 // indirect
+	github.com/nicksnyder/go-i18n v1.10.0 // indirect
+	github.com/olekukonko/tablewriter v0.0.0-20180506121414-d4647c9c7a84 // indirect
+	github.com/pelletier/go-toml v1.2.0 // indirect
+	github.com/pkg/errors v0.8.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/russross/blackfriday v0.0.0-20180804101149-46c73eb196ba // indirect
+	github.com/sanity-io/litter v1.1.0 // indirect
+	github.com/sergi/go-diff v1.0.0 // indirect
+	github.com/shurcooL/sanitized_anchor_name v0.0.0-20170918181015-86672fcb3f95 // indirect
+	github.com/spf13/afero v1.1.2 // indirect
+	github.com/spf13/cast v1.2.0 // indirect
+	github.com/spf13/cobra v0.0.3 // indirect
+	github.com/spf13/fsync v0.0.0-20170320142552-12a01e648f05 // indirect
+	github.com/spf13/jwalterweatherman v1.0.0 // indirect
+	github.com/spf13/nitro v0.0.0-20131003134307-24d7ef30a12d // indirect
+	github.com/spf13/pflag v1.0.2 // indirect
+	github.com/stretchr/objx v0.1.1 // indirect
+	github.com/tdewolff/minify v2.3.5+incompatible // indirect
+	github.com/tdewolff/parse v2.3.3+incompatible // indirect
+	github.com/tdewolff/test v0.0.0-20171106182207-265427085153 // indirect
+	github.com/wellington/go-libsass v0.0.0-20180624165032-615eaa47ef79 // indirect
+	github.com/yosssi/ace v0.0.5 // indirect
+	golang.org/x/crypto v0.0.0-20180904163835-0709b304e793 // indirect
+	golang.org/x/image v0.0.0-20180708004352-c73c2afc3b81 // indirect
+	golang.org/x/sync v0.0.0-20180314180146-1d60e4601c6f // indirect
 	golang.org/x/sys v0.0.0-20181011152604-fa43e7bc11ba // indirect
+	golang.org/x/text v0.3.0 // indirect
+	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
+	gopkg.in/yaml.v2 v2.2.1 // indirect
 )
@@ -2,9 +2,12 @@
 package healthy
 
 import (
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/CapstoneLabs/slick"
 	log "github.com/sirupsen/logrus"
@@ -12,25 +15,41 @@ import (
 
 // Healthy is a struct holding URL's to evaluate
 type Healthy struct {
-	urls []string
+	lock   sync.Mutex
+	config Config
 }
 
-func init() {
-	slick.RegisterPlugin(&Healthy{})
+// Config is the HealthCheck section of the config file.
+type Config struct {
+	Urls []string
 }
 
-// InitPlugin loads configuration and listens for new messages
-func (healthy *Healthy) InitPlugin(bot *slick.Bot) {
-	var conf struct {
-		HealthCheck struct {
-			Urls []string
+// Validate checks the URLs, see `slick.ConfigValidator`.
+func (c *Config) Validate() error {
+	for _, rawURL := range c.Urls {
+		parsed, err := url.Parse(rawURL)
+		if err != nil {
+			return err
+		}
+		if parsed.Scheme != "http" && parsed.Scheme != "https" {
+			return fmt.Errorf("%q isn't an http(s) URL", rawURL)
 		}
 	}
+	return nil
+}
 
-	bot.LoadConfig(&conf)
+func init() {
+	slick.RegisterPlugin(&Healthy{})
+}
 
-	healthy.urls = conf.HealthCheck.Urls
+// ConfigSchema declares the HealthCheck section of the config, loaded
+// before InitPlugin. See `slick.ConfigSchema`.
+func (healthy *Healthy) ConfigSchema() (string, interface{}) {
+	return "HealthCheck", &healthy.config
+}
 
+// InitPlugin listens for new messages
+func (healthy *Healthy) InitPlugin(bot *slick.Bot) {
 	bot.Listen(&slick.Listener{
 		MentionsMeOnly:     true,
 		ContainsAny:        []string{"health", "healthy?", "health_check"},
@@ -38,6 +57,19 @@ func (healthy *Healthy) InitPlugin(bot *slick.Bot) {
 	})
 }
 
+// ReloadConfig loads the URLs to check, see `slick.ConfigReloader`.
+func (healthy *Healthy) ReloadConfig(bot *slick.Bot) error {
+	var config Config
+	if err := bot.LoadPluginConfig("HealthCheck", &config); err != nil {
+		return err
+	}
+
+	healthy.lock.Lock()
+	healthy.config = config
+	healthy.lock.Unlock()
+	return nil
+}
+
 // ChatHandler replies to the end user
 func (healthy *Healthy) ChatHandler(listen *slick.Listener, msg *slick.Message) {
 	log.Println("Health check. Requested by", msg.FromUser.Name)
@@ -46,9 +78,13 @@ func (healthy *Healthy) ChatHandler(listen *slick.Listener, msg *slick.Message)
 
 // CheckAll checks each URL in the struct
 func (healthy *Healthy) CheckAll() string {
+	healthy.lock.Lock()
+	urls := healthy.config.Urls
+	healthy.lock.Unlock()
+
 	result := make(map[string]bool)
 	failed := make([]string, 0)
-	for _, url := range healthy.urls {
+	for _, url := range urls {
 		ok := check(url)
 		result[url] = ok
 		if !ok {
@@ -57,7 +93,7 @@ func (healthy *Healthy) CheckAll() string {
 	}
 	if len(failed) == 0 {
 		return "All green (For " +
-			strings.Join(healthy.urls, ", ") + ")"
+			strings.Join(urls, ", ") + ")"
 	} else {
 		return "WARN!! Something wrong with " +
 			strings.Join(failed, ", ")
@@ -0,0 +1,368 @@
+package slick
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// InteractionsPath is where Slack posts interactions (button clicks,
+// menu selections and modal submissions), on the WebServer's public
+// router. Set it as the app's Interactivity Request URL.
+const InteractionsPath = "/public/slack/interactions"
+
+type interaction string
+
+// BlockActions is the `Type` of InteractionEvents for clicks on
+// buttons and picks in select menus.
+const BlockActions = interaction("block_actions")
+
+// ViewSubmission is the `Type` of InteractionEvents for modal
+// submissions.
+const ViewSubmission = interaction("view_submission")
+
+// ViewClosed is the `Type` of InteractionEvents for modals closed
+// without submitting, when they have `NotifyOnClose`.
+const ViewClosed = interaction("view_closed")
+
+// InteractionListener listens for interactions with Block Kit
+// elements, registered with `Bot.ListenInteraction` or
+// `Reply.ListenInteraction`.
+type InteractionListener struct {
+	ListenUntil    time.Time
+	ListenDuration time.Duration
+	FromUser       *slack.User
+	Type           interaction
+
+	// ActionID filters BlockActions on the `ActionID` of the element.
+	ActionID string
+	// BlockID filters BlockActions on the `BlockID` of the block.
+	BlockID string
+	// CallbackID filters ViewSubmission and ViewClosed on the
+	// `CallbackID` of the modal.
+	CallbackID string
+
+	HandlerFunc func(listen *InteractionListener, event *InteractionEvent)
+	TimeoutFunc func(*InteractionListener)
+
+	listener *Listener
+}
+
+func (interListen *InteractionListener) newListener() *Listener {
+	newListen := &Listener{}
+	if !interListen.ListenUntil.IsZero() {
+		newListen.ListenUntil = interListen.ListenUntil
+	}
+	if interListen.ListenDuration != time.Duration(0) {
+		newListen.ListenDuration = interListen.ListenDuration
+	}
+	if interListen.TimeoutFunc != nil {
+		newListen.TimeoutFunc = func(listen *Listener) {
+			interListen.TimeoutFunc(interListen)
+		}
+	}
+	interListen.listener = newListen
+
+	return newListen
+}
+
+func (listen *InteractionListener) filterInteraction(ev *InteractionEvent) bool {
+	if listen.Type != "" && ev.Type != listen.Type {
+		return false
+	}
+	if listen.FromUser != nil && ev.User != listen.FromUser.ID {
+		return false
+	}
+	if listen.ActionID != "" && ev.ActionID != listen.ActionID {
+		return false
+	}
+	if listen.BlockID != "" && ev.BlockID != listen.BlockID {
+		return false
+	}
+	if listen.CallbackID != "" && ev.CallbackID != listen.CallbackID {
+		return false
+	}
+	return true
+}
+
+func (listen *InteractionListener) Close() {
+	listen.listener.Close()
+}
+
+func (listen *InteractionListener) ResetNewDuration(d time.Duration) {
+	listen.listener.ListenDuration = d
+	listen.listener.ResetDuration()
+}
+
+func (listen *InteractionListener) ResetDuration() {
+	listen.listener.ResetDuration()
+}
+
+// InteractionEvent is a click, a menu pick or a modal submission,
+// dispatched to InteractionListeners. It also goes through the
+// `EventHandlerFunc` of all Listeners.
+type InteractionEvent struct {
+	Type interaction
+	// User is the ID of the user who interacted.
+	User    string
+	Channel string
+	// MessageTimestamp is the timestamp of the message holding the
+	// element, for BlockActions.
+	MessageTimestamp string
+	// TriggerID can be used to open a modal, with `Bot.OpenModal`.
+	TriggerID   string
+	ResponseURL string
+
+	// For BlockActions, one event is dispatched per action.
+	ActionID string
+	BlockID  string
+	// Value is the value of the clicked button, or of the picked
+	// option.
+	Value string
+
+	// For ViewSubmission and ViewClosed.
+	CallbackID      string
+	PrivateMetadata string
+	// Values holds the inputs of a submitted modal, by block ID and
+	// then action ID.
+	Values map[string]map[string]string
+
+	// Payload is the original payload sent by Slack.
+	Payload json.RawMessage
+
+	// Listener is a reference to the InteractionListener handling the
+	// event.
+	Listener *InteractionListener
+}
+
+// Respond posts a message through the `ResponseURL` of the
+// interaction. With `replaceOriginal`, the message holding the
+// element is replaced.
+func (ev *InteractionEvent) Respond(text string, blocks []Block, replaceOriginal bool) error {
+	if ev.ResponseURL == "" {
+		return fmt.Errorf("interaction of type %q has no response URL", ev.Type)
+	}
+
+	content, err := json.Marshal(map[string]interface{}{
+		"text":             text,
+		"blocks":           blocks,
+		"replace_original": replaceOriginal,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.Post(ev.ResponseURL, "application/json", bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("response URL returned %s", resp.Status)
+	}
+	return nil
+}
+
+// ListenInteraction dispatches the matching interactions to the
+// listener.
+func (bot *Bot) ListenInteraction(interListen *InteractionListener) {
+	bot.listenInteraction("", interListen, callerPlugin())
+}
+
+// ListenInteraction dispatches the interactions with the elements of
+// this Reply, sent with `SendBlocks`, to the listener.
+func (r *Reply) ListenInteraction(interListen *InteractionListener) {
+	plugin := callerPlugin()
+	r.OnAck(func(ack *slack.AckMessage) {
+		r.bot.listenInteraction(ack.Timestamp, interListen, plugin)
+	})
+}
+
+func (bot *Bot) listenInteraction(messageTimestamp string, interListen *InteractionListener, plugin string) {
+	listen := interListen.newListener()
+	listen.plugin = plugin
+	listen.EventHandlerFunc = func(_ *Listener, event interface{}) {
+		ev, ok := event.(*InteractionEvent)
+		if !ok {
+			return
+		}
+
+		if messageTimestamp != "" && ev.MessageTimestamp != messageTimestamp {
+			return
+		}
+
+		if !interListen.filterInteraction(ev) {
+			return
+		}
+
+		dispatched := *ev
+		dispatched.Listener = interListen
+		interListen.HandlerFunc(interListen, &dispatched)
+	}
+	bot.Listen(listen)
+}
+
+// interactionPayload is the subset of Slack's interaction payloads we
+// decode.
+type interactionPayload struct {
+	Type string `json:"type"`
+	Team struct {
+		ID string `json:"id"`
+	} `json:"team"`
+	TriggerID   string `json:"trigger_id"`
+	ResponseURL string `json:"response_url"`
+	User        struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	Container struct {
+		MessageTimestamp string `json:"message_ts"`
+		ChannelID        string `json:"channel_id"`
+	} `json:"container"`
+	Actions []struct {
+		ActionID       string `json:"action_id"`
+		BlockID        string `json:"block_id"`
+		Value          string `json:"value"`
+		SelectedOption struct {
+			Value string `json:"value"`
+		} `json:"selected_option"`
+	} `json:"actions"`
+	View struct {
+		CallbackID      string `json:"callback_id"`
+		PrivateMetadata string `json:"private_metadata"`
+		State           struct {
+			Values map[string]map[string]struct {
+				Value          string `json:"value"`
+				SelectedOption struct {
+					Value string `json:"value"`
+				} `json:"selected_option"`
+			} `json:"values"`
+		} `json:"state"`
+	} `json:"view"`
+}
+
+// parseInteraction converts an interaction payload to one or more
+// InteractionEvents.
+func parseInteraction(raw []byte) ([]*InteractionEvent, error) {
+	var payload interactionPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, err
+	}
+
+	base := InteractionEvent{
+		Type:             interaction(payload.Type),
+		User:             payload.User.ID,
+		Channel:          payload.Channel.ID,
+		MessageTimestamp: payload.Container.MessageTimestamp,
+		TriggerID:        payload.TriggerID,
+		ResponseURL:      payload.ResponseURL,
+		Payload:          raw,
+	}
+	if base.Channel == "" {
+		base.Channel = payload.Container.ChannelID
+	}
+
+	switch base.Type {
+	case BlockActions:
+		var events []*InteractionEvent
+		for _, action := range payload.Actions {
+			ev := base
+			ev.ActionID = action.ActionID
+			ev.BlockID = action.BlockID
+			ev.Value = action.Value
+			if ev.Value == "" {
+				ev.Value = action.SelectedOption.Value
+			}
+			events = append(events, &ev)
+		}
+		return events, nil
+
+	case ViewSubmission, ViewClosed:
+		ev := base
+		ev.CallbackID = payload.View.CallbackID
+		ev.PrivateMetadata = payload.View.PrivateMetadata
+		ev.Values = make(map[string]map[string]string)
+		for blockID, actions := range payload.View.State.Values {
+			ev.Values[blockID] = make(map[string]string)
+			for actionID, input := range actions {
+				value := input.Value
+				if value == "" {
+					value = input.SelectedOption.Value
+				}
+				ev.Values[blockID][actionID] = value
+			}
+		}
+		return []*InteractionEvent{&ev}, nil
+	}
+
+	return []*InteractionEvent{&base}, nil
+}
+
+// handleInteractions receives the interactions posted by Slack on
+// InteractionsPath, verifies them with the signing secret of their
+// team, and injects them in that team's event loop.
+func (bot *Bot) handleInteractions(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "couldn't read body", http.StatusBadRequest)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	payload := []byte(form.Get("payload"))
+
+	// The team is only trusted once the signature is verified with
+	// its secret
+	var header interactionPayload
+	json.Unmarshal(payload, &header)
+	team := bot.Team
+	if other := bot.TeamByID(header.Team.ID); header.Team.ID != "" && other != nil {
+		team = other
+	}
+
+	secret := team.Config().SigningSecret
+	err = errors.New("no signing_secret configured")
+	if secret != "" {
+		err = verifySlackSignature(r.Header, body, secret, time.Now())
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Type":   "InteractionSignature",
+			"Team":   team.Name,
+			"Remote": r.RemoteAddr,
+		}).WithError(err).Warn("Refused interaction request.")
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	events, err := parseInteraction(payload)
+	if err != nil {
+		log.WithError(err).Warn("Couldn't decode interaction payload.")
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	for _, ev := range events {
+		team.injectEvent(slack.RTMEvent{Type: string(ev.Type), Data: ev})
+	}
+
+	// An empty response closes modals, and acknowledges everything
+	// else.
+	w.WriteHeader(http.StatusOK)
+}
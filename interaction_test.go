@@ -0,0 +1,109 @@
+package slick
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const buttonClickPayload = `{
+	"type": "block_actions",
+	"trigger_id": "123.456",
+	"user": {"id": "U1", "name": "bob"},
+	"channel": {"id": "C1"},
+	"container": {"type": "message", "message_ts": "1234.5678", "channel_id": "C1"},
+	"response_url": "https://hooks.slack.com/actions/T/1/xyz",
+	"actions": [{"action_id": "vote", "block_id": "b1", "value": "pizza"}]
+}`
+
+const modalSubmissionPayload = `{
+	"type": "view_submission",
+	"user": {"id": "U1"},
+	"view": {
+		"callback_id": "feedback",
+		"private_metadata": "C1",
+		"state": {"values": {
+			"comment": {"text": {"type": "plain_text_input", "value": "great bot"}},
+			"rating": {"stars": {"type": "static_select", "selected_option": {"value": "5"}}}
+		}}
+	}
+}`
+
+func TestParseInteraction(t *testing.T) {
+	events, err := parseInteraction([]byte(buttonClickPayload))
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		ev := events[0]
+		assert.Equal(t, BlockActions, ev.Type)
+		assert.Equal(t, "U1", ev.User)
+		assert.Equal(t, "C1", ev.Channel)
+		assert.Equal(t, "1234.5678", ev.MessageTimestamp)
+		assert.Equal(t, "vote", ev.ActionID)
+		assert.Equal(t, "pizza", ev.Value)
+	}
+
+	events, err = parseInteraction([]byte(modalSubmissionPayload))
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		ev := events[0]
+		assert.Equal(t, ViewSubmission, ev.Type)
+		assert.Equal(t, "feedback", ev.CallbackID)
+		assert.Equal(t, "great bot", ev.Values["comment"]["text"])
+		assert.Equal(t, "5", ev.Values["rating"]["stars"])
+	}
+}
+
+func TestHandleInteractions(t *testing.T) {
+	bot := New("")
+	bot.setConfig(SlackConfig{SigningSecret: "s3cr3t"})
+
+	post := func(payload, secret string) int {
+		body := url.Values{"payload": {payload}}.Encode()
+		req := httptest.NewRequest("POST", InteractionsPath, strings.NewReader(body))
+		signRequest(req, body, secret, time.Now())
+		rec := httptest.NewRecorder()
+		bot.handleInteractions(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, post(buttonClickPayload, "wrong"))
+	assert.Equal(t, http.StatusOK, post(buttonClickPayload, "s3cr3t"))
+
+	select {
+	case event := <-bot.internalEvents:
+		ev := event.Data.(*InteractionEvent)
+		listen := &InteractionListener{ActionID: "vote"}
+		assert.True(t, listen.filterInteraction(ev))
+		listen = &InteractionListener{Type: ViewSubmission}
+		assert.False(t, listen.filterInteraction(ev))
+	case <-time.After(time.Second):
+		t.Fatal("interaction not injected")
+	}
+
+	// Interactions of other teams are verified with their secret, and
+	// injected in their event loop
+	acme := newTeam(bot, "acme")
+	acme.ID = "T2"
+	acme.setConfig(SlackConfig{SigningSecret: "acme-s3cr3t"})
+	bot.teams = append(bot.teams, acme)
+	acmeClick := strings.Replace(buttonClickPayload, `"type": "block_actions",`, `"type": "block_actions", "team": {"id": "T2"},`, 1)
+
+	assert.Equal(t, http.StatusUnauthorized, post(acmeClick, "s3cr3t"))
+	assert.Equal(t, http.StatusOK, post(acmeClick, "acme-s3cr3t"))
+	select {
+	case te := <-bot.teamEvents:
+		assert.Equal(t, acme, te.team)
+		assert.Equal(t, "vote", te.event.Data.(*InteractionEvent).ActionID)
+	case <-time.After(time.Second):
+		t.Fatal("interaction not injected in its team")
+	}
+	assert.Len(t, bot.internalEvents, 0)
+
+	acme.setConfig(SlackConfig{})
+	assert.Equal(t, http.StatusUnauthorized, post(acmeClick, ""), "teams without a secret refuse interactions")
+}
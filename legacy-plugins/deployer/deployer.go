@@ -2,6 +2,7 @@ package deployer
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -26,6 +27,7 @@ type Deployer struct {
 	pubsub     *pubsub.PubSub
 	internal   *internal.InternalAPI
 	lockedBy   string
+	deploys    *slick.CounterVec
 }
 
 type DeployerConfig struct {
@@ -48,6 +50,7 @@ func (dep *Deployer) InitPlugin(bot *slick.Bot) {
 	bot.LoadConfig(&conf)
 
 	dep.bot = bot
+	dep.deploys = bot.Metrics.Counter("deployer_deploys_total", "Deploys run, by environment and result.", "environment", "result")
 	dep.pubsub = pubsub.New(100)
 	dep.config = &conf.Deployer
 	dep.env = os.Getenv("PLOTLY_ENV")
@@ -87,22 +90,33 @@ type DeployJob struct {
 	params  *DeployParams
 	quit    chan bool
 	kill    chan bool
+	done    chan bool
 	killing bool
 }
 
+// Permissions needed to deploy or cancel a deploy, and to lock or
+// unlock deployments.
+const (
+	PermissionDeploy = "deployer.deploy"
+	PermissionLock   = "deployer.lock"
+)
+
 var deployFormat = regexp.MustCompile(`deploy( ([a-zA-Z0-9_\.-]+))? to ([a-z_-]+)( using ([a-zA-Z0-9_\.-]+))?((,| with)? tags?:? ?(.+))?`)
 
 func (dep *Deployer) ChatHandler(listen *slick.Listener, msg *slick.Message) {
 	bot := listen.Bot
 
 	// Discard non "mention_name, " prefixed messages
-	if !strings.HasPrefix(msg.Text, fmt.Sprintf("%s, ", bot.Config.Nickname)) {
+	if !strings.HasPrefix(msg.Text, fmt.Sprintf("%s, ", bot.Config().Nickname)) {
 		return
 	}
 
 	if match := deployFormat.FindStringSubmatch(msg.Text); match != nil {
+		if !bot.RequirePermission(msg, PermissionDeploy) {
+			return
+		}
 		if dep.lockedBy != "" {
-			msg.Reply(fmt.Sprintf("Deployment was locked by %s.  Unlock with '%s, unlock deployment' if they're OK with it.", dep.lockedBy, dep.bot.Config.Nickname))
+			msg.Reply(fmt.Sprintf("Deployment was locked by %s.  Unlock with '%s, unlock deployment' if they're OK with it.", dep.lockedBy, dep.bot.Config().Nickname))
 			return
 		}
 		if dep.runningJob != nil {
@@ -119,11 +133,21 @@ func (dep *Deployer) ChatHandler(listen *slick.Listener, msg *slick.Message) {
 				From:             "chat",
 				initiatedByChat:  msg,
 			}
+			bot.Audit(msg.Context(), slick.AuditEntry{
+				Actor:   msg.FromUser.ID,
+				Action:  "deployer.deploy",
+				Channel: msg.Channel,
+				Target:  params.String(),
+				Outcome: slick.AuditOK,
+			})
 			go dep.handleDeploy(params)
 		}
 		return
 
 	} else if msg.Contains("cancel deploy") {
+		if !bot.RequirePermission(msg, PermissionDeploy) {
+			return
+		}
 
 		if dep.runningJob == nil {
 			msg.Reply("No deploy running, sorry man..")
@@ -147,15 +171,21 @@ func (dep *Deployer) ChatHandler(listen *slick.Listener, msg *slick.Message) {
 			msg.Reply(fmt.Sprintf("@%s couldn't get current revision on prod", mention))
 		}
 	} else if msg.Contains("unlock deploy") {
+		if !bot.RequirePermission(msg, PermissionLock) {
+			return
+		}
 		dep.lockedBy = ""
 		msg.Reply(fmt.Sprintf("Deployment is now unlocked."))
 		bot.Notify(dep.config.AnnounceRoom, "purple", "text", fmt.Sprintf("%s has unlocked deployment", msg.FromUser.Name), true)
 	} else if msg.Contains("lock deploy") {
+		if !bot.RequirePermission(msg, PermissionLock) {
+			return
+		}
 		dep.lockedBy = msg.FromUser.Name
-		msg.Reply(fmt.Sprintf("Deployment is now locked.  Unlock with '%s, unlock deployment' ASAP!", dep.bot.Config.Nickname))
+		msg.Reply(fmt.Sprintf("Deployment is now locked.  Unlock with '%s, unlock deployment' ASAP!", dep.bot.Config().Nickname))
 		bot.Notify(dep.config.AnnounceRoom, "purple", "text", fmt.Sprintf("%s has locked deployment", dep.lockedBy), true)
 	} else if msg.Contains("deploy") || msg.Contains("push to") {
-		mention := dep.bot.Config.Nickname
+		mention := dep.bot.Config().Nickname
 		msg.Reply(fmt.Sprintf(`Usage: %s, [please|insert reverence] deploy [<branch-name>] to <environment> [using <deployment-branch>][, tags: <ansible-playbook tags>, ..., ...]
 examples: %s, please deploy to prod
 %s, deploy thing-to-test to stage
@@ -234,6 +264,7 @@ func (dep *Deployer) handleDeploy(params *DeployParams) {
 		params:  params,
 		quit:    make(chan bool, 2),
 		kill:    make(chan bool, 2),
+		done:    make(chan bool),
 	}
 
 	go dep.manageDeployIo(pty)
@@ -242,15 +273,37 @@ func (dep *Deployer) handleDeploy(params *DeployParams) {
 	if err := cmd.Wait(); err != nil {
 		dep.pubLine(fmt.Sprintf("[deployer] terminated with error: %s", err))
 		dep.replyPersonnally(params, fmt.Sprintf("your deploy failed: %s", err))
+		dep.deploys.Inc(params.Environment, "failed")
 	} else {
 		dep.pubLine("[deployer] terminated successfully")
+		dep.deploys.Inc(params.Environment, "succeeded")
 		dep.replyPersonnally(params, bot.WithMood("your deploy was successful", "your deploy was GREAT, you're great !"))
 	}
 
 	dep.runningJob.quit <- true
+	close(dep.runningJob.done)
 	dep.runningJob = nil
 }
 
+// StopPlugin waits for the running deploy to finish, and interrupts it
+// if the bot can't wait any longer.
+func (dep *Deployer) StopPlugin(ctx context.Context) error {
+	job := dep.runningJob
+	if job == nil {
+		return nil
+	}
+
+	dep.pubLine("[deployer] Bot is shutting down, waiting for the deploy to finish")
+	select {
+	case <-job.done:
+		return nil
+	case <-ctx.Done():
+		dep.pubLine("[deployer] Bot is shutting down, interrupting the deploy")
+		job.kill <- true
+		return ctx.Err()
+	}
+}
+
 func (dep *Deployer) pullDeployRepo(deploymentBranch string) error {
 	cmd := exec.Command("git", "fetch")
 	cmd.Dir = dep.config.RepositoryPath
@@ -2,7 +2,6 @@ package totw
 
 import (
 	"strings"
-	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -46,7 +45,12 @@ func (totw *Totw) InitPlugin(bot *slick.Bot) {
 
 	totw.bot = bot
 
-	go totw.ScheduleAlerts(bot.Config.GeneralChannel, time.Thursday, 16, 0)
+	_, err := bot.Scheduler.Cron("totw", "0 16 * * thu", func() {
+		totw.SendAlert(bot.Config().GeneralChannel)
+	})
+	if err != nil {
+		log.Println("TOTW: couldn't schedule alerts:", err)
+	}
 
 	bot.Listen(&slick.Listener{
 		MessageHandlerFunc: totw.ChatHandler,
@@ -59,14 +63,7 @@ func (totw *Totw) ChatHandler(conv *slick.Listener, msg *slick.Message) {
 	}
 }
 
-func (totw *Totw) ScheduleAlerts(channel string, w time.Weekday, hour, min int) {
-	for {
-		next, when := slick.NextWeekdayTime(time.Now(), w, hour, min)
-		log.Println("TOTW: Next occurrence: ", next)
-
-		<-time.After(when)
-
-		totw.bot.SendToChannel(channel, slick.RandomString("useless techs"))
-		totw.bot.SendToChannel(channel, `Time for some Tech of the Week! What's your pick ?  Start your line with "!techoftheweek"`)
-	}
+func (totw *Totw) SendAlert(channel string) {
+	totw.bot.SendToChannel(channel, slick.RandomString("useless techs"))
+	totw.bot.SendToChannel(channel, `Time for some Tech of the Week! What's your pick ?  Start your line with "!techoftheweek"`)
 }
@@ -1,8 +1,11 @@
 package slick
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"regexp"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -15,6 +18,13 @@ import (
 type Listener struct {
 	// replyAck is filled when you call Listen() on a Reply.
 	replyAck *slack.AckMessage
+	// team, when set, restricts the Listener to the events of a Team,
+	// like the acknowledgements of a Reply.
+	team *Team
+
+	// Name identifies the Listener in logs and panic reports. It
+	// defaults to the name of the handler function.
+	Name string
 
 	// ListenUntil sets an absolute date at which this Listener
 	// expires and stops listening.  ListenUntil and ListenDuration
@@ -52,26 +62,32 @@ type Listener struct {
 
 	// Matches checks that the given text matches the given Regexp
 	// with a `FindStringSubmatch` call. It will set the `Message.Match`
-	// attribute.
-	//
-	// NOTE: if you spin off a goroutine in the MessageHandlerFunc,
-	// make sure to keep a copy of the `Message.Match` object because it
-	// will be overwritten by the next Listener the moment your
-	// MessageHandlerFunc unblocks.
+	// attribute, on this Listener's own copy of the Message.
 	Matches *regexp.Regexp
 
+	// InThread filters out messages not posted in the thread started
+	// by the message with this timestamp. See `Message.ThreadRoot()`
+	// and `Reply.ListenThread()`.
+	InThread string
+
 	// ListenForEdits will trigger a message when a user edits a
 	// message as well as creates a new one.
 	ListenForEdits bool
 
 	// MentionsMe filters out messages that do not mention the Bot's
-	// `bot.Config.MentionName`
+	// `bot.Config().MentionName`
 	MentionsMeOnly bool
 
 	// MatchMyMessages equal to false filters out messages that the bot
 	// himself sent.
 	MatchMyMessages bool
 
+	// Permission, when set, is needed for the messages passing the
+	// other filters to reach the handler, see `Bot.Can`. Others get a
+	// reply saying so, so narrow down the messages with the other
+	// filters. Events other than messages aren't checked.
+	Permission string
+
 	// MessageHandlerFunc is a handling function provided by the user, and
 	// called when a relevant message comes in.
 	MessageHandlerFunc func(*Listener, *Message)
@@ -96,21 +112,64 @@ type Listener struct {
 
 	TimeoutFunc func(*Listener)
 
+	// Workers is the number of goroutines running this Listener's
+	// handler, DefaultListenerWorkers if zero. The events of a given
+	// channel are always handled in order, by the same worker.
+	Workers int
+
+	// QueueSize bounds the events waiting for each worker,
+	// DefaultListenerQueueSize if zero. Events are dropped when the
+	// queue is full, see `Bot.ListenerStats`.
+	QueueSize int
+
 	// Bot is a reference to the bot instance.  It will always be populated before being
 	// passed to handler functions.
 	Bot *Bot
 
 	resetCh chan bool
 	doneCh  chan bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	// panics counts the panics of the handler, see `recoverListener`.
+	panics int32
+	// plugin is the package which registered the Listener, see
+	// `callerPlugin`.
+	plugin string
+	queue  *listenerQueue
+	// closed is set by Close, so the workers stop handling the events
+	// already queued before the Listener is removed.
+	closed int32
 }
 
 // Close terminates the Listener management goroutine, and stops
 // any further listening and message handling
 func (listen *Listener) Close() {
+	atomic.StoreInt32(&listen.closed, 1)
+	listen.cancelContext()
 	listen.Bot.delListenerCh <- listen
 	listen.doneCh <- true
 }
 
+// Context returns the context of the Listener, cancelled when it is
+// closed or when the bot shuts down. The contexts of the Messages it
+// handles derive from it.
+func (listen *Listener) Context() context.Context {
+	if listen.ctx == nil {
+		return context.Background()
+	}
+	return listen.ctx
+}
+
+func (listen *Listener) isClosed() bool {
+	return atomic.LoadInt32(&listen.closed) != 0
+}
+
+func (listen *Listener) cancelContext() {
+	if listen.cancel != nil {
+		listen.cancel()
+	}
+}
+
 // ReplyAck returns the AckMessage received that corresponds to the Reply
 // on which you called Listen()
 func (listen *Listener) ReplyAck() *slack.AckMessage {
@@ -124,7 +183,7 @@ func (listen *Listener) ResetDuration() error {
 	if int64(listen.ListenDuration) == 0 {
 		msg := "Listener has no ListenDuration"
 		log.Println("ResetDuration() error: ", msg)
-		return fmt.Errorf(msg)
+		return errors.New(msg)
 	}
 
 	listen.resetCh <- true
@@ -190,12 +249,7 @@ func (listen *Listener) checkParams() error {
 func (listen *Listener) setupChannels() {
 	listen.resetCh = make(chan bool, 10)
 	listen.doneCh = make(chan bool, 10)
-}
-
-func (listen *Listener) filterAndDispatchMessage(msg *Message) {
-	if listen.filterMessage(msg) {
-		listen.MessageHandlerFunc(listen, msg)
-	}
+	listen.ctx, listen.cancel = context.WithCancel(listen.Bot.ctx)
 }
 
 // filterMessage applies checks from a Listener against a Message.
@@ -213,6 +267,10 @@ func (listen *Listener) filterMessage(msg *Message) bool {
 		return false
 	}
 
+	if listen.InThread != "" && msg.ThreadTimestamp != listen.InThread {
+		return false
+	}
+
 	if listen.MentionsMeOnly && !msg.MentionsMe {
 		return false
 	}
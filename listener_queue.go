@@ -0,0 +1,186 @@
+package slick
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync/atomic"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultListenerWorkers is the number of workers of a Listener
+	// which doesn't set `Workers`.
+	DefaultListenerWorkers = 1
+	// DefaultListenerQueueSize is the size of the workers' queues of a
+	// Listener which doesn't set `QueueSize`.
+	DefaultListenerQueueSize = 100
+)
+
+// listenerQueue runs the handlers of a Listener on its own workers, so
+// a slow Listener doesn't hold the others back. Each worker has a
+// bounded queue: when it's full, events are dropped.
+type listenerQueue struct {
+	queues []chan *Dispatch
+	closed int32
+
+	// depth counts the dispatches queued or running.
+	depth   int64
+	handled uint64
+	dropped uint64
+}
+
+// ListenerStats are the dispatch counters of a Listener, see
+// `Bot.ListenerStats`.
+type ListenerStats struct {
+	Name   string
+	Plugin string
+	// Queued is the number of events waiting for, or being handled
+	// by, the Listener's workers.
+	Queued  int64
+	Handled uint64
+	// Dropped is the number of events dropped because the queues
+	// were full.
+	Dropped uint64
+}
+
+// startListener launches the workers of a Listener.
+func (bot *Bot) startListener(listen *Listener) {
+	workers := listen.Workers
+	if workers <= 0 {
+		workers = DefaultListenerWorkers
+	}
+	size := listen.QueueSize
+	if size <= 0 {
+		size = DefaultListenerQueueSize
+	}
+
+	lq := &listenerQueue{}
+	for i := 0; i < workers; i++ {
+		queue := make(chan *Dispatch, size)
+		lq.queues = append(lq.queues, queue)
+		go bot.listenerWorker(lq, queue)
+	}
+	listen.queue = lq
+
+	bot.listenerStatsLock.Lock()
+	bot.listenerQueues[listen] = lq
+	bot.listenerStatsLock.Unlock()
+}
+
+// stopListener stops the workers of a removed Listener. The events
+// still queued are discarded.
+func (bot *Bot) stopListener(listen *Listener) {
+	lq := listen.queue
+	if lq == nil {
+		return
+	}
+
+	atomic.StoreInt32(&lq.closed, 1)
+	for _, queue := range lq.queues {
+		close(queue)
+	}
+
+	bot.listenerStatsLock.Lock()
+	delete(bot.listenerQueues, listen)
+	bot.listenerStatsLock.Unlock()
+}
+
+func (bot *Bot) listenerWorker(lq *listenerQueue, queue chan *Dispatch) {
+	for d := range queue {
+		// The Listener may be closed before the event loop removes it:
+		// its queued events are dropped.
+		if atomic.LoadInt32(&lq.closed) == 0 && !d.Listener.isClosed() {
+			bot.dispatch(d)
+			atomic.AddUint64(&lq.handled, 1)
+		}
+		atomic.AddInt64(&lq.depth, -1)
+	}
+}
+
+// enqueue hands a Dispatch to the Listener's workers. Events of a
+// given channel always go to the same worker, so they are handled in
+// order.
+func (bot *Bot) enqueue(d *Dispatch) {
+	listen := d.Listener
+	if listen.queue == nil {
+		bot.startListener(listen)
+	}
+	lq := listen.queue
+
+	queue := lq.queues[0]
+	if len(lq.queues) > 1 {
+		hash := fnv.New32a()
+		hash.Write([]byte(eventChannel(d.Event)))
+		queue = lq.queues[hash.Sum32()%uint32(len(lq.queues))]
+	}
+
+	atomic.AddInt64(&lq.depth, 1)
+	select {
+	case queue <- d:
+	default:
+		atomic.AddInt64(&lq.depth, -1)
+		dropped := atomic.AddUint64(&lq.dropped, 1)
+		name, plugin := listen.identity()
+		log.WithFields(log.Fields{
+			"Type":     "ListenerQueueFull",
+			"Listener": name,
+			"Plugin":   plugin,
+			"Dropped":  dropped,
+		}).Warn("Listener queue is full, dropping event.")
+	}
+}
+
+// eventChannel returns the channel an event happened in, if any.
+func eventChannel(event interface{}) string {
+	switch ev := event.(type) {
+	case *Message:
+		return ev.Channel
+	case *slack.ReactionAddedEvent:
+		return ev.Item.Channel
+	case *slack.ReactionRemovedEvent:
+		return ev.Item.Channel
+	case *InteractionEvent:
+		return ev.Channel
+	}
+	return ""
+}
+
+// ListenerStats returns the dispatch counters of the active
+// Listeners, by plugin and name.
+func (bot *Bot) ListenerStats() []ListenerStats {
+	bot.listenerStatsLock.Lock()
+	defer bot.listenerStatsLock.Unlock()
+
+	var stats []ListenerStats
+	for listen, lq := range bot.listenerQueues {
+		name, plugin := listen.identity()
+		stats = append(stats, ListenerStats{
+			Name:    name,
+			Plugin:  plugin,
+			Queued:  atomic.LoadInt64(&lq.depth),
+			Handled: atomic.LoadUint64(&lq.handled),
+			Dropped: atomic.LoadUint64(&lq.dropped),
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Plugin != stats[j].Plugin {
+			return stats[i].Plugin < stats[j].Plugin
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// copy returns a copy of the Message, for a Listener to own.
+func (msg *Message) copy() *Message {
+	out := *msg
+	if msg.Msg != nil {
+		inner := *msg.Msg
+		out.Msg = &inner
+	}
+	out.Match = nil
+	return &out
+}
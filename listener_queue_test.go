@@ -0,0 +1,156 @@
+package slick
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitListeners waits for the listeners' workers to handle all the
+// queued events.
+func waitListeners(t *testing.T, bot *Bot) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		idle := true
+		for _, stats := range bot.ListenerStats() {
+			if stats.Queued != 0 {
+				idle = false
+			}
+		}
+		if idle {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("listeners still busy")
+}
+
+func messageEvent(channel, text string) *slack.RTMEvent {
+	return &slack.RTMEvent{Type: "message", Data: &slack.MessageEvent{
+		Msg: slack.Msg{Type: "message", Channel: channel, User: "U1", Text: text},
+	}}
+}
+
+func TestSlowListenerDoesntBlockOthers(t *testing.T) {
+	bot := New("")
+	bot.Users.Set(slack.User{ID: "U1", Name: "bob"})
+	bot.Channels.Set(Channel{ID: "C1", Name: "general", IsChannel: true})
+
+	started := make(chan bool, 10)
+	unblock := make(chan bool)
+	fast := make(chan string, 10)
+	bot.listeners = []*Listener{
+		{
+			Name:      "slow",
+			QueueSize: 1,
+			MessageHandlerFunc: func(*Listener, *Message) {
+				started <- true
+				<-unblock
+			},
+		},
+		{
+			Name: "fast",
+			MessageHandlerFunc: func(_ *Listener, msg *Message) {
+				fast <- msg.Text
+			},
+		},
+	}
+
+	for i := 0; i < 3; i++ {
+		bot.handleRTMEvent(messageEvent("C1", fmt.Sprintf("msg %d", i)))
+		if i == 0 {
+			<-started
+		}
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case text := <-fast:
+			assert.Equal(t, fmt.Sprintf("msg %d", i), text)
+		case <-time.After(time.Second):
+			t.Fatal("fast listener blocked by the slow one")
+		}
+	}
+
+	// One running, one queued, one dropped
+	stats := bot.ListenerStats()
+	assert.Equal(t, "slow", stats[1].Name)
+	assert.Equal(t, int64(2), stats[1].Queued)
+	assert.Equal(t, uint64(1), stats[1].Dropped)
+
+	close(unblock)
+	waitListeners(t, bot)
+	assert.Equal(t, uint64(2), bot.ListenerStats()[1].Handled)
+}
+
+func TestListenerMatchesAreIndependent(t *testing.T) {
+	bot := New("")
+	bot.Users.Set(slack.User{ID: "U1", Name: "bob"})
+	bot.Channels.Set(Channel{ID: "C1", Name: "general", IsChannel: true})
+
+	var lock sync.Mutex
+	matches := make(map[string][]string)
+	for _, word := range []string{"hello", "world"} {
+		word := word
+		bot.listeners = append(bot.listeners, &Listener{
+			Name:    word,
+			Workers: 4,
+			Matches: regexp.MustCompile(word + `(\d)`),
+			MessageHandlerFunc: func(listen *Listener, msg *Message) {
+				time.Sleep(time.Millisecond)
+				lock.Lock()
+				defer lock.Unlock()
+				matches[listen.Name] = append(matches[listen.Name], msg.Channel+":"+msg.Match[1])
+			},
+		})
+	}
+
+	for i := 0; i < 5; i++ {
+		bot.handleRTMEvent(messageEvent("C1", fmt.Sprintf("hello%d world%d", i, i)))
+	}
+	waitListeners(t, bot)
+
+	// Each listener sees its own match, in order within a channel
+	assert.Equal(t, []string{"C1:0", "C1:1", "C1:2", "C1:3", "C1:4"}, matches["hello"])
+	assert.Equal(t, []string{"C1:0", "C1:1", "C1:2", "C1:3", "C1:4"}, matches["world"])
+}
+
+func TestClosedListenerDropsQueuedEvents(t *testing.T) {
+	bot := New("")
+	bot.Users.Set(slack.User{ID: "U1", Name: "bob"})
+	bot.Channels.Set(Channel{ID: "C1", Name: "general", IsChannel: true})
+
+	started := make(chan bool, 1)
+	unblock := make(chan bool)
+	var lock sync.Mutex
+	var handled []string
+	listen := &Listener{
+		Name: "once",
+		Bot:  bot,
+		MessageHandlerFunc: func(listen *Listener, msg *Message) {
+			lock.Lock()
+			handled = append(handled, msg.Text)
+			lock.Unlock()
+			started <- true
+			<-unblock
+			listen.Close()
+		},
+	}
+	listen.setupChannels()
+	bot.listeners = []*Listener{listen}
+
+	// The first event is being handled while the others are queued
+	bot.handleRTMEvent(messageEvent("C1", "first"))
+	<-started
+	bot.handleRTMEvent(messageEvent("C1", "second"))
+	bot.handleRTMEvent(messageEvent("C1", "third"))
+
+	close(unblock)
+	waitListeners(t, bot)
+
+	assert.Equal(t, []string{"first"}, handled, "a closed listener doesn't handle its queued events")
+}
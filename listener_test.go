@@ -87,3 +87,20 @@ func TestMatchesMessage(t *testing.T) {
 		t.Error("didn't find 'this'")
 	}
 }
+
+func TestInThreadFilter(t *testing.T) {
+	c := &Listener{InThread: "1234.5678"}
+	topLevel := &Message{Msg: &slack.Msg{Text: "hello", Timestamp: "1234.9999"}}
+	inThread := &Message{Msg: &slack.Msg{Text: "hello", Timestamp: "1234.9999", ThreadTimestamp: "1234.5678"}}
+	inOtherThread := &Message{Msg: &slack.Msg{Text: "hello", Timestamp: "1234.9999", ThreadTimestamp: "1111.1111"}}
+
+	if c.filterMessage(topLevel) {
+		t.Error("filterMessage should drop top level messages")
+	}
+	if !c.filterMessage(inThread) {
+		t.Error("filterMessage should keep messages in the thread")
+	}
+	if c.filterMessage(inOtherThread) {
+		t.Error("filterMessage should drop messages in other threads")
+	}
+}
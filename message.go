@@ -1,6 +1,7 @@
 package slick
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
@@ -18,6 +19,7 @@ type Message struct {
 	*slack.Msg
 	SubMessage  *slack.Msg
 	bot         *Bot
+	team        *Team
 	MentionsMe  bool
 	IsEdit      bool
 	FromMe      bool
@@ -28,6 +30,53 @@ type Message struct {
 	// Listener.Matches.FindStringSubmatch(msg.Text), when `Matches`
 	// is set on the `Listener`.
 	Match []string
+
+	ctx context.Context
+}
+
+// Context returns the context of the Message, carrying its request ID
+// (see `RequestID`). It is cancelled when the Listener handling it is
+// closed, or when the bot shuts down, so long operations should pass
+// it along.
+func (msg *Message) Context() context.Context {
+	if msg.ctx == nil {
+		return context.Background()
+	}
+	return msg.ctx
+}
+
+// Workspace returns the Team the message comes from. The embedded
+// `slack.Msg` already has a Team field, holding its ID.
+func (msg *Message) Workspace() *Team {
+	if msg.team == nil {
+		return msg.bot.Team
+	}
+	return msg.team
+}
+
+// resolve fills FromUser and FromChannel when the event loop didn't know
+// them yet, fetching them from the Web API. It runs on the Listener's
+// worker, before the handler.
+func (msg *Message) resolve() {
+	if msg.team == nil && msg.bot == nil {
+		return
+	}
+	team := msg.Workspace()
+
+	userID := msg.User
+	if msg.IsEdit && msg.SubMessage != nil {
+		userID = msg.SubMessage.User
+	}
+	if msg.FromUser == nil && userID != "" {
+		if user, ok := team.Users.Get(userID); ok {
+			msg.FromUser = &user
+		}
+	}
+	if msg.FromChannel == nil && msg.Channel != "" {
+		if channel, ok := team.Channels.Get(msg.Channel); ok {
+			msg.FromChannel = &channel
+		}
+	}
 }
 
 // IsPrivate determines if a message is private or not
@@ -92,35 +141,60 @@ func (msg *Message) HasPrefix(prefix string) bool {
 
 // AddReaction adds a reaction to a message
 func (msg *Message) AddReaction(emoticon string) *Message {
-	msg.bot.Slack.AddReaction(emoticon, slack.NewRefToMessage(msg.Channel, msg.Timestamp))
+	msg.Workspace().Transport.AddReaction(msg.Context(), emoticon, slack.NewRefToMessage(msg.Channel, msg.Timestamp))
 	return msg
 }
 
 // RemoveReaction removes a reaction from a message
 func (msg *Message) RemoveReaction(emoticon string) *Message {
-	msg.bot.Slack.RemoveReaction(emoticon, slack.NewRefToMessage(msg.Channel, msg.Timestamp))
+	msg.Workspace().Transport.RemoveReaction(msg.Context(), emoticon, slack.NewRefToMessage(msg.Channel, msg.Timestamp))
 	return msg
 }
 
 // ListenReaction listens for a reaction on a message
 func (msg *Message) ListenReaction(reactListen *ReactionListener) {
-	msg.bot.ListenReaction(msg.Timestamp, reactListen)
+	msg.Workspace().ListenReaction(msg.Timestamp, reactListen)
 }
 
 // Reply sends a message back to the source it came from, without a mention
 func (msg *Message) Reply(text string, v ...interface{}) *Reply {
-	to := msg.User
+	text = Format(text, v...)
+	return msg.Workspace().SendOutgoingMessage(text, msg.replyTo())
+}
+
+// ReplyInThread replies in the thread of the message, starting a new
+// thread under it if it was posted at the channel's top level.
+func (msg *Message) ReplyInThread(text string, v ...interface{}) *Reply {
+	text = Format(text, v...)
+	return msg.Workspace().SendOutgoingMessage(text, msg.replyTo(), InThread(msg.ThreadRoot()))
+}
+
+// ThreadRoot returns the timestamp of the message starting the thread
+// this message is in, or its own timestamp when it's not in a thread.
+func (msg *Message) ThreadRoot() string {
+	if msg.ThreadTimestamp != "" {
+		return msg.ThreadTimestamp
+	}
+	return msg.Timestamp
+}
+
+// IsInThread tells whether the message was posted as a reply in a
+// thread.
+func (msg *Message) IsInThread() bool {
+	return msg.ThreadTimestamp != "" && msg.ThreadTimestamp != msg.Timestamp
+}
+
+func (msg *Message) replyTo() string {
 	if msg.Channel != "" {
-		to = msg.Channel
+		return msg.Channel
 	}
-	text = Format(text, v...)
-	return msg.bot.SendOutgoingMessage(text, to)
+	return msg.User
 }
 
 // ReplyPrivately replies to the user in an IM
 func (msg *Message) ReplyPrivately(text string, v ...interface{}) *Reply {
 	text = Format(text, v...)
-	return msg.bot.SendPrivateMessage(msg.User, text)
+	return msg.Workspace().SendPrivateMessage(msg.User, text)
 }
 
 // ReplyMention replies with a @mention named prefixed, when replying
@@ -131,7 +205,7 @@ func (msg *Message) ReplyMention(text string, v ...interface{}) *Reply {
 	}
 	prefix := ""
 	if msg.FromUser != nil {
-		prefix = fmt.Sprintf("<@%s> ", msg.FromUser.Name)
+		prefix = MentionUser(msg.FromUser.ID) + " "
 	}
 	return msg.Reply(fmt.Sprintf("%s%s", prefix, text), v...)
 }
@@ -141,19 +215,19 @@ func (msg *Message) String() string {
 	return fmt.Sprintf("%#v", msg)
 }
 
-func (msg *Message) applyMentionsMe(bot *Bot) {
+func (msg *Message) applyMentionsMe(team *Team) {
 	if msg.IsPrivate() {
 		msg.MentionsMe = true
 	}
 
 	m := reAtMention.FindStringSubmatch(msg.Text)
-	if m != nil && m[1] == bot.Myself.ID {
+	if m != nil && m[1] == team.Myself.ID {
 		msg.MentionsMe = true
 	}
 }
 
-func (msg *Message) applyFromMe(bot *Bot) {
-	if msg.User != "" && msg.User == bot.Myself.ID {
+func (msg *Message) applyFromMe(team *Team) {
+	if msg.User != "" && msg.User == team.Myself.ID {
 		msg.FromMe = true
 	}
 }
@@ -2,6 +2,7 @@ package slick
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -17,7 +18,7 @@ var publicSlackMessage = slack.Msg{
 	Text:      "This is a test.",
 	User:      "U2147483698",
 	Channel:   "C2147483705",
-	Timestamp: string(now.Unix()),
+	Timestamp: strconv.FormatInt(now.Unix(), 10),
 }
 var publicMessage = Message{
 	Msg:        &publicSlackMessage,
@@ -30,7 +31,7 @@ var publicSlackMessageWithMention = slack.Msg{
 	Text:      "This is a test. <@U2147483697>",
 	User:      "U2147483697",
 	Channel:   "C2147483705",
-	Timestamp: string(now.Unix()),
+	Timestamp: strconv.FormatInt(now.Unix(), 10),
 }
 var publicMessageWithMention = Message{
 	Msg:        &publicSlackMessageWithMention,
@@ -43,7 +44,7 @@ var privateSlackMessage = slack.Msg{
 	Text:      "This is a test.",
 	User:      "U2147483697",
 	Channel:   "D2147483705",
-	Timestamp: string(now.Unix()),
+	Timestamp: strconv.FormatInt(now.Unix(), 10),
 }
 var privateMessage = Message{
 	Msg:        &privateSlackMessage,
@@ -53,7 +54,7 @@ var privateMessage = Message{
 	FromMe:     false,
 }
 
-var mockedBot = &Bot{
+var mockedTeam = &Team{
 	Myself: slack.UserDetails{
 		ID: "U2147483697",
 	},
@@ -98,36 +99,36 @@ func TestShouldConvertAMessageToString(t *testing.T) {
 
 func TestShouldNotApplyAMentionToMe(t *testing.T) {
 	assert.False(t, publicMessage.MentionsMe)
-	publicMessage.applyMentionsMe(mockedBot)
+	publicMessage.applyMentionsMe(mockedTeam)
 	assert.False(t, publicMessage.MentionsMe)
 }
 
 func TestShouldApplyAMentionToMeDirectMessage(t *testing.T) {
 	assert.False(t, privateMessage.MentionsMe)
-	privateMessage.applyMentionsMe(mockedBot)
+	privateMessage.applyMentionsMe(mockedTeam)
 	assert.True(t, privateMessage.MentionsMe)
 }
 
 func TestShouldApplyAMentionToMePublicMessageWithMention(t *testing.T) {
 	assert.False(t, publicMessageWithMention.MentionsMe)
-	publicMessageWithMention.applyMentionsMe(mockedBot)
+	publicMessageWithMention.applyMentionsMe(mockedTeam)
 	assert.True(t, publicMessageWithMention.MentionsMe)
 }
 
 func TestShouldNotApplyFromMe(t *testing.T) {
 	assert.False(t, publicMessage.FromMe)
-	publicMessage.applyFromMe(mockedBot)
+	publicMessage.applyFromMe(mockedTeam)
 	assert.False(t, publicMessage.FromMe)
 }
 
 func TestShouldApplyFromMeDirectMessage(t *testing.T) {
 	assert.False(t, privateMessage.FromMe)
-	privateMessage.applyFromMe(mockedBot)
+	privateMessage.applyFromMe(mockedTeam)
 	assert.True(t, privateMessage.FromMe)
 }
 
 func TestShouldReturnTheSameStringUnformatted(t *testing.T) {
-	s := "text"
+	const s = "text"
 	fs := Format(s)
 	assert.Equal(t, s, fs)
 }
@@ -145,3 +146,13 @@ func TestShouldReturnTheStringConcatenatedWithAnotherContentType(t *testing.T) {
 	fs := Format(s1, i)
 	assert.Equal(t, fs, fmt.Sprintf(s1, i))
 }
+
+func TestMessageThreadRoot(t *testing.T) {
+	topLevel := Message{Msg: &slack.Msg{Timestamp: "1.1"}}
+	assert.Equal(t, "1.1", topLevel.ThreadRoot())
+	assert.False(t, topLevel.IsInThread())
+
+	threadReply := Message{Msg: &slack.Msg{Timestamp: "1.2", ThreadTimestamp: "1.1"}}
+	assert.Equal(t, "1.1", threadReply.ThreadRoot())
+	assert.True(t, threadReply.IsInThread())
+}
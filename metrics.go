@@ -0,0 +1,346 @@
+package slick
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metrics is a registry of counters, gauges and histograms, exported
+// in the Prometheus text format on the WebServer's `/metrics` route.
+// Plugins publish their own metrics through `Bot.Metrics`, prefixing
+// their names with the plugin name, like "vote_votes_total".
+type Metrics struct {
+	lock    sync.Mutex
+	metrics []metric
+	byName  map[string]metric
+}
+
+// metric is a family of samples sharing a name, help and type.
+type metric interface {
+	describe() (name, help, typ string)
+	collect() []sample
+}
+
+type sample struct {
+	suffix string
+	labels []string // alternating names and values
+	value  float64
+}
+
+// NewMetrics returns an empty registry.
+func NewMetrics() *Metrics {
+	return &Metrics{byName: make(map[string]metric)}
+}
+
+func (m *Metrics) register(name string, newMetric func() metric) metric {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if existing, ok := m.byName[name]; ok {
+		return existing
+	}
+
+	created := newMetric()
+	m.metrics = append(m.metrics, created)
+	m.byName[name] = created
+	return created
+}
+
+// Counter returns the counter `name`, registering it on first call.
+// Counters only go up.
+func (m *Metrics) Counter(name, help string, labelNames ...string) *CounterVec {
+	return m.register(name, func() metric {
+		counter := &CounterVec{}
+		counter.init(name, help, labelNames)
+		return counter
+	}).(*CounterVec)
+}
+
+// Gauge returns the gauge `name`, registering it on first call.
+func (m *Metrics) Gauge(name, help string, labelNames ...string) *GaugeVec {
+	return m.register(name, func() metric {
+		gauge := &GaugeVec{}
+		gauge.init(name, help, labelNames)
+		return gauge
+	}).(*GaugeVec)
+}
+
+// GaugeFunc registers a gauge whose value is computed by `f` at each
+// scrape.
+func (m *Metrics) GaugeFunc(name, help string, f func() float64) {
+	m.register(name, func() metric {
+		return &metricFunc{name: name, help: help, typ: "gauge", f: func() []sample {
+			return []sample{{value: f()}}
+		}}
+	})
+}
+
+// DefaultBuckets are the histogram buckets, in seconds, of latency
+// histograms.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Histogram returns the histogram `name`, registering it on first
+// call. `buckets` are the upper bounds of the buckets, DefaultBuckets
+// if nil.
+func (m *Metrics) Histogram(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	return m.register(name, func() metric {
+		histogram := &HistogramVec{buckets: buckets}
+		histogram.init(name, help, labelNames)
+		return histogram
+	}).(*HistogramVec)
+}
+
+// collectFunc registers a metric whose samples are computed by `f` at
+// each scrape, for label sets which come and go.
+func (m *Metrics) collectFunc(name, help, typ string, f func() []sample) {
+	m.register(name, func() metric {
+		return &metricFunc{name: name, help: help, typ: typ, f: f}
+	})
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.lock.Lock()
+	metrics := append([]metric(nil), m.metrics...)
+	m.lock.Unlock()
+
+	var written int64
+	for _, met := range metrics {
+		name, help, typ := met.describe()
+		n, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, typ)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+
+		for _, s := range met.collect() {
+			n, err := fmt.Fprintf(w, "%s%s%s %s\n", name, s.suffix, formatLabels(s.labels), formatValue(s.value))
+			written += int64(n)
+			if err != nil {
+				return written, err
+			}
+		}
+	}
+
+	return written, nil
+}
+
+// metricVec holds the values of a metric, by label values.
+type metricVec struct {
+	name       string
+	help       string
+	labelNames []string
+
+	lock   sync.Mutex
+	values map[string]*metricValue
+}
+
+type metricValue struct {
+	labelValues []string
+	value       float64
+	// For histograms
+	counts []uint64
+	count  uint64
+}
+
+func (v *metricVec) init(name, help string, labelNames []string) {
+	v.name = name
+	v.help = help
+	v.labelNames = labelNames
+	v.values = make(map[string]*metricValue)
+}
+
+// get returns the value for the label values, with the lock held.
+func (v *metricVec) get(labelValues []string) *metricValue {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("slick: metric %q expects labels %v, got %v", v.name, v.labelNames, labelValues))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	value, ok := v.values[key]
+	if !ok {
+		value = &metricValue{labelValues: append([]string(nil), labelValues...)}
+		v.values[key] = value
+	}
+	return value
+}
+
+func (v *metricVec) labels(value *metricValue, extra ...string) []string {
+	var labels []string
+	for i, name := range v.labelNames {
+		labels = append(labels, name, value.labelValues[i])
+	}
+	return append(labels, extra...)
+}
+
+// sorted returns the values by label values, with the lock held.
+func (v *metricVec) sorted() []*metricValue {
+	var keys []string
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var values []*metricValue
+	for _, key := range keys {
+		values = append(values, v.values[key])
+	}
+	return values
+}
+
+func (v *metricVec) collectValues() []sample {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	var samples []sample
+	for _, value := range v.sorted() {
+		samples = append(samples, sample{labels: v.labels(value), value: value.value})
+	}
+	return samples
+}
+
+// CounterVec is a counter, partitioned by label values.
+type CounterVec struct {
+	metricVec
+}
+
+func (c *CounterVec) describe() (string, string, string) { return c.name, c.help, "counter" }
+func (c *CounterVec) collect() []sample                  { return c.collectValues() }
+
+// Inc adds one to the counter with the given label values.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds `delta`, which must not be negative, to the counter with
+// the given label values.
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic(fmt.Sprintf("slick: counter %q can't decrease", c.name))
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.get(labelValues).value += delta
+}
+
+// GaugeVec is a gauge, partitioned by label values.
+type GaugeVec struct {
+	metricVec
+}
+
+func (g *GaugeVec) describe() (string, string, string) { return g.name, g.help, "gauge" }
+func (g *GaugeVec) collect() []sample                  { return g.collectValues() }
+
+// Set sets the gauge with the given label values.
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.get(labelValues).value = value
+}
+
+// Add adds `delta` to the gauge with the given label values.
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.get(labelValues).value += delta
+}
+
+// HistogramVec is a histogram, partitioned by label values.
+type HistogramVec struct {
+	metricVec
+	buckets []float64
+}
+
+func (h *HistogramVec) describe() (string, string, string) { return h.name, h.help, "histogram" }
+
+// Observe adds an observation to the histogram with the given label
+// values.
+func (h *HistogramVec) Observe(observed float64, labelValues ...string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	value := h.get(labelValues)
+	if value.counts == nil {
+		value.counts = make([]uint64, len(h.buckets))
+	}
+	for i, bound := range h.buckets {
+		if observed <= bound {
+			value.counts[i]++
+		}
+	}
+	value.count++
+	value.value += observed
+}
+
+func (h *HistogramVec) collect() []sample {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	var samples []sample
+	for _, value := range h.sorted() {
+		for i, bound := range h.buckets {
+			samples = append(samples, sample{
+				suffix: "_bucket",
+				labels: h.labels(value, "le", formatValue(bound)),
+				value:  float64(value.counts[i]),
+			})
+		}
+		samples = append(samples,
+			sample{suffix: "_bucket", labels: h.labels(value, "le", "+Inf"), value: float64(value.count)},
+			sample{suffix: "_sum", labels: h.labels(value), value: value.value},
+			sample{suffix: "_count", labels: h.labels(value), value: float64(value.count)},
+		)
+	}
+	return samples
+}
+
+type metricFunc struct {
+	name, help, typ string
+	f               func() []sample
+}
+
+func (m *metricFunc) describe() (string, string, string) { return m.name, m.help, m.typ }
+func (m *metricFunc) collect() []sample                  { return m.f() }
+
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	var parts []string
+	for i := 0; i+1 < len(labels); i += 2 {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, labels[i], labelEscaper.Replace(labels[i+1])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
@@ -0,0 +1,98 @@
+package slick
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsTextFormat(t *testing.T) {
+	m := NewMetrics()
+
+	events := m.Counter("events_total", "Events.", "type")
+	events.Inc("message")
+	events.Add(2, "message")
+	events.Inc(`re"action`)
+
+	m.Gauge("latency_seconds", "Latency.").Set(0.25)
+	m.GaugeFunc("depth", "Depth.", func() float64 { return 3 })
+
+	handler := m.Histogram("handler_seconds", "Handlers.", []float64{0.1, 1}, "listener")
+	handler.Observe(0.05, "echo")
+	handler.Observe(0.5, "echo")
+
+	var out bytes.Buffer
+	m.WriteTo(&out)
+
+	assert.Equal(t, `# HELP events_total Events.
+# TYPE events_total counter
+events_total{type="message"} 3
+events_total{type="re\"action"} 1
+# HELP latency_seconds Latency.
+# TYPE latency_seconds gauge
+latency_seconds 0.25
+# HELP depth Depth.
+# TYPE depth gauge
+depth 3
+# HELP handler_seconds Handlers.
+# TYPE handler_seconds histogram
+handler_seconds_bucket{listener="echo",le="0.1"} 1
+handler_seconds_bucket{listener="echo",le="1"} 2
+handler_seconds_bucket{listener="echo",le="+Inf"} 2
+handler_seconds_sum{listener="echo"} 0.55
+handler_seconds_count{listener="echo"} 2
+`, out.String())
+}
+
+func TestMetricsRegisterOnce(t *testing.T) {
+	m := NewMetrics()
+
+	assert.True(t, m.Counter("votes_total", "Votes.") == m.Counter("votes_total", "Votes."))
+	assert.Panics(t, func() { m.Counter("votes_total", "Votes.").Inc("extra") })
+	assert.Panics(t, func() { m.Counter("votes_total", "Votes.").Add(-1) })
+}
+
+func TestBotMetrics(t *testing.T) {
+	bot := New("")
+	bot.Users.Set(slack.User{ID: "U1", Name: "bob"})
+	bot.Channels.Set(Channel{ID: "C1", Name: "general", IsChannel: true})
+	bot.listeners = []*Listener{{
+		Name:               "echo",
+		MessageHandlerFunc: func(*Listener, *Message) {},
+	}}
+	bot.handleRTMEvent(messageEvent("C1", "hello"))
+	waitListeners(t, bot)
+
+	bot.countAPIError("chat.postMessage", "channel_not_found")
+	bot.handleRTMEvent(&slack.RTMEvent{Type: "ack_error", Data: &slack.AckErrorEvent{
+		ErrorObj: &SendError{ReplyTo: 1, Err: errors.New("is_archived"), Method: "chat.postMessage"},
+	}})
+
+	acme := newTeam(bot, "acme")
+	bot.teams = append(bot.teams, acme)
+	acme.outgoing.push(&Reply{OutgoingMessage: &slack.OutgoingMessage{Channel: "C9"}})
+
+	rec := httptest.NewRecorder()
+	bot.Metrics.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rec.Body.String()
+	assert.Contains(t, body, `slick_slack_api_errors_total{method="chat.postMessage",error="channel_not_found"} 1`)
+	assert.Contains(t, body, `slick_listener_handler_seconds_count{plugin="github.com/CapstoneLabs/slick",listener="echo"} 1`)
+	assert.Contains(t, body, "slick_listeners 1\n")
+	assert.Contains(t, body, `slick_slack_api_errors_total{method="chat.postMessage",error="is_archived"} 1`)
+	assert.Contains(t, body, "slick_outgoing_queue_depth 1\n", "all the teams are counted")
+}
+
+func TestAPIErrorCode(t *testing.T) {
+	assert.Equal(t, "channel_not_found", apiErrorCode(errors.New("channel_not_found")))
+	assert.Equal(t, "ratelimited", apiErrorCode(&slack.RateLimitedError{RetryAfter: time.Second}))
+	assert.Equal(t, "timeout", apiErrorCode(fmt.Errorf("users.info: %w", context.DeadlineExceeded)))
+	assert.Equal(t, "request_failed", apiErrorCode(errors.New("dial tcp: connection refused")))
+}
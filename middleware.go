@@ -0,0 +1,80 @@
+package slick
+
+import (
+	"context"
+	"time"
+)
+
+// Dispatch is the delivery of an event to a Listener, passed through
+// the middlewares registered with `Bot.Use`.
+type Dispatch struct {
+	Listener *Listener
+	// Message is the incoming message, or nil for other events.
+	Message *Message
+	// Event is what the Listener's `EventHandlerFunc` receives: the
+	// Message for messages, the original event otherwise.
+	Event interface{}
+	// Context is the context of the Message, see `Message.Context`.
+	Context context.Context
+}
+
+// Handler handles a Dispatch.
+type Handler func(*Dispatch)
+
+// Middleware wraps the dispatch of events to Listeners. It calls
+// `next` to continue the dispatch, or returns without calling it to
+// drop the event for this Listener.
+type Middleware func(next Handler) Handler
+
+// Use adds middlewares around the dispatch of messages and events to
+// the Listeners. Messages only go through the middlewares of the
+// Listeners whose filters they pass. The first middleware added is
+// the outermost. Call `Use` before the bot runs, like in `InitPlugin`.
+//
+// Example, to ignore a muted channel:
+//
+//	bot.Use(func(next slick.Handler) slick.Handler {
+//		return func(d *slick.Dispatch) {
+//			if d.Message != nil && d.Message.Channel == mutedChannelID {
+//				return
+//			}
+//			next(d)
+//		}
+//	})
+func (bot *Bot) Use(middlewares ...Middleware) {
+	bot.middlewares = append(bot.middlewares, middlewares...)
+
+	var handler Handler = callListener
+	for i := len(bot.middlewares) - 1; i >= 0; i-- {
+		handler = bot.middlewares[i](handler)
+	}
+	bot.dispatchChain = handler
+}
+
+func (bot *Bot) dispatch(d *Dispatch) {
+	defer bot.recoverListener(d)
+	defer bot.observeDispatch(d, time.Now())
+
+	if d.Message != nil {
+		d.Message.resolve()
+	}
+	if d.Message != nil && !bot.RequirePermission(d.Message, d.Listener.Permission) {
+		return
+	}
+
+	if bot.dispatchChain == nil {
+		callListener(d)
+		return
+	}
+	bot.dispatchChain(d)
+}
+
+// callListener is the innermost Handler, calling the Listener's
+// handler function.
+func callListener(d *Dispatch) {
+	if d.Listener.MessageHandlerFunc != nil {
+		d.Listener.MessageHandlerFunc(d.Listener, d.Message)
+		return
+	}
+	d.Listener.EventHandlerFunc(d.Listener, d.Event)
+}
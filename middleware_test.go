@@ -0,0 +1,93 @@
+package slick
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	bot := New("")
+	bot.Users.Set(slack.User{ID: "U1", Name: "bob"})
+	bot.Channels.Set(Channel{ID: "C1", Name: "general", IsChannel: true})
+	bot.Channels.Set(Channel{ID: "C2", Name: "muted", IsChannel: true})
+
+	// Calls, by listener
+	var lock sync.Mutex
+	calls := make(map[string][]string)
+	record := func(listen *Listener, call string) {
+		lock.Lock()
+		defer lock.Unlock()
+		calls[listen.Name] = append(calls[listen.Name], call)
+	}
+
+	bot.Use(func(next Handler) Handler {
+		return func(d *Dispatch) {
+			record(d.Listener, "outer")
+			next(d)
+			record(d.Listener, "outer done")
+		}
+	}, func(next Handler) Handler {
+		return func(d *Dispatch) {
+			if d.Message != nil && d.Message.Channel == "C2" {
+				record(d.Listener, "muted")
+				return
+			}
+			next(d)
+		}
+	})
+
+	bot.listeners = []*Listener{
+		{
+			Name:     "messages",
+			Contains: "ping",
+			MessageHandlerFunc: func(listen *Listener, msg *Message) {
+				record(listen, "message "+msg.Text)
+			},
+		},
+		{
+			Name: "events",
+			EventHandlerFunc: func(listen *Listener, event interface{}) {
+				if _, ok := event.(*Message); ok {
+					record(listen, "event message")
+				} else {
+					record(listen, "event other")
+				}
+			},
+		},
+	}
+
+	handle := func(ev *slack.RTMEvent) map[string][]string {
+		calls = make(map[string][]string)
+		bot.handleRTMEvent(ev)
+		waitListeners(t, bot)
+		return calls
+	}
+
+	assert.Equal(t, map[string][]string{
+		"messages": {"outer", "message ping", "outer done"},
+		"events":   {"outer", "event message", "outer done"},
+	}, handle(&slack.RTMEvent{Type: "message", Data: &slack.MessageEvent{
+		Msg: slack.Msg{Type: "message", Channel: "C1", User: "U1", Text: "ping"},
+	}}))
+
+	// Filtered out messages don't go through the middlewares
+	assert.Equal(t, map[string][]string{
+		"events": {"outer", "event message", "outer done"},
+	}, handle(&slack.RTMEvent{Type: "message", Data: &slack.MessageEvent{
+		Msg: slack.Msg{Type: "message", Channel: "C1", User: "U1", Text: "hello"},
+	}}))
+
+	assert.Equal(t, map[string][]string{
+		"messages": {"outer", "muted", "outer done"},
+		"events":   {"outer", "muted", "outer done"},
+	}, handle(&slack.RTMEvent{Type: "message", Data: &slack.MessageEvent{
+		Msg: slack.Msg{Type: "message", Channel: "C2", User: "U1", Text: "ping"},
+	}}))
+
+	assert.Equal(t, map[string][]string{
+		"events": {"outer", "event other", "outer done"},
+	}, handle(&slack.RTMEvent{Type: "hello", Data: &slack.HelloEvent{}}))
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/CapstoneLabs/slick"
+	log "github.com/sirupsen/logrus"
 )
 
 type Mooder struct {
@@ -17,32 +18,27 @@ func init() {
 
 func (mooder *Mooder) InitPlugin(bot *slick.Bot) {
 	mooder.bot = bot
-	go mooder.SetupMoodChanger()
+
+	mooder.ChangeMood()
+	_, err := bot.Scheduler.Cron("mooder", "0 12 * * mon-fri", mooder.ChangeMood)
+	if err != nil {
+		log.WithError(err).Error("mooder: couldn't schedule the mood changer")
+	}
 }
 
-func (mooder *Mooder) SetupMoodChanger() {
+// ChangeMood picks the mood of the day.
+func (mooder *Mooder) ChangeMood() {
 	bot := mooder.bot
-	for {
-		time.Sleep(10 * time.Second)
-		newMood := slick.Happy
-
-		rand.Seed(time.Now().UTC().UnixNano())
+	newMood := slick.Happy
 
-		happyChances := rand.Int() % 10
-		if happyChances > 6 {
-			newMood = slick.Hyper
-		}
+	rand.Seed(time.Now().UTC().UnixNano())
 
-		bot.Mood = newMood
+	happyChances := rand.Int() % 10
+	if happyChances > 6 {
+		newMood = slick.Hyper
+	}
 
-		//bot.SendToChannel(bot.Config.GeneralChannel, bot.WithMood("I'm quite happy today.", "I can haz!! It's going to be a great one today!!"))
+	bot.Mood = newMood
 
-		select {
-		case <-slick.AfterNextWeekdayTime(time.Now(), time.Monday, 12, 0):
-		case <-slick.AfterNextWeekdayTime(time.Now(), time.Tuesday, 12, 0):
-		case <-slick.AfterNextWeekdayTime(time.Now(), time.Wednesday, 12, 0):
-		case <-slick.AfterNextWeekdayTime(time.Now(), time.Thursday, 12, 0):
-		case <-slick.AfterNextWeekdayTime(time.Now(), time.Friday, 12, 0):
-		}
-	}
+	//bot.SendToChannel(bot.Config().GeneralChannel, bot.WithMood("I'm quite happy today.", "I can haz!! It's going to be a great one today!!"))
 }
@@ -0,0 +1,496 @@
+package slick
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// Slack allows about one message per second in a channel, with short
+// bursts, and a few hundred per minute for the whole workspace. The
+// rates can be tuned with the `message_rate` and
+// `channel_message_rate` configs, in messages per second.
+const (
+	DefaultMessageRate         = 5.0
+	DefaultChannelMessageRate  = 1.0
+	defaultMessageBurst        = 20
+	defaultChannelMessageBurst = 5
+)
+
+// maxSendAttempts is how many times a rate limited message is sent
+// before giving up.
+const maxSendAttempts = 5
+
+// ackTimeout is how long a sent message waits for its acknowledgement.
+const ackTimeout = 20 * time.Second
+
+var errRateLimited = errors.New("rate_limited")
+
+// SendError is the error of a message which couldn't be sent.
+// Transports report it in a `*slack.AckErrorEvent`, so the bot knows
+// which Reply failed. Without it, as with the RTM, the error is matched
+// to the oldest message waiting for its acknowledgement.
+type SendError struct {
+	ReplyTo int
+	Err     error
+
+	// Method is the Web API method which failed, counted in the
+	// metrics. It is empty when the error is counted where it happens.
+	Method string
+}
+
+func (e *SendError) Error() string {
+	return fmt.Sprintf("sending message %d: %s", e.ReplyTo, e.Err)
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// tokenBucket allows `rate` messages per second, in bursts of up to
+// `burst` messages.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// wait returns how long until a token is available.
+func (b *tokenBucket) wait(now time.Time) time.Duration {
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		return 0
+	}
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	if wait < time.Millisecond {
+		wait = time.Millisecond
+	}
+	return wait
+}
+
+// setRate changes the rate of the bucket, after adding the tokens
+// earned at the previous rate.
+func (b *tokenBucket) setRate(rate float64, now time.Time) {
+	b.wait(now)
+	b.rate = rate
+}
+
+func (b *tokenBucket) take() {
+	b.tokens--
+}
+
+// outgoingChannel holds the Replies waiting to be sent to a channel.
+type outgoingChannel struct {
+	bucket *tokenBucket
+	queue  []*Reply
+}
+
+// outgoing paces the Replies sent by `replyHandler`, with a token
+// bucket for the workspace and one per channel, and keeps the sent
+// Replies until they're acknowledged, to retry or report failures.
+type outgoing struct {
+	team *Team
+	lock sync.Mutex
+
+	rate        float64
+	channelRate float64
+	global      *tokenBucket
+	channels    map[string]*outgoingChannel
+	// ready lists the channels with queued Replies, served in turns.
+	ready []string
+	// pausedUntil holds all sending after Slack rate limited the bot.
+	pausedUntil time.Time
+	// inFlight are the Replies sent and not yet acknowledged, in
+	// sending order.
+	inFlight []*Reply
+	// posting holds the channels with a Reply being posted through
+	// the Web API, and when it was sent. Their next Replies wait for
+	// it, so they keep their order.
+	posting map[string]time.Time
+
+	wake chan bool
+	now  func() time.Time
+}
+
+func newOutgoing(team *Team) *outgoing {
+	o := &outgoing{
+		team:     team,
+		channels: make(map[string]*outgoingChannel),
+		posting:  make(map[string]time.Time),
+		wake:     make(chan bool, 1),
+		now:      time.Now,
+	}
+	o.setRates(0, 0)
+	return o
+}
+
+// setRates sets the messages per second, using the defaults for zero
+// values. The queued Replies are kept, and the buckets keep their
+// tokens.
+func (o *outgoing) setRates(rate, channelRate float64) {
+	if rate <= 0 {
+		rate = DefaultMessageRate
+	}
+	if channelRate <= 0 {
+		channelRate = DefaultChannelMessageRate
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.global != nil && rate == o.rate && channelRate == o.channelRate {
+		return
+	}
+	o.rate = rate
+	o.channelRate = channelRate
+	if o.global == nil {
+		o.global = newTokenBucket(rate, defaultMessageBurst)
+		return
+	}
+
+	now := o.now()
+	o.global.setRate(rate, now)
+	for _, c := range o.channels {
+		c.bucket.setRate(channelRate, now)
+	}
+}
+
+// setRates sets the messages per second of every team.
+func (bot *Bot) setRates(rate, channelRate float64) {
+	for _, team := range bot.Teams() {
+		team.outgoing.setRates(rate, channelRate)
+	}
+}
+
+func (o *outgoing) channel(id string) *outgoingChannel {
+	c, ok := o.channels[id]
+	if !ok {
+		c = &outgoingChannel{bucket: newTokenBucket(o.channelRate, defaultChannelMessageBurst)}
+		o.channels[id] = c
+	}
+	return c
+}
+
+// push queues a Reply, after the others of its channel.
+func (o *outgoing) push(r *Reply) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	c := o.channel(r.Channel)
+	if len(c.queue) == 0 {
+		o.ready = append(o.ready, r.Channel)
+	}
+	c.queue = append(c.queue, r)
+}
+
+// retry queues a Reply again, before the others of its channel, and
+// holds all sending for `delay`.
+func (o *outgoing) retry(r *Reply, delay time.Duration) {
+	o.lock.Lock()
+	c := o.channel(r.Channel)
+	if len(c.queue) == 0 {
+		o.ready = append(o.ready, r.Channel)
+	}
+	c.queue = append([]*Reply{r}, c.queue...)
+	if r.options != nil {
+		delete(o.posting, r.Channel)
+	}
+	if until := o.now().Add(delay); until.After(o.pausedUntil) {
+		o.pausedUntil = until
+	}
+	o.lock.Unlock()
+
+	o.signal()
+}
+
+// posted lets the next Replies of the channel of `r` be sent, once
+// the post of `r` is acknowledged or failed.
+func (o *outgoing) posted(r *Reply) {
+	if r.options == nil {
+		return
+	}
+
+	o.lock.Lock()
+	delete(o.posting, r.Channel)
+	o.lock.Unlock()
+
+	o.signal()
+}
+
+// signal wakes `replyHandler` up, to look for Replies to send.
+func (o *outgoing) signal() {
+	select {
+	case o.wake <- true:
+	default:
+	}
+}
+
+// next returns the next Reply which can be sent, or how long to wait
+// before one can. It returns a zero wait when nothing is queued.
+func (o *outgoing) next() (*Reply, time.Duration) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if len(o.ready) == 0 {
+		return nil, 0
+	}
+
+	now := o.now()
+	if now.Before(o.pausedUntil) {
+		return nil, o.pausedUntil.Sub(now)
+	}
+
+	var minWait time.Duration
+	for i, id := range o.ready {
+		c := o.channels[id]
+		wait := c.bucket.wait(now)
+		if started, ok := o.posting[id]; ok {
+			// The post is given up on after ackTimeout, like in `sent`.
+			if posting := started.Add(ackTimeout).Sub(now); posting > wait {
+				wait = posting
+			}
+		}
+		if wait > 0 {
+			if minWait == 0 || wait < minWait {
+				minWait = wait
+			}
+			continue
+		}
+		if wait := o.global.wait(now); wait > 0 {
+			return nil, wait
+		}
+
+		c.bucket.take()
+		o.global.take()
+
+		r := c.queue[0]
+		c.queue = c.queue[1:]
+		if r.options != nil {
+			o.posting[id] = now
+		}
+		o.ready = append(o.ready[:i:i], o.ready[i+1:]...)
+		if len(c.queue) != 0 {
+			o.ready = append(o.ready, id)
+		}
+		return r, 0
+	}
+
+	return nil, minWait
+}
+
+// queued returns the number of Replies waiting to be sent.
+func (o *outgoing) queued() int {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	var queued int
+	for _, id := range o.ready {
+		queued += len(o.channels[id].queue)
+	}
+	return queued
+}
+
+// sent records a Reply as waiting for its acknowledgement, and forgets
+// those which waited for too long.
+func (o *outgoing) sent(r *Reply) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	now := o.now()
+	r.sentAt = now
+
+	var inFlight []*Reply
+	for _, waiting := range o.inFlight {
+		if now.Sub(waiting.sentAt) < ackTimeout {
+			inFlight = append(inFlight, waiting)
+		}
+	}
+	o.inFlight = append(inFlight, r)
+}
+
+// waitingAcks returns the number of Replies waiting for their
+// acknowledgement.
+func (o *outgoing) waitingAcks() int {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	var waiting int
+	now := o.now()
+	for _, r := range o.inFlight {
+		if now.Sub(r.sentAt) < ackTimeout {
+			waiting++
+		}
+	}
+	return waiting
+}
+
+// acked forgets the Reply with ID `id`.
+func (o *outgoing) acked(id int) *Reply {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	for i, r := range o.inFlight {
+		if r.ID == id {
+			o.inFlight = append(o.inFlight[:i:i], o.inFlight[i+1:]...)
+			return r
+		}
+	}
+	return nil
+}
+
+// oldest forgets and returns the oldest Reply sent through the
+// Transport waiting for its acknowledgement.
+func (o *outgoing) oldest() *Reply {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	for i, r := range o.inFlight {
+		if r.options == nil {
+			o.inFlight = append(o.inFlight[:i:i], o.inFlight[i+1:]...)
+			return r
+		}
+	}
+	return nil
+}
+
+// failed handles the error of a sent message: rate limited messages
+// are retried with a backoff, the others fail their Reply.
+func (o *outgoing) failed(err error) {
+	var reply *Reply
+	var sendErr *SendError
+	if errors.As(err, &sendErr) {
+		reply = o.acked(sendErr.ReplyTo)
+		err = sendErr.Err
+	} else {
+		reply = o.oldest()
+	}
+
+	if reply == nil {
+		log.WithFields(log.Fields{
+			"Type": "UnmatchedSendError",
+		}).WithError(err).Warn("Error for a message which isn't waiting for an acknowledgement.")
+		return
+	}
+
+	if delay, ok := retryDelay(err, reply.attempts); ok && reply.attempts < maxSendAttempts {
+		log.WithFields(log.Fields{
+			"Type":    "SendRateLimited",
+			"Channel": reply.Channel,
+			"Attempt": reply.attempts,
+			"Delay":   delay,
+		}).Warn("Rate limited, retrying message.")
+
+		o.retry(reply, delay)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"Type":     "SendMessageError",
+		"Channel":  reply.Channel,
+		"Attempts": reply.attempts,
+	}).WithError(err).Error("Error sending message.")
+
+	o.posted(reply)
+	o.team.auditReply(reply, "", err)
+	reply.fail(err)
+}
+
+// retryDelay returns how long to wait before sending a message again,
+// after its `attempt`th sending failed with `err`, and false if the
+// message shouldn't be sent again.
+func retryDelay(err error, attempt int) (time.Duration, bool) {
+	var rateLimited *slack.RateLimitedError
+	if errors.As(err, &rateLimited) {
+		return rateLimited.RetryAfter, true
+	}
+	// The RTM says "rate_limited", the Web API "ratelimited"
+	if !errors.Is(err, errRateLimited) && !strings.Contains(err.Error(), "rate_limited") && !strings.Contains(err.Error(), "ratelimited") {
+		return 0, false
+	}
+
+	delay := time.Second << uint(attempt-1)
+	if delay > 30*time.Second || delay <= 0 {
+		delay = 30 * time.Second
+	}
+	return delay, true
+}
+
+// replyHandler sends the outgoing messages, as fast as the rate limits
+// allow.
+func (team *Team) replyHandler() {
+	bot := team.bot
+	o := team.outgoing
+	for {
+		// Queue all the pending messages, so every channel gets its
+		// turn.
+	drain:
+		for {
+			select {
+			case r := <-team.outgoingMsgCh:
+				o.push(r)
+			default:
+				break drain
+			}
+		}
+
+		reply, wait := o.next()
+		if reply != nil {
+			team.sendReply(reply)
+			continue
+		}
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+
+		select {
+		case r := <-team.outgoingMsgCh:
+			o.push(r)
+		case <-o.wake:
+		case <-timeout:
+		case <-bot.stopCh:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// sendReply sends a Reply through the Transport, or posts it through
+// the Web API when it has ReplyOptions, without waiting for the
+// response.
+func (team *Team) sendReply(r *Reply) {
+	r.attempts++
+	team.outgoing.sent(r)
+
+	if r.options != nil {
+		go team.postReply(r)
+		return
+	}
+	team.Transport.SendMessage(r.OutgoingMessage)
+}
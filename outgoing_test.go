@@ -0,0 +1,226 @@
+package slick
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestOutgoing(rate, channelRate float64) (*Bot, *time.Time) {
+	bot := New("")
+	bot.Transport = newFakeTransport()
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	bot.outgoing.now = func() time.Time { return now }
+	bot.outgoing.setRates(rate, channelRate)
+	return bot, &now
+}
+
+func queueReply(bot *Bot, channel, text string) *Reply {
+	reply := &Reply{OutgoingMessage: bot.Transport.NewOutgoingMessage(text, channel), bot: bot, team: bot.Team}
+	bot.outgoing.push(reply)
+	return reply
+}
+
+func TestOutgoingChannelRateLimit(t *testing.T) {
+	bot, now := newTestOutgoing(1000, 1)
+	o := bot.outgoing
+
+	for i := 0; i < defaultChannelMessageBurst+1; i++ {
+		queueReply(bot, "C1", "general")
+	}
+	other := queueReply(bot, "C2", "random")
+
+	for i := 0; i < defaultChannelMessageBurst+1; i++ {
+		reply, _ := o.next()
+		assert.NotNil(t, reply, "bursts are allowed")
+		if i == 1 {
+			assert.Equal(t, other, reply, "channels are served in turns")
+		}
+	}
+
+	reply, wait := o.next()
+	assert.Nil(t, reply)
+	assert.Equal(t, time.Second, wait)
+
+	*now = now.Add(time.Second)
+	reply, _ = o.next()
+	assert.Equal(t, "general", reply.Text)
+
+	reply, wait = o.next()
+	assert.Nil(t, reply)
+	assert.Equal(t, time.Duration(0), wait, "nothing queued")
+}
+
+func TestOutgoingGlobalRateLimit(t *testing.T) {
+	bot, now := newTestOutgoing(2, 1000)
+	o := bot.outgoing
+
+	for i := 0; i < defaultMessageBurst+1; i++ {
+		queueReply(bot, fmt.Sprintf("C%d", i), "hello")
+	}
+	for i := 0; i < defaultMessageBurst; i++ {
+		reply, _ := o.next()
+		assert.NotNil(t, reply)
+	}
+
+	reply, wait := o.next()
+	assert.Nil(t, reply)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	*now = now.Add(wait)
+	reply, _ = o.next()
+	assert.NotNil(t, reply)
+}
+
+func TestOutgoingRetriesRateLimited(t *testing.T) {
+	bot, now := newTestOutgoing(0, 0)
+	o := bot.outgoing
+
+	first := queueReply(bot, "C1", "first")
+	second := queueReply(bot, "C1", "second")
+	reply, _ := o.next()
+	bot.sendReply(reply)
+	reply, _ = o.next()
+	bot.sendReply(reply)
+
+	// The RTM doesn't tell which message was rate limited: it's the
+	// oldest waiting for its ack.
+	o.failed(errRateLimited)
+
+	reply, wait := o.next()
+	assert.Nil(t, reply)
+	assert.Equal(t, time.Second, wait)
+
+	*now = now.Add(wait)
+	reply, _ = o.next()
+	assert.Equal(t, first, reply)
+	assert.Nil(t, o.acked(first.ID), "retried messages wait for their ack again once sent")
+	assert.Equal(t, second, o.acked(second.ID))
+
+	bot.sendReply(reply)
+	o.failed(&SendError{ReplyTo: first.ID, Err: &slack.RateLimitedError{RetryAfter: 3 * time.Second}})
+	_, wait = o.next()
+	assert.Equal(t, 3*time.Second, wait, "Retry-After is honored")
+}
+
+func TestOutgoingPostsInOrder(t *testing.T) {
+	bot, now := newTestOutgoing(1000, 1000)
+	o := bot.outgoing
+
+	post := func(channel, text string) *Reply {
+		reply := queueReply(bot, channel, text)
+		reply.options = &ReplyOptions{Text: text}
+		return reply
+	}
+	first := post("C1", "first")
+	second := post("C1", "second")
+	other := post("C2", "other")
+
+	reply, _ := o.next()
+	assert.Equal(t, first, reply)
+	reply, _ = o.next()
+	assert.Equal(t, other, reply, "the other channels don't wait")
+	reply, wait := o.next()
+	assert.Nil(t, reply, "the channel waits for its post")
+	assert.Equal(t, ackTimeout, wait)
+
+	o.posted(first)
+	reply, _ = o.next()
+	assert.Equal(t, second, reply)
+
+	third := post("C1", "third")
+	*now = now.Add(ackTimeout)
+	reply, _ = o.next()
+	assert.Equal(t, third, reply, "lost posts are given up on")
+}
+
+func TestOutgoingErrorCallsOnError(t *testing.T) {
+	bot, _ := newTestOutgoing(0, 0)
+	o := bot.outgoing
+
+	reply := queueReply(bot, "C1", "hello")
+	errs := make(chan error, 2)
+	reply.OnError(func(err error) { errs <- err })
+
+	sent, _ := o.next()
+	bot.sendReply(sent)
+	o.failed(&SendError{ReplyTo: reply.ID, Err: errors.New("channel_not_found")})
+
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "channel_not_found")
+	case <-time.After(time.Second):
+		t.Fatal("OnError not called")
+	}
+
+	event := <-bot.internalEvents
+	ack := event.Data.(*slack.AckMessage)
+	assert.Equal(t, reply.ID, ack.ReplyTo)
+	assert.False(t, ack.Ok, "OnAck listeners are closed with a failed ack")
+
+	reply.OnError(func(err error) { errs <- err })
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "channel_not_found", "late callbacks are called too")
+	case <-time.After(time.Second):
+		t.Fatal("late OnError not called")
+	}
+}
+
+func TestOutgoingGivesUpRetrying(t *testing.T) {
+	bot, now := newTestOutgoing(0, 0)
+	o := bot.outgoing
+
+	reply := queueReply(bot, "C1", "hello")
+	errs := make(chan error, 1)
+	reply.OnError(func(err error) { errs <- err })
+
+	for i := 0; i < maxSendAttempts; i++ {
+		sent, wait := o.next()
+		if sent == nil {
+			*now = now.Add(wait)
+			sent, _ = o.next()
+		}
+		bot.sendReply(sent)
+		o.failed(&SendError{ReplyTo: reply.ID, Err: errors.New("chat.postMessage: rate_limited")})
+	}
+
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "chat.postMessage: rate_limited")
+	case <-time.After(time.Second):
+		t.Fatal("OnError not called")
+	}
+	assert.Equal(t, 0, o.queued())
+}
+
+func TestOutgoingSetRatesKeepsQueues(t *testing.T) {
+	bot, now := newTestOutgoing(1000, 1)
+	o := bot.outgoing
+
+	for i := 0; i < defaultChannelMessageBurst+2; i++ {
+		queueReply(bot, "C1", "general")
+	}
+	for i := 0; i < defaultChannelMessageBurst; i++ {
+		o.next()
+	}
+	_, wait := o.next()
+	assert.Equal(t, time.Second, wait)
+
+	o.setRates(1000, 1)
+	assert.Equal(t, 2, o.queued(), "unchanged rates keep everything")
+
+	o.setRates(1000, 4)
+	assert.Equal(t, 2, o.queued(), "reloading the rates keeps the queued replies")
+	_, wait = o.next()
+	assert.Equal(t, 250*time.Millisecond, wait, "the new rate applies to the spent bucket")
+
+	*now = now.Add(250 * time.Millisecond)
+	reply, _ := o.next()
+	assert.NotNil(t, reply)
+}
@@ -0,0 +1,412 @@
+package slick
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// Role grants permissions to users. It is defined in the Roles section
+// of the config, by name:
+//
+//	"Roles": {
+//	  "admins": {"permissions": ["*"], "users": ["U024BE7LH"]},
+//	  "deployers": {
+//	    "permissions": ["deployer.*", "wicked.start"],
+//	    "user_groups": ["ops"],
+//	    "email_domains": ["example.com"]
+//	  }
+//	}
+//
+// Users holding the PermissionManageRoles permission can also grant
+// the roles to other users in chat, see `!grant-role`.
+type Role struct {
+	// Permissions are names like "deployer.deploy". "*" grants all
+	// of them, and "deployer.*" the ones starting with "deployer.".
+	Permissions []string
+
+	// Users are user IDs: not names, which users can change.
+	// UserGroups are Slack user group handles or IDs, and EmailDomains
+	// match the domain of the users' email.
+	Users        []string
+	UserGroups   []string `json:"user_groups" mapstructure:"user_groups"`
+	EmailDomains []string `json:"email_domains" mapstructure:"email_domains"`
+}
+
+// userIDFormat matches the IDs of Slack users.
+var userIDFormat = regexp.MustCompile(`^[UW][A-Z0-9]+$`)
+
+// PermissionManageRoles is needed to grant and revoke roles in chat.
+const PermissionManageRoles = "roles.manage"
+
+// userGroupsTTL is how long the members of the Slack user groups are
+// cached.
+const userGroupsTTL = 10 * time.Minute
+
+// permissions holds the roles of the config.
+type permissions struct {
+	lock  sync.RWMutex
+	roles map[string]Role
+}
+
+// userGroups caches the members of the user groups of a Team.
+type userGroups struct {
+	lock    sync.Mutex
+	members map[string]map[string]bool
+	fetched time.Time
+	// fetching is closed when the fetch in progress ends, nil when
+	// there is none.
+	fetching chan struct{}
+}
+
+// setRoles applies the Roles section of the config.
+func (bot *Bot) setRoles(roles map[string]Role) {
+	bot.permissions.lock.Lock()
+	defer bot.permissions.lock.Unlock()
+	bot.permissions.roles = roles
+}
+
+// checkRoles checks the Roles section of the config.
+func checkRoles(roles map[string]Role) []error {
+	var errs []error
+	names := make([]string, 0, len(roles))
+	for name := range roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if len(roles[name].Permissions) == 0 {
+			errs = append(errs, fmt.Errorf("roles.%s: no permissions", name))
+		}
+		for _, user := range roles[name].Users {
+			if !userIDFormat.MatchString(user) {
+				errs = append(errs, fmt.Errorf("roles.%s.users: %q is not a user ID", name, user))
+			}
+		}
+	}
+	return errs
+}
+
+// Can tells if `user` has `permission`. Permissions are only enforced
+// once the config defines Roles: until then, everyone can do
+// everything.
+func (bot *Bot) Can(user *slack.User, permission string) bool {
+	bot.permissions.lock.RLock()
+	roles := bot.permissions.roles
+	bot.permissions.lock.RUnlock()
+
+	if permission == "" || len(roles) == 0 {
+		return true
+	}
+	if user == nil {
+		return false
+	}
+
+	for _, name := range bot.UserRoles(user) {
+		for _, granted := range roles[name].Permissions {
+			if permissionMatches(granted, permission) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasRoles tells if the config defines roles, and permissions are
+// enforced.
+func (bot *Bot) hasRoles() bool {
+	bot.permissions.lock.RLock()
+	defer bot.permissions.lock.RUnlock()
+	return len(bot.permissions.roles) != 0
+}
+
+// inAdminChannel tells if the message was posted in the
+// `admin_channel`.
+func (bot *Bot) inAdminChannel(msg *Message) bool {
+	admin := bot.Config().AdminChannel
+	return admin != "" && msg.FromChannel != nil && msg.FromChannel.Name == admin
+}
+
+func permissionMatches(granted, permission string) bool {
+	if granted == "*" || granted == permission {
+		return true
+	}
+	return strings.HasSuffix(granted, ".*") && strings.HasPrefix(permission, strings.TrimSuffix(granted, "*"))
+}
+
+// UserRoles returns the names of the roles of `user`, from the config
+// and granted in chat, sorted.
+func (bot *Bot) UserRoles(user *slack.User) []string {
+	bot.permissions.lock.RLock()
+	roles := bot.permissions.roles
+	bot.permissions.lock.RUnlock()
+
+	team := bot.userTeam(user)
+	granted := team.grantedRoles(user.ID)
+
+	var names []string
+	for name, role := range roles {
+		if granted[name] || team.hasRole(user, role) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// userTeam returns the team of `user`, the primary one when unknown.
+func (bot *Bot) userTeam(user *slack.User) *Team {
+	if team := bot.TeamByID(user.TeamID); user.TeamID != "" && team != nil {
+		return team
+	}
+	return bot.Team
+}
+
+func (team *Team) hasRole(user *slack.User, role Role) bool {
+	for _, member := range role.Users {
+		if member == user.ID {
+			return true
+		}
+	}
+
+	email := strings.ToLower(user.Profile.Email)
+	for _, domain := range role.EmailDomains {
+		if email != "" && strings.HasSuffix(email, "@"+strings.ToLower(strings.TrimPrefix(domain, "@"))) {
+			return true
+		}
+	}
+
+	if len(role.UserGroups) == 0 {
+		return false
+	}
+	members := team.userGroupMembers()
+	for _, group := range role.UserGroups {
+		if members[strings.TrimPrefix(group, "@")][user.ID] {
+			return true
+		}
+	}
+	return false
+}
+
+// userGroupMembers returns the members of the Slack user groups of the
+// team, by handle and by ID. They are fetched again in the background
+// after userGroupsTTL, serving the previous members meanwhile, and
+// kept if that fails. Only the first fetch is waited for.
+func (team *Team) userGroupMembers() map[string]map[string]bool {
+	groups := &team.userGroups
+	groups.lock.Lock()
+	if team.Slack != nil && groups.fetching == nil && time.Since(groups.fetched) >= userGroupsTTL {
+		groups.fetching = make(chan struct{})
+		go team.fetchUserGroups(groups.fetching)
+	}
+	members, fetching := groups.members, groups.fetching
+	groups.lock.Unlock()
+
+	if members == nil && fetching != nil {
+		<-fetching
+		groups.lock.Lock()
+		members = groups.members
+		groups.lock.Unlock()
+	}
+	return members
+}
+
+// fetchUserGroups fetches the members of the user groups with the Web
+// API, without holding the lock, and closes `done`.
+func (team *Team) fetchUserGroups(done chan struct{}) {
+	groups := &team.userGroups
+	defer func() {
+		groups.lock.Lock()
+		groups.fetched = time.Now()
+		groups.fetching = nil
+		groups.lock.Unlock()
+		close(done)
+	}()
+
+	ctx, cancel := context.WithTimeout(team.bot.Context(), 10*time.Second)
+	defer cancel()
+	fetched, err := team.Slack.GetUserGroupsContext(ctx, slack.GetUserGroupsOptionIncludeUsers(true))
+	if team.bot.countAPICall("usergroups.list", err) != nil {
+		log.WithFields(log.Fields{
+			"Type": "Permissions",
+			"Team": team.Name,
+		}).WithError(err).Error("Couldn't fetch the user groups.")
+		return
+	}
+
+	members := make(map[string]map[string]bool)
+	for _, group := range fetched {
+		users := make(map[string]bool)
+		for _, userID := range group.Users {
+			users[userID] = true
+		}
+		members[group.ID] = users
+		members[group.Handle] = users
+	}
+
+	groups.lock.Lock()
+	groups.members = members
+	groups.lock.Unlock()
+}
+
+// grantedRoles returns the roles granted in chat to a user of the team.
+func (team *Team) grantedRoles(userID string) map[string]bool {
+	granted := make(map[string]bool)
+	if team.bot.StorageBackend == nil {
+		return granted
+	}
+
+	var names []string
+	if err := team.Storage("roles").Get(userID, &names); err != nil && err != ErrNotFound {
+		log.WithFields(log.Fields{
+			"Type": "Permissions",
+			"User": userID,
+		}).WithError(err).Error("Couldn't read the granted roles.")
+	}
+	for _, name := range names {
+		granted[name] = true
+	}
+	return granted
+}
+
+// RequirePermission tells if the author of `msg` has `permission`.
+// When they don't, it replies that they can't, and records the denied
+// attempt in the audit log.
+func (bot *Bot) RequirePermission(msg *Message, permission string) bool {
+	if bot.Can(msg.FromUser, permission) {
+		return true
+	}
+
+	bot.denyPermission(msg, permission)
+	entry := AuditEntry{
+		Action:  AuditPermissionDenied,
+		Channel: msg.Channel,
+		Target:  permission,
+		Outcome: AuditDenied,
+		Team:    msg.Workspace().Name,
+	}
+	if msg.FromUser != nil {
+		entry.Actor = msg.FromUser.ID
+	}
+	bot.Audit(msg.Context(), entry)
+	return false
+}
+
+// denyPermission logs a denied attempt, and replies to its author.
+func (bot *Bot) denyPermission(msg *Message, permission string) {
+	userID := ""
+	if msg.FromUser != nil {
+		userID = msg.FromUser.ID
+	}
+	Log(msg.Context()).WithFields(log.Fields{
+		"Type":       "PermissionDenied",
+		"User":       userID,
+		"Channel":    msg.Channel,
+		"Permission": permission,
+		"Text":       msg.Text,
+	}).Warn("Permission denied.")
+
+	msg.ReplyMention("sorry, you need the `%s` permission for that", permission)
+}
+
+// listenRoleCommands registers the commands granting and listing
+// roles.
+func (bot *Bot) listenRoleCommands() {
+	bot.Command(&Command{
+		Name:        "roles",
+		Args:        []CommandArg{{Name: "user", Type: ArgUser, Optional: true}},
+		Usage:       "lists the roles of a user, or your own",
+		HandlerFunc: bot.rolesCommand,
+	})
+	bot.Command(&Command{
+		Name:        "grant-role",
+		Args:        []CommandArg{{Name: "role", Type: ArgWord}, {Name: "user", Type: ArgUser}},
+		Usage:       "grants a role of the config to a user",
+		Permission:  PermissionManageRoles,
+		HandlerFunc: bot.grantRoleCommand,
+	})
+	bot.Command(&Command{
+		Name:        "revoke-role",
+		Args:        []CommandArg{{Name: "role", Type: ArgWord}, {Name: "user", Type: ArgUser}},
+		Usage:       "revokes a role granted in chat",
+		Permission:  PermissionManageRoles,
+		HandlerFunc: bot.grantRoleCommand,
+	})
+}
+
+func (bot *Bot) rolesCommand(cmd *Command, msg *Message, args CommandArgs) {
+	user := msg.FromUser
+	if args.Has("user") {
+		user = args.User("user")
+	}
+	if user == nil {
+		return
+	}
+
+	roles := bot.UserRoles(user)
+	if len(roles) == 0 {
+		msg.Reply("%s has no roles", MentionUser(user.ID))
+		return
+	}
+	msg.Reply("%s has the roles: %s", MentionUser(user.ID), strings.Join(roles, ", "))
+}
+
+func (bot *Bot) grantRoleCommand(cmd *Command, msg *Message, args CommandArgs) {
+	role, user := strings.ToLower(args.String("role")), args.User("user")
+	grant := cmd.Name == "grant-role"
+
+	bot.permissions.lock.RLock()
+	_, ok := bot.permissions.roles[role]
+	bot.permissions.lock.RUnlock()
+	if !ok && grant {
+		msg.ReplyMention("there's no role %q in the config", role)
+		return
+	}
+
+	err := msg.Workspace().Storage("roles").Update(func(tx StoreTx) error {
+		var names []string
+		if err := tx.Get(user.ID, &names); err != nil && err != ErrNotFound {
+			return err
+		}
+
+		var updated []string
+		for _, name := range names {
+			if name != role {
+				updated = append(updated, name)
+			}
+		}
+		if grant {
+			updated = append(updated, role)
+		}
+		if len(updated) == 0 {
+			return tx.Delete(user.ID)
+		}
+		return tx.Put(user.ID, updated)
+	})
+	if err != nil {
+		msg.ReplyMention("couldn't update the roles: %s", err)
+		return
+	}
+
+	Log(msg.Context()).WithFields(log.Fields{
+		"Type":  "Permissions",
+		"Admin": msg.FromUser.ID,
+		"User":  user.ID,
+		"Role":  role,
+		"Grant": grant,
+	}).Info("Role updated.")
+
+	if grant {
+		msg.ReplyMention("%s now has the role %s", MentionUser(user.ID), role)
+	} else {
+		msg.ReplyMention("%s no longer has the role %s, unless the config grants it", MentionUser(user.ID), role)
+	}
+}
@@ -0,0 +1,103 @@
+package slick
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCan(t *testing.T) {
+	bot := New("")
+	alice := &slack.User{ID: "UALICE", Name: "alice"}
+	bob := &slack.User{ID: "UBOB", Name: "bob"}
+	bob.Profile.Email = "Bob@Example.com"
+	carol := &slack.User{ID: "UCAROL", Name: "carol"}
+
+	assert.True(t, bot.Can(carol, "deployer.deploy"), "without roles, everyone can do everything")
+
+	bot.setRoles(map[string]Role{
+		"admins":    {Permissions: []string{"*"}, Users: []string{"UALICE"}},
+		"deployers": {Permissions: []string{"deployer.*"}, EmailDomains: []string{"example.com"}},
+		"ops":       {Permissions: []string{"wicked.start"}, UserGroups: []string{"@ops"}},
+	})
+	bot.userGroups.members = map[string]map[string]bool{"ops": {"UCAROL": true}}
+	bot.userGroups.fetched = time.Now()
+
+	assert.True(t, bot.Can(alice, "anything"))
+	assert.False(t, bot.Can(&slack.User{ID: "UMALLORY", Name: "UALICE"}, "anything"), "roles match IDs, not names")
+	assert.True(t, bot.Can(bob, "deployer.lock"), "by email domain and prefix")
+	assert.False(t, bot.Can(bob, "deployers"))
+	assert.False(t, bot.Can(bob, "wicked.start"))
+	assert.True(t, bot.Can(carol, "wicked.start"), "by user group")
+	assert.False(t, bot.Can(nil, "wicked.start"))
+	assert.True(t, bot.Can(nil, ""), "no permission needed")
+
+	assert.Equal(t, []string{"deployers"}, bot.UserRoles(bob))
+	assert.Equal(t, []string{"ops"}, bot.UserRoles(carol))
+
+	// The user groups are those of the user's team
+	acme := newTeam(bot, "acme")
+	acme.ID = "T2"
+	acme.userGroups.members = map[string]map[string]bool{"ops": {"UDAVE": true}}
+	acme.userGroups.fetched = time.Now()
+	bot.teams = append(bot.teams, acme)
+	assert.True(t, bot.Can(&slack.User{ID: "UDAVE", TeamID: "T2"}, "wicked.start"))
+	assert.False(t, bot.Can(&slack.User{ID: "UCAROL", TeamID: "T2"}, "wicked.start"))
+	assert.False(t, bot.Can(&slack.User{ID: "UDAVE"}, "wicked.start"))
+}
+
+func TestUserGroupsRefresh(t *testing.T) {
+	release := make(chan bool)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprint(w, `{"ok":true,"usergroups":[{"id":"S1","handle":"ops","users":["UDAVE"]}]}`)
+	}))
+	defer api.Close()
+
+	defaultAPI := slack.SLACK_API
+	slack.SLACK_API = api.URL + "/"
+	defer func() { slack.SLACK_API = defaultAPI }()
+
+	bot := New("")
+	bot.Slack = slack.New("xoxb-1")
+
+	// The first fetch is waited for
+	go func() { release <- true }()
+	assert.True(t, bot.userGroupMembers()["ops"]["UDAVE"])
+	assert.True(t, bot.userGroupMembers()["S1"]["UDAVE"], "cached")
+
+	// Stale members are served while they are fetched again
+	bot.userGroups.lock.Lock()
+	bot.userGroups.members = map[string]map[string]bool{"ops": {"UCAROL": true}}
+	bot.userGroups.fetched = time.Now().Add(-userGroupsTTL)
+	bot.userGroups.lock.Unlock()
+	assert.True(t, bot.userGroupMembers()["ops"]["UCAROL"])
+
+	bot.userGroups.lock.Lock()
+	fetching := bot.userGroups.fetching
+	bot.userGroups.lock.Unlock()
+	if assert.NotNil(t, fetching, "the members are fetched in the background") {
+		release <- true
+		<-fetching
+	}
+	assert.False(t, bot.userGroupMembers()["ops"]["UCAROL"])
+	assert.True(t, bot.userGroupMembers()["ops"]["UDAVE"])
+}
+
+func TestCheckRoles(t *testing.T) {
+	assert.Empty(t, checkRoles(nil))
+	assert.Equal(t, []error{
+		errors.New("roles.empty: no permissions"),
+		errors.New(`roles.names.users: "alice" is not a user ID`),
+	}, checkRoles(map[string]Role{
+		"empty": {Users: []string{"UALICE"}},
+		"full":  {Permissions: []string{"*"}},
+		"names": {Permissions: []string{"*"}, Users: []string{"alice"}},
+	}))
+}
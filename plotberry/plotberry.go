@@ -37,7 +37,12 @@ func (plotberry *PlotBerry) InitPlugin(bot *slick.Bot) {
 
 	statchan := make(chan TotalUsers, 100)
 
-	go plotberry.launchWatcher(statchan)
+	_, err := bot.Scheduler.Cron("plotberry", fmt.Sprintf("@every %s", plotberry.pingTime), func() {
+		plotberry.checkUsers(statchan)
+	})
+	if err != nil {
+		log.Print(err)
+	}
 	go plotberry.launchCounter(statchan)
 
 	bot.Listen(&slick.Listener{
@@ -76,24 +81,18 @@ func GetPlotberry() (*TotalUsers, error) {
 	return &data, nil
 }
 
-func (plotberry *PlotBerry) launchWatcher(statchan chan TotalUsers) {
-
-	for {
-		time.Sleep(plotberry.pingTime)
-
-		data, err := GetPlotberry()
-
-		if err != nil {
-			log.Print(err)
-			continue
-		}
-
-		if data.Plotberries != plotberry.totalUsers {
-			statchan <- *data
-		}
+func (plotberry *PlotBerry) checkUsers(statchan chan TotalUsers) {
+	data, err := GetPlotberry()
+	if err != nil {
+		log.Print(err)
+		return
+	}
 
-		plotberry.totalUsers = data.Plotberries
+	if data.Plotberries != plotberry.totalUsers {
+		statchan <- *data
 	}
+
+	plotberry.totalUsers = data.Plotberries
 }
 
 func (plotberry *PlotBerry) launchCounter(statchan chan TotalUsers) {
@@ -132,7 +131,7 @@ func (plotberry *PlotBerry) launchCounter(statchan chan TotalUsers) {
 			case rem == 2:
 				msg = fmt.Sprintf("%d more! humpa humpa\n", rem)
 			case rem == 1:
-				plotberry.bot.SendToChannel(plotberry.bot.Config.GeneralChannel, fmt.Sprintf("%d users until 100000.\nYOU'RE ALL MAGIC!", rem))
+				plotberry.bot.SendToChannel(plotberry.bot.Config().GeneralChannel, fmt.Sprintf("%d users until 100000.\nYOU'RE ALL MAGIC!", rem))
 				msg = "https://31.media.tumblr.com/3b74abfa367a3ed9a2cd753cd9018baa/tumblr_miul04oqog1qkp8xio1_400.gif"
 			case rem <= 0:
 				msg = fmt.Sprintf("@all FINALCOUNTDOWN!!!\n We're at %d user signups!!!!! My human compatriots, taking an idea to a product with 100,000 users is an achievement few will experience in their life times. Reflect, humans, on your hard work and celebrate this success. You deserve it, and remember, Plot On!", totalUsers)
@@ -140,7 +139,7 @@ func (plotberry *PlotBerry) launchCounter(statchan chan TotalUsers) {
 			default:
 				msg = fmt.Sprintf("We are at %d total user signups!", totalUsers)
 			}
-			plotberry.bot.SendToChannel(plotberry.bot.Config.GeneralChannel, msg)
+			plotberry.bot.SendToChannel(plotberry.bot.Config().GeneralChannel, msg)
 		}
 	}
 }
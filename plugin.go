@@ -1,7 +1,9 @@
 package slick
 
 import (
+	"context"
 	"net/http"
+	"reflect"
 
 	log "github.com/sirupsen/logrus"
 
@@ -23,6 +25,22 @@ type PluginInitializer interface {
 	InitPlugin(*Bot)
 }
 
+// PluginStopper describes the plugins which need to clean up when the
+// bot shuts down, like flushing their data or waiting for a running
+// job. StopPlugin should return once `ctx` is done, see
+// `Bot.Shutdown`.
+type PluginStopper interface {
+	StopPlugin(ctx context.Context) error
+}
+
+// ConfigReloader describes the plugins which apply a new configuration
+// without restarting the bot. ReloadConfig is called once the config
+// file has been read again and checked, and should call
+// `bot.LoadConfig` like InitPlugin does. See `Bot.ReloadConfig`.
+type ConfigReloader interface {
+	ReloadConfig(bot *Bot) error
+}
+
 // WebServer describes the interface for webserver plugins
 type WebServer interface {
 	// Used internally by the `slick` library.
@@ -62,8 +80,17 @@ func RegisteredPlugins() []Plugin {
 	return registeredPlugins
 }
 
+// pluginName returns the type name of a plugin, like "faceoff.Faceoff".
+func pluginName(plugin Plugin) string {
+	pluginType := reflect.TypeOf(plugin)
+	if pluginType.Kind() == reflect.Ptr {
+		pluginType = pluginType.Elem()
+	}
+	return pluginType.String()
+}
+
 func initChatPlugins(bot *Bot) {
-	for _, plugin := range registeredPlugins {
+	for _, plugin := range bot.plugins {
 		chatPlugin, ok := plugin.(PluginInitializer)
 		if ok {
 			chatPlugin.InitPlugin(bot)
@@ -72,7 +99,7 @@ func initChatPlugins(bot *Bot) {
 }
 
 func initWebServer(bot *Bot, enabledPlugins []string) {
-	for _, plugin := range registeredPlugins {
+	for _, plugin := range bot.plugins {
 		webServer, ok := plugin.(WebServer)
 		if ok {
 			webServer.InitWebServer(bot, enabledPlugins)
@@ -87,7 +114,7 @@ func initWebPlugins(bot *Bot) {
 		return
 	}
 
-	for _, plugin := range registeredPlugins {
+	for _, plugin := range bot.plugins {
 		if webPlugin, ok := plugin.(WebPlugin); ok {
 			webPlugin.InitWebPlugin(bot, bot.WebServer.PrivateRouter(), bot.WebServer.PublicRouter())
 		}
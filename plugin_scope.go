@@ -0,0 +1,259 @@
+package slick
+
+import (
+	"path"
+	"reflect"
+	"runtime"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PluginConfig enables a plugin, and restricts it to some channels. It
+// is read from the Plugins section of the config, by package name:
+//
+//	"Plugins": {
+//	  "faceoff": {"enabled": false},
+//	  "todo": {"allow_channels": ["general", "dev"]},
+//	  "funny": {"deny_channels": ["announcements"]}
+//	}
+type PluginConfig struct {
+	// Enabled defaults to true. Enabling or disabling a plugin needs a
+	// restart.
+	Enabled *bool
+
+	// AllowChannels, when set, are the only channels where the
+	// plugin's listeners and commands get messages, and DenyChannels
+	// are channels where they don't. Channels are given by name or
+	// ID, and names are resolved once the team's channels are loaded.
+	// Until a denied name is resolved, the messages of unknown channels
+	// are denied too. Direct messages are only handled by the plugins
+	// without AllowChannels.
+	AllowChannels []string `json:"allow_channels" mapstructure:"allow_channels"`
+	DenyChannels  []string `json:"deny_channels" mapstructure:"deny_channels"`
+}
+
+// pluginPackage returns the import path of a plugin's package, like
+// "github.com/CapstoneLabs/slick/todo".
+func pluginPackage(plugin Plugin) string {
+	pluginType := reflect.TypeOf(plugin)
+	if pluginType.Kind() == reflect.Ptr {
+		pluginType = pluginType.Elem()
+	}
+	return pluginType.PkgPath()
+}
+
+// corePackage is the import path of this package.
+var corePackage = reflect.TypeOf(Bot{}).PkgPath()
+
+// callerPlugin returns the package of the code registering a Listener
+// or a Command: the first caller outside of this package. It returns ""
+// when they are registered by this package itself, so that the
+// package of their handler is used.
+func callerPlugin() string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if pkgPath := funcPackage(frame.Function); pkgPath != corePackage {
+			// The standard library, like the goroutines of the
+			// listeners or of the tests, isn't a plugin.
+			if pkgPath == "main" || strings.Contains(strings.Split(pkgPath, "/")[0], ".") {
+				return pkgPath
+			}
+			return ""
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// EnabledPlugins returns the registered plugins not disabled in the
+// config. They are the ones started by Run.
+func (bot *Bot) EnabledPlugins() []Plugin {
+	bot.pluginsLock.RLock()
+	defer bot.pluginsLock.RUnlock()
+	return bot.plugins
+}
+
+func (bot *Bot) pluginEnabled(plugin Plugin) bool {
+	for _, enabled := range bot.EnabledPlugins() {
+		if enabled == plugin {
+			return true
+		}
+	}
+	return false
+}
+
+// pluginsEnabledBy returns the registered plugins not disabled by
+// `configs`, the Plugins section of the config.
+func pluginsEnabledBy(configs map[string]PluginConfig) []Plugin {
+	var enabled []Plugin
+	for _, plugin := range registeredPlugins {
+		config, ok := configs[path.Base(pluginPackage(plugin))]
+		if !ok || config.Enabled == nil || *config.Enabled {
+			enabled = append(enabled, plugin)
+		}
+	}
+	return enabled
+}
+
+// setPluginConfigs applies the Plugins section of the config. Before
+// the plugins are started, it also picks the enabled ones.
+func (bot *Bot) setPluginConfigs(configs map[string]PluginConfig) {
+	known := make(map[string]bool)
+	scopes := make(map[string]PluginConfig)
+	for _, plugin := range registeredPlugins {
+		pkgPath := pluginPackage(plugin)
+		known[path.Base(pkgPath)] = true
+		if config, ok := configs[path.Base(pkgPath)]; ok {
+			scopes[pkgPath] = config
+		}
+	}
+	for name := range configs {
+		if !known[name] {
+			log.WithFields(log.Fields{
+				"Type":   "PluginConfig",
+				"Plugin": name,
+			}).Warn("No such plugin, ignoring its config.")
+		}
+	}
+	enabled := pluginsEnabledBy(configs)
+
+	bot.pluginsLock.Lock()
+	defer bot.pluginsLock.Unlock()
+
+	bot.pluginScopes = scopes
+	for _, team := range bot.Teams() {
+		team.resolvePluginScopesLocked()
+	}
+	if !bot.pluginsStarted {
+		bot.plugins = enabled
+		return
+	}
+
+	if len(enabled) != len(bot.plugins) {
+		log.WithFields(log.Fields{
+			"Type": "PluginConfig",
+		}).Warn("Enabling or disabling plugins needs a restart, ignoring it.")
+	}
+}
+
+// pluginScope is the PluginConfig of a plugin, with its channels
+// resolved to the IDs of a team's channels.
+type pluginScope struct {
+	allow map[string]bool
+	deny  map[string]bool
+	// unresolved tells if some denied channels aren't known to the
+	// team.
+	unresolved bool
+}
+
+// resolvePluginScope resolves the channels of `config` to IDs, by
+// name in the team's directory.
+func (team *Team) resolvePluginScope(config PluginConfig) pluginScope {
+	resolve := func(channels []string) (ids map[string]bool, unresolved bool) {
+		ids = make(map[string]bool)
+		for _, channel := range channels {
+			channel = strings.TrimPrefix(channel, "#")
+			if reChannelID.MatchString(channel) {
+				ids[channel] = true
+			} else if found, ok := team.Channels.ByName(channel); ok {
+				ids[found.ID] = true
+			} else {
+				unresolved = true
+			}
+		}
+		return ids, unresolved
+	}
+
+	var scope pluginScope
+	scope.allow, _ = resolve(config.AllowChannels)
+	scope.deny, scope.unresolved = resolve(config.DenyChannels)
+	return scope
+}
+
+// resolvePluginScopes resolves the channels of the plugin scopes for
+// the team. It runs once its directory has loaded, when channels are
+// renamed, and when the config is reloaded.
+func (team *Team) resolvePluginScopes() {
+	team.bot.pluginsLock.Lock()
+	defer team.bot.pluginsLock.Unlock()
+	team.resolvePluginScopesLocked()
+}
+
+func (team *Team) resolvePluginScopesLocked() {
+	scopes := make(map[string]pluginScope)
+	for pkgPath, config := range team.bot.pluginScopes {
+		scopes[pkgPath] = team.resolvePluginScope(config)
+	}
+	team.pluginScopes = scopes
+}
+
+// inScope tells if the plugin with the package `pkgPath` handles the
+// messages of `channelID`, a channel of `team`.
+func (bot *Bot) inScope(team *Team, pkgPath, channelID string) bool {
+	bot.pluginsLock.RLock()
+	config, ok := bot.pluginScopes[pkgPath]
+	scope, resolved := team.pluginScopes[pkgPath]
+	bot.pluginsLock.RUnlock()
+	if !ok || (len(config.AllowChannels) == 0 && len(config.DenyChannels) == 0) {
+		return true
+	}
+	if !resolved {
+		scope = team.resolvePluginScope(config)
+	}
+
+	// This runs on the event loop: unknown channels aren't waited for.
+	// Those created since the scope was resolved are matched by name.
+	channel, known := team.Channels.peek(channelID)
+	matches := func(ids map[string]bool, channels []string) bool {
+		if ids[channelID] {
+			return true
+		}
+		for _, name := range channels {
+			if known && channel.Name != "" && strings.TrimPrefix(name, "#") == channel.Name {
+				return true
+			}
+		}
+		return false
+	}
+
+	if matches(scope.deny, config.DenyChannels) {
+		return false
+	}
+	if !known && scope.unresolved {
+		// It might be one of the denied channels.
+		return false
+	}
+	if len(config.AllowChannels) != 0 {
+		return matches(scope.allow, config.AllowChannels)
+	}
+	return true
+}
+
+// pluginPackage returns the package of the plugin which registered
+// the Command, or else of its HandlerFunc.
+func (cmd *Command) pluginPackage() string {
+	if cmd.plugin != "" {
+		return cmd.plugin
+	}
+	_, plugin := funcIdentity(cmd.HandlerFunc)
+	return plugin
+}
+
+// listenerInScope tells if `listen` handles the messages of
+// `channelID` in `team`, given the scope of the plugin which registered
+// it.
+func (bot *Bot) listenerInScope(listen *Listener, team *Team, channelID string) bool {
+	bot.pluginsLock.RLock()
+	scoped := len(bot.pluginScopes) != 0
+	bot.pluginsLock.RUnlock()
+	if !scoped {
+		return true
+	}
+
+	_, plugin := listen.identity()
+	return bot.inScope(team, plugin, channelID)
+}
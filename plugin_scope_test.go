@@ -0,0 +1,75 @@
+package slick
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPluginConfigs(t *testing.T) {
+	plugin := &schemaPlugin{}
+	defer func(plugins []Plugin) { registeredPlugins = plugins }(registeredPlugins)
+	registeredPlugins = []Plugin{plugin}
+
+	disabled := false
+	assert.Equal(t, []Plugin{plugin}, pluginsEnabledBy(nil))
+	assert.Empty(t, pluginsEnabledBy(map[string]PluginConfig{"slick": {Enabled: &disabled}}))
+
+	v := readTestConfig(t, `{
+		"Slack": {"api_token": "xoxb-1"},
+		"Plugins": {"slick": {"enabled": false}}
+	}`)
+	assert.NoError(t, checkConfig(v, true), "disabled plugins don't need a valid config")
+
+	bot := New("")
+	bot.Channels.Set(Channel{ID: "C1", Name: "general"})
+	bot.Channels.Set(Channel{ID: "C2", Name: "random"})
+	bot.Channels.Set(Channel{ID: "C3", Name: "dev"})
+
+	bot.setPluginConfigs(map[string]PluginConfig{
+		"slick": {AllowChannels: []string{"#general", "C2"}, DenyChannels: []string{"random"}},
+	})
+	assert.Equal(t, []Plugin{plugin}, bot.EnabledPlugins())
+
+	pkgPath := pluginPackage(plugin)
+	assert.True(t, bot.inScope(bot.Team, pkgPath, "C1"), "allowed by name")
+	assert.False(t, bot.inScope(bot.Team, pkgPath, "C2"), "the deny list wins")
+	assert.False(t, bot.inScope(bot.Team, pkgPath, "C3"), "not in the allow list")
+	assert.False(t, bot.inScope(bot.Team, pkgPath, "D1"), "direct messages aren't in the allow list")
+	assert.True(t, bot.inScope(bot.Team, "github.com/CapstoneLabs/slick/todo", "C3"), "other plugins aren't restricted")
+
+	listen := &Listener{MessageHandlerFunc: func(*Listener, *Message) {}}
+	assert.False(t, bot.listenerInScope(listen, bot.Team, "C3"), "listeners follow their plugin's scope")
+
+	listen.plugin = "github.com/CapstoneLabs/slick/todo"
+	assert.True(t, bot.listenerInScope(listen, bot.Team, "C3"), "the plugin registering the listener wins over its handler's")
+	assert.Empty(t, callerPlugin(), "this package isn't a plugin")
+
+	bot.pluginsStarted = true
+	bot.setPluginConfigs(map[string]PluginConfig{"slick": {Enabled: &disabled}})
+	assert.Equal(t, []Plugin{plugin}, bot.EnabledPlugins(), "disabling a plugin needs a restart")
+	assert.True(t, bot.inScope(bot.Team, pkgPath, "C3"), "scopes are reloaded")
+}
+
+func TestPluginScopeResolvesChannels(t *testing.T) {
+	plugin := &schemaPlugin{}
+	defer func(plugins []Plugin) { registeredPlugins = plugins }(registeredPlugins)
+	registeredPlugins = []Plugin{plugin}
+	pkgPath := pluginPackage(plugin)
+
+	bot := New("")
+	bot.setPluginConfigs(map[string]PluginConfig{
+		"slick": {DenyChannels: []string{"announcements"}},
+	})
+	assert.False(t, bot.inScope(bot.Team, pkgPath, "C9"), "unknown channels might be denied")
+
+	bot.Channels.Set(Channel{ID: "C1", Name: "general"})
+	bot.Channels.Set(Channel{ID: "C9", Name: "announcements"})
+	bot.Team.resolvePluginScopes()
+	assert.True(t, bot.inScope(bot.Team, pkgPath, "C1"))
+	assert.False(t, bot.inScope(bot.Team, pkgPath, "C9"))
+
+	bot.Channels.Delete("C9")
+	assert.False(t, bot.inScope(bot.Team, pkgPath, "C9"), "denied by ID once resolved")
+	assert.True(t, bot.inScope(bot.Team, pkgPath, "C8"), "all the denied channels are known")
+}
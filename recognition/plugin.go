@@ -1,16 +1,15 @@
 package recognition
 
 import (
-	log "github.com/sirupsen/logrus"
+	"sync"
 
 	"github.com/CapstoneLabs/slick"
-	"github.com/boltdb/bolt"
 )
 
 type Plugin struct {
-	bot    *slick.Bot
-	config Config
-	store  Store
+	bot        *slick.Bot
+	configLock sync.Mutex
+	config     Config
 }
 
 func init() {
@@ -20,22 +19,32 @@ func init() {
 func (p *Plugin) InitPlugin(bot *slick.Bot) {
 	p.bot = bot
 
-	err := bot.DB.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists(bucketName)
-		return err
-	})
-	if err != nil {
-		log.Fatalln("Couldn't create the `recognition` bucket")
-	}
+	p.listenRecognize()
+	p.listenUpvotes()
+}
 
-	var conf struct {
-		Recognition Config
+// ConfigSchema declares the Recognition section of the config, loaded
+// before InitPlugin. See `slick.ConfigSchema`.
+func (p *Plugin) ConfigSchema() (string, interface{}) {
+	return "Recognition", &p.config
+}
+
+// ReloadConfig loads the recognition channel and domain restriction,
+// see `slick.ConfigReloader`.
+func (p *Plugin) ReloadConfig(bot *slick.Bot) error {
+	var config Config
+	if err := bot.LoadPluginConfig("Recognition", &config); err != nil {
+		return err
 	}
-	bot.LoadConfig(&conf)
-	p.config = conf.Recognition
 
-	p.store = &boltStore{db: bot.DB}
+	p.configLock.Lock()
+	p.config = config
+	p.configLock.Unlock()
+	return nil
+}
 
-	p.listenRecognize()
-	p.listenUpvotes()
+func (p *Plugin) getConfig() Config {
+	p.configLock.Lock()
+	defer p.configLock.Unlock()
+	return p.config
 }
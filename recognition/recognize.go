@@ -23,9 +23,11 @@ func (p *Plugin) handleRecognize(listen *slick.Listener, msg *slick.Message) {
 	users := msg.Match[1]
 	feat := msg.Match[5]
 
-	channel := p.bot.GetChannelByName(p.config.Channel)
+	team := msg.Workspace()
+	channelName := p.getConfig().Channel
+	channel := team.GetChannelByName(channelName)
 	if channel == nil {
-		fmt.Println("Didn't find the recognitions, can't handle `!recognition` requests. Searched for:", p.config.Channel)
+		fmt.Println("Didn't find the recognitions, can't handle `!recognition` requests. Searched for:", channelName)
 		return
 	}
 
@@ -36,7 +38,7 @@ func (p *Plugin) handleRecognize(listen *slick.Listener, msg *slick.Message) {
 		return
 	}
 
-	announcement := p.bot.SendOutgoingMessage(fmt.Sprintf("<@%s|%s> would like to recognize %s\n>>> For %s", msg.FromUser.ID, msg.FromUser.Name, users, feat), channel.ID)
+	announcement := team.SendOutgoingMessage(fmt.Sprintf("%s would like to recognize %s\n>>> For %s", slick.MentionUser(msg.FromUser.ID), users, feat), channel.ID)
 
 	announcement.AddReaction("+1")
 	announcement.AddReaction("dart")
@@ -49,7 +51,7 @@ func (p *Plugin) handleRecognize(listen *slick.Listener, msg *slick.Message) {
 
 	announcement.OnAck(func(ack *slack.AckMessage) {
 		ts := ack.Timestamp
-		domain := p.bot.Config.TeamDomain
+		domain := team.Config().TeamDomain
 		url := fmt.Sprintf("https://%s.slack.com/archives/%s/p%s", domain, channel.Name, strings.Replace(ts, ".", "", 1))
 		msg.ReplyMention("Great! Everyone can upvote this recognition here %s", url)
 
@@ -63,7 +65,11 @@ func (p *Plugin) handleRecognize(listen *slick.Listener, msg *slick.Message) {
 				msg.FromUser.ID: 1,
 			},
 		}
-		p.store.Put(recog)
+		if err := p.storeFor(team).Put(recog); err != nil {
+			log.WithError(err).Error("recognition: couldn't save recognition")
+			msg.ReplyMention("sorry, I couldn't save your recognition: %s", err)
+			return
+		}
 
 		p.bot.PubSub.Pub(recog, "recognition:recognized")
 
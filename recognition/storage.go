@@ -1,73 +1,53 @@
 package recognition
 
 import (
-	"encoding/json"
-
-	log "github.com/sirupsen/logrus"
-
-	"github.com/boltdb/bolt"
+	"github.com/CapstoneLabs/slick"
 )
 
 type Store interface {
-	Get(ts string) *Recognition
-	Put(*Recognition)
-	All() map[string]*Recognition
+	// Get returns the recognition posted at `ts`, or
+	// slick.ErrNotFound.
+	Get(ts string) (*Recognition, error)
+	Put(*Recognition) error
+	All() (map[string]*Recognition, error)
 }
 
-type boltStore struct {
-	db *bolt.DB
+// namespace is also the name of the BoltDB bucket the recognitions
+// were stored in before the storage API.
+const namespace = "recognitions"
+
+// storeFor returns the Store of a team, so each team has its own
+// recognitions.
+func (p *Plugin) storeFor(team *slick.Team) Store {
+	return &slickStore{store: team.Storage(namespace)}
 }
 
-var bucketName = []byte("recognitions")
+type slickStore struct {
+	store slick.Store
+}
 
-func (s *boltStore) Get(ts string) (r *Recognition) {
-	err := s.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket(bucketName)
-		return json.Unmarshal(b.Get([]byte(ts)), &r)
-	})
-	if err != nil {
-		log.Println("ERROR fetching recognition:", err)
-	}
+func (s *slickStore) Get(ts string) (r *Recognition, err error) {
+	err = s.store.Get(ts, &r)
 	return
 }
 
-func (s *boltStore) Put(r *Recognition) {
-	err := s.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket(bucketName)
-		cnt, err := json.Marshal(r)
-		if err != nil {
-			return err
-		}
-
-		b.Put([]byte(r.MsgTimestamp), cnt)
-
-		return nil
-	})
-	if err != nil {
-		log.Println("ERROR saving recognition:", err)
-	}
+func (s *slickStore) Put(r *Recognition) error {
+	return s.store.Put(r.MsgTimestamp, r)
 }
 
-func (s *boltStore) All() map[string]*Recognition {
+func (s *slickStore) All() (map[string]*Recognition, error) {
 	out := make(map[string]*Recognition)
 
-	err := s.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket(bucketName)
-
-		return b.ForEach(func(k, v []byte) error {
-			r := &Recognition{}
-
-			if err := json.Unmarshal(v, &r); err != nil {
-				return err
-			}
+	err := s.store.List("", func(key string, value slick.StoredValue) error {
+		r := &Recognition{}
+		if err := value.Decode(r); err != nil {
+			return err
+		}
 
-			out[r.MsgTimestamp] = r
+		out[r.MsgTimestamp] = r
 
-			return nil
-		})
+		return nil
 	})
-	if err != nil {
-		log.Println("ERROR fetching recognition:", err)
-	}
-	return out
+
+	return out, err
 }
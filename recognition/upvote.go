@@ -1,6 +1,7 @@
 package recognition
 
 import (
+	"fmt"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
@@ -17,33 +18,64 @@ func (p *Plugin) listenUpvotes() {
 			}
 
 			log.Println("Fetching item ts:", react.Item.Timestamp)
-			recognition := p.store.Get(react.Item.Timestamp)
+			team, recognition, err := p.findRecognition(react.Item.Timestamp)
+			if err != nil {
+				log.WithError(err).Error("recognition: couldn't fetch recognition")
+				return
+			}
 			if recognition == nil {
 				return
 			}
 
-			user := p.bot.Users[react.User]
+			user, _ := team.Users.Get(react.User)
 			if user.IsBot {
 				log.Println("Not taking votes from bots")
 				return
 			}
 
-			if p.config.DomainRestriction != "" && !strings.HasSuffix(user.Profile.Email, p.config.DomainRestriction) {
-				log.Printf("Not taking votes from people outsite domain %q, was %q", p.config.DomainRestriction, user.Profile.Email)
+			domain := p.getConfig().DomainRestriction
+			if domain != "" && !strings.HasSuffix(user.Profile.Email, domain) {
+				log.Printf("Not taking votes from people outsite domain %q, was %q", domain, user.Profile.Email)
 				return
 			}
 
 			log.Println("Up/down voting recognition")
-			p.upvoteRecognition(recognition, react)
+			p.upvoteRecognition(team, recognition, react)
 		},
 	})
 }
 
-func (p *Plugin) upvoteRecognition(recognition *Recognition, reaction *slick.ReactionEvent) {
+// findRecognition returns the recognition posted at `ts`, and the team
+// it was posted in, or a nil recognition.
+func (p *Plugin) findRecognition(ts string) (*slick.Team, *Recognition, error) {
+	for _, team := range p.bot.Teams() {
+		recognition, err := p.storeFor(team).Get(ts)
+		if err == slick.ErrNotFound {
+			continue
+		}
+		return team, recognition, err
+	}
+	return nil, nil, nil
+}
+
+func (p *Plugin) upvoteRecognition(team *slick.Team, recognition *Recognition, reaction *slick.ReactionEvent) {
 	direction := 1
 	if reaction.Type == slick.ReactionRemoved {
 		direction = -1
 	}
 	recognition.Reactions[reaction.User] += direction
-	p.store.Put(recognition)
+
+	entry := slick.AuditEntry{
+		Actor:   reaction.User,
+		Action:  "recognition.vote",
+		Channel: reaction.Item.Channel,
+		Target:  fmt.Sprintf("%s %+d", reaction.Item.Timestamp, direction),
+		Outcome: slick.AuditOK,
+		Team:    team.Name,
+	}
+	if err := p.storeFor(team).Put(recognition); err != nil {
+		log.WithError(err).Error("recognition: couldn't save vote")
+		entry.Outcome, entry.Error = slick.AuditFailed, err.Error()
+	}
+	p.bot.Audit(p.bot.Context(), entry)
 }
@@ -0,0 +1,153 @@
+package slick
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// recoverListener recovers from a panic in a Listener's handler, or
+// in a middleware, so it doesn't take the bot down. It is deferred
+// around each dispatch.
+//
+// The panic is logged and reported to the `admin_channel`. With
+// `max_listener_panics`, a Listener panicking that many times is
+// closed.
+func (bot *Bot) recoverListener(d *Dispatch) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	listen := d.Listener
+	panics := atomic.AddInt32(&listen.panics, 1)
+	name, plugin := listen.identity()
+
+	Log(d.Context).WithFields(log.Fields{
+		"Type":     "ListenerPanic",
+		"Listener": name,
+		"Plugin":   plugin,
+		"Panics":   panics,
+		"Event":    fmt.Sprintf("%T", d.Event),
+	}).Errorf("Listener panicked: %v\n%s", r, debug.Stack())
+
+	disabled := bot.Config().MaxListenerPanics > 0 && int(panics) == bot.Config().MaxListenerPanics
+	if disabled {
+		log.WithFields(log.Fields{
+			"Type":     "ListenerDisabled",
+			"Listener": name,
+			"Plugin":   plugin,
+		}).Errorf("Disabling listener after %d panics.", panics)
+		bot.disableListener(listen)
+	}
+
+	bot.reportPanic(fmt.Sprintf("Listener `%s` of plugin `%s`", name, plugin), r, d.Message, panics, disabled)
+}
+
+// recoverCommand handles a panic `r` of a Command's HandlerFunc, like
+// recoverListener does for Listeners: the panic is counted against the
+// Command, which is disabled after `max_listener_panics` panics, and
+// not against the Listener dispatching all the commands.
+func (bot *Bot) recoverCommand(cmd *Command, msg *Message, r interface{}) {
+	panics := atomic.AddInt32(&cmd.panics, 1)
+	funcName, _ := funcIdentity(cmd.HandlerFunc)
+	plugin := cmd.pluginPackage()
+
+	Log(msg.Context()).WithFields(log.Fields{
+		"Type":    "CommandPanic",
+		"Command": cmd.Name,
+		"Handler": funcName,
+		"Plugin":  plugin,
+		"Panics":  panics,
+	}).Errorf("Command panicked: %v\n%s", r, debug.Stack())
+
+	disabled := bot.Config().MaxListenerPanics > 0 && int(panics) == bot.Config().MaxListenerPanics
+	if disabled {
+		log.WithFields(log.Fields{
+			"Type":    "CommandDisabled",
+			"Command": cmd.Name,
+			"Plugin":  plugin,
+		}).Errorf("Disabling command after %d panics.", panics)
+		atomic.StoreInt32(&cmd.disabled, 1)
+	}
+
+	bot.reportPanic(fmt.Sprintf("Command `%s%s` of plugin `%s`", CommandPrefix, cmd.Name, plugin), r, msg, panics, disabled)
+}
+
+// reportPanic reports the panic `r` of `what` to the `admin_channel`.
+func (bot *Bot) reportPanic(what string, r interface{}, msg *Message, panics int32, disabled bool) {
+	if bot.Config().AdminChannel == "" {
+		return
+	}
+
+	report := fmt.Sprintf("%s panicked: `%v`", what, r)
+	if msg != nil {
+		report += fmt.Sprintf("\nwhile handling a message from %s in %s", MentionUser(msg.User), LinkChannel(msg.Channel))
+	}
+	if disabled {
+		report += fmt.Sprintf("\nIt panicked %d times, it is now disabled.", panics)
+	}
+	bot.SendToChannel(bot.Config().AdminChannel, report)
+}
+
+// disableListener stops dispatching to the Listener. Its TimeoutFunc
+// isn't called.
+func (bot *Bot) disableListener(listen *Listener) {
+	atomic.StoreInt32(&listen.closed, 1)
+	bot.delListenerCh <- listen
+	if listen.doneCh != nil {
+		select {
+		case listen.doneCh <- true:
+		default:
+		}
+	}
+}
+
+// identity returns the name of the Listener, and of the plugin which
+// registered it. Unless set with `Listener.Name`, they are derived
+// from the handler function, like "todo.(*Plugin).handleTodo" and
+// "github.com/CapstoneLabs/slick/todo".
+func (listen *Listener) identity() (name, plugin string) {
+	var handler interface{} = listen.MessageHandlerFunc
+	if listen.MessageHandlerFunc == nil {
+		handler = listen.EventHandlerFunc
+	}
+
+	funcName, plugin := funcIdentity(handler)
+	if listen.plugin != "" {
+		plugin = listen.plugin
+	}
+
+	name = listen.Name
+	if name == "" {
+		name = funcName[strings.LastIndex(funcName, "/")+1:]
+	}
+
+	return name, plugin
+}
+
+// funcIdentity returns the full name of a function, and the package
+// defining it.
+func funcIdentity(handler interface{}) (funcName, plugin string) {
+	funcName = "unknown"
+	if fn := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()); fn != nil {
+		funcName = strings.TrimSuffix(fn.Name(), "-fm")
+	}
+	return funcName, funcPackage(funcName)
+}
+
+// funcPackage returns the package of the function with the full name
+// `funcName`.
+func funcPackage(funcName string) string {
+	// "github.com/CapstoneLabs/slick/todo.(*Plugin).handleTodo"
+	pkgEnd := strings.LastIndex(funcName, "/") + 1
+	if dot := strings.Index(funcName[pkgEnd:], "."); dot != -1 {
+		return funcName[:pkgEnd+dot]
+	}
+	return funcName
+}
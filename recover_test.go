@@ -0,0 +1,91 @@
+package slick
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+type panickyPlugin struct{}
+
+func (p *panickyPlugin) handlePing(listen *Listener, msg *Message) {
+	var user *slack.User
+	msg.Reply(user.Name)
+}
+
+func TestListenerPanicIsRecovered(t *testing.T) {
+	bot := New("")
+	bot.Transport = newFakeTransport()
+	bot.setConfig(SlackConfig{AdminChannel: "admin", MaxListenerPanics: 2})
+	bot.Users.Set(slack.User{ID: "U1", Name: "bob"})
+	bot.Channels.Set(Channel{ID: "C1", Name: "general", IsChannel: true})
+	bot.Channels.Set(Channel{ID: "C2", Name: "admin", IsChannel: true})
+
+	handled := make(chan bool, 2)
+	panicky := &Listener{Contains: "ping", MessageHandlerFunc: (&panickyPlugin{}).handlePing}
+	bot.listeners = []*Listener{
+		panicky,
+		{Contains: "ping", MessageHandlerFunc: func(*Listener, *Message) { handled <- true }},
+	}
+
+	ping := &slack.RTMEvent{Type: "message", Data: &slack.MessageEvent{
+		Msg: slack.Msg{Type: "message", Channel: "C1", User: "U1", Text: "ping"},
+	}}
+
+	bot.handleRTMEvent(ping)
+	waitListeners(t, bot)
+	assert.Len(t, handled, 1, "the next listener still runs")
+
+	report := <-bot.outgoingMsgCh
+	assert.Equal(t, "C2", report.Channel)
+	assert.Contains(t, report.Text, "Listener `slick.(*panickyPlugin).handlePing` of plugin `github.com/CapstoneLabs/slick` panicked")
+	assert.Contains(t, report.Text, "<@U1> in <#C1>")
+	assert.Len(t, bot.delListenerCh, 0)
+
+	bot.handleRTMEvent(ping)
+	waitListeners(t, bot)
+	report = <-bot.outgoingMsgCh
+	assert.True(t, strings.HasSuffix(report.Text, "it is now disabled."), report.Text)
+	assert.Equal(t, panicky, <-bot.delListenerCh)
+}
+
+func (p *panickyPlugin) pingCommand(cmd *Command, msg *Message, args CommandArgs) {
+	var user *slack.User
+	msg.Reply(user.Name)
+}
+
+func TestCommandPanicIsRecovered(t *testing.T) {
+	bot := newCommandTestBot()
+	bot.Transport = newFakeTransport()
+	bot.setConfig(SlackConfig{AdminChannel: "admin", MaxListenerPanics: 2})
+	bot.Channels.Set(Channel{ID: "C2", Name: "admin", IsChannel: true})
+	bot.Command(&Command{Name: "ping", HandlerFunc: (&panickyPlugin{}).pingCommand})
+	cmd := bot.GetCommand("ping")
+
+	msg := &Message{Msg: &slack.Msg{Text: "!ping", Channel: "C1", User: "U1"}, bot: bot, team: bot.Team}
+	msg.resolve()
+	bot.runCommand(cmd, msg, "")
+
+	report := <-bot.outgoingMsgCh
+	assert.Equal(t, "C2", report.Channel)
+	assert.Contains(t, report.Text, "Command `!ping` of plugin `github.com/CapstoneLabs/slick` panicked")
+	assert.Contains(t, report.Text, "<@U1> in <#C1>")
+
+	bot.runCommand(cmd, msg, "")
+	report = <-bot.outgoingMsgCh
+	assert.True(t, strings.HasSuffix(report.Text, "it is now disabled."), report.Text)
+	assert.Len(t, bot.delListenerCh, 0, "the Listener dispatching the commands stays")
+
+	bot.runCommand(cmd, msg, "")
+	assert.Len(t, bot.outgoingMsgCh, 0, "the command is disabled")
+	assert.Equal(t, int32(2), cmd.panics)
+}
+
+func TestListenerIdentity(t *testing.T) {
+	listen := &Listener{Name: "pinger", MessageHandlerFunc: (&panickyPlugin{}).handlePing}
+	name, plugin := listen.identity()
+	assert.Equal(t, "pinger", name)
+	assert.Equal(t, "github.com/CapstoneLabs/slick", plugin)
+}
@@ -0,0 +1,280 @@
+package slick
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// configWatchDelay groups the file events of a single save, as editors
+// often write a file in a few steps.
+const configWatchDelay = 500 * time.Millisecond
+
+// ReloadConfig reads the config file again, checks it, and applies it
+// without restarting the bot: the logging, the message rates, the
+// Scheduler's time zone and the `join_channels` change right away, and
+// the plugins implementing ConfigReloader are called to pick up their
+// own sections.
+//
+// The connection and storage settings (`api_token`, `app_token`,
+// `signing_secret`, `connection_mode`, `storage`, `storage_dsn` and
+// `db_path`) need a restart: their new values are ignored, with a
+// warning. An invalid config file is rejected as a whole, and the
+// current config is kept, see CheckConfig.
+//
+// The config is reloaded when its file changes, when the bot gets a
+// SIGHUP, or with the `!reload-config` command in the `admin_channel`.
+func (bot *Bot) ReloadConfig() error {
+	bot.reloadLock.Lock()
+	defer bot.reloadLock.Unlock()
+
+	file := bot.configViper().ConfigFileUsed()
+	if file == "" {
+		return errors.New("no config file loaded")
+	}
+	if bot.configFile != "" {
+		if err := checkPermission(file); err != nil {
+			return err
+		}
+	}
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	// Check the new config on its own before replacing the current one,
+	// which the plugins read with LoadPluginConfig.
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadConfig(bytes.NewReader(content)); err != nil {
+		return fmt.Errorf("invalid config file: %s", err)
+	}
+	if err := applyConfigOverrides(v); err != nil {
+		return err
+	}
+	if err := checkConfig(v, true); err != nil {
+		return err
+	}
+	var config baseConfig
+	if err := v.Unmarshal(&config); err != nil {
+		return fmt.Errorf("invalid config file: %s", err)
+	}
+	location, err := bot.checkReload(&config.Slack)
+	if err != nil {
+		return err
+	}
+
+	bot.setConfigViper(v)
+	bot.applyConfig(config, location)
+
+	if !bot.pluginsStarted {
+		return nil
+	}
+
+	var firstErr error
+	for _, plugin := range bot.EnabledPlugins() {
+		if err := reloadPlugin(bot, plugin); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %s", pluginName(plugin), err)
+		}
+	}
+	return firstErr
+}
+
+// checkReload keeps the current values of the settings which need a
+// restart in the new Slack config. It returns the Scheduler's new time
+// zone.
+func (bot *Bot) checkReload(config *SlackConfig) (*time.Location, error) {
+	location := time.Local
+	if config.Timezone != "" {
+		loc, err := time.LoadLocation(config.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config file: %s", err)
+		}
+		location = loc
+	}
+
+	current := bot.Config()
+	restartOnly := []struct {
+		name     string
+		old, new *string
+	}{
+		{"api_token", &current.ApiToken, &config.ApiToken},
+		{"app_token", &current.AppToken, &config.AppToken},
+		{"signing_secret", &current.SigningSecret, &config.SigningSecret},
+		{"connection_mode", &current.ConnectionMode, &config.ConnectionMode},
+		{"storage", &current.Storage, &config.Storage},
+		{"storage_dsn", &current.StorageDSN, &config.StorageDSN},
+		{"db_path", &current.DBPath, &config.DBPath},
+	}
+	for _, setting := range restartOnly {
+		if *setting.new != *setting.old {
+			log.WithFields(log.Fields{
+				"Type":    "ConfigReload",
+				"Setting": setting.name,
+			}).Warn("Changing this setting needs a restart, ignoring it.")
+			*setting.new = *setting.old
+		}
+	}
+
+	return location, nil
+}
+
+// applyConfig replaces the bot's config with the checked `config`. It
+// is called with the reloadLock held.
+func (bot *Bot) applyConfig(config baseConfig, location *time.Location) {
+	bot.setConfig(config.Slack)
+	bot.Logging = config.Logging
+	bot.setupLogging()
+	bot.setRates(config.Slack.MessageRate, config.Slack.ChannelMessageRate)
+	bot.Scheduler.setLocation(location)
+	bot.setPluginConfigs(config.Plugins)
+	bot.setRoles(config.Roles)
+	bot.checkTeamConfigs(config.Teams)
+
+	// Join the new channels, if connected already
+	if bot.Myself.ID != "" {
+		bot.joinChannels()
+	}
+}
+
+// joinChannels joins the `join_channels` the bot isn't a member of.
+func (team *Team) joinChannels() {
+	for _, channelName := range team.Config().JoinChannels {
+		channel := team.GetChannelByName(channelName)
+		if channel != nil && !channel.IsMember {
+			_, err := team.Slack.JoinChannel(channel.ID)
+			if team.bot.countAPICall("channels.join", err) != nil {
+				log.WithFields(log.Fields{
+					"Type":    "JoinChannel",
+					"Channel": channelName,
+				}).WithError(err).Warn("Couldn't join the channel.")
+			}
+		}
+	}
+}
+
+// reloadPlugin calls the plugin's ReloadConfig, if it has one, and
+// recovers from its panics.
+func reloadPlugin(bot *Bot, plugin Plugin) (err error) {
+	reloader, ok := plugin.(ConfigReloader)
+	if !ok {
+		return nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.WithFields(log.Fields{
+				"Type":   "PluginReloadPanic",
+				"Plugin": pluginName(plugin),
+			}).Errorf("Plugin panicked while reloading its config: %v", r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	if err := reloader.ReloadConfig(bot); err != nil {
+		log.WithFields(log.Fields{
+			"Type":   "PluginReloadError",
+			"Plugin": pluginName(plugin),
+		}).WithError(err).Error("Plugin failed to reload its config.")
+		return err
+	}
+	return nil
+}
+
+// reloadConfigFrom calls ReloadConfig and logs the result. `source`
+// tells what triggered the reload.
+func (bot *Bot) reloadConfigFrom(source string) error {
+	err := bot.ReloadConfig()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Type":   "ConfigReloadError",
+			"Source": source,
+		}).WithError(err).Error("Error reloading config.")
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"Type":   "ConfigReload",
+		"Source": source,
+	}).Info("Config reloaded.")
+	return nil
+}
+
+// watchConfig reloads the config when its file changes, until the bot
+// shuts down.
+func (bot *Bot) watchConfig() {
+	used := bot.configViper().ConfigFileUsed()
+	if used == "" {
+		return
+	}
+	file, err := filepath.Abs(used)
+	if err != nil {
+		log.WithError(err).Error("Can't watch the config file.")
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.WithError(err).Error("Can't watch the config file.")
+		return
+	}
+	defer watcher.Close()
+
+	// Watch the directory, as editors often replace the file instead
+	// of writing to it.
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		log.WithError(err).Error("Can't watch the config file.")
+		return
+	}
+
+	var changed <-chan time.Time
+	for {
+		select {
+		case ev := <-watcher.Events:
+			if filepath.Clean(ev.Name) != file {
+				continue
+			}
+			if ev.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+				changed = time.After(configWatchDelay)
+			}
+		case err := <-watcher.Errors:
+			log.WithError(err).Error("Error watching the config file.")
+		case <-changed:
+			changed = nil
+			bot.reloadConfigFrom("file")
+		case <-bot.stopCh:
+			return
+		}
+	}
+}
+
+// listenReloadCommand registers the `!reload-config` command, which
+// only works in the `admin_channel`.
+func (bot *Bot) listenReloadCommand() {
+	bot.Command(&Command{
+		Name:        "reload-config",
+		Usage:       "reloads the config file, in the admin channel",
+		PublicOnly:  true,
+		HandlerFunc: bot.reloadCommand,
+	})
+}
+
+func (bot *Bot) reloadCommand(cmd *Command, msg *Message, args CommandArgs) {
+	if !bot.inAdminChannel(msg) {
+		return
+	}
+
+	if err := bot.reloadConfigFrom("command"); err != nil {
+		msg.ReplyMention("config not reloaded: %s", err)
+		return
+	}
+	msg.ReplyMention("config reloaded")
+}
@@ -0,0 +1,88 @@
+package slick
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type reloadingPlugin struct {
+	channels []string
+	err      error
+}
+
+func (p *reloadingPlugin) ReloadConfig(bot *Bot) error {
+	var conf struct {
+		Reloading struct {
+			Channels []string
+		}
+	}
+	if err := bot.LoadConfig(&conf); err != nil {
+		return err
+	}
+	p.channels = conf.Reloading.Channels
+	return p.err
+}
+
+func TestReloadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slick")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "config.json")
+	writeConfig := func(content string) {
+		assert.NoError(t, ioutil.WriteFile(configFile, []byte(content), 0600))
+	}
+	writeConfig(`{
+		"Slack": {"api_token": "xoxb-1", "db_path": "/tmp/one.db", "message_rate": 2},
+		"Reloading": {"Channels": ["general"]}
+	}`)
+
+	plugin := &reloadingPlugin{}
+	defer func(plugins []Plugin) { registeredPlugins = plugins }(registeredPlugins)
+	registeredPlugins = []Plugin{plugin}
+
+	bot := New(configFile)
+	bot.loadBaseConfig()
+	bot.pluginsStarted = true
+
+	writeConfig(`{
+		"Slack": {"api_token": "xoxb-2", "db_path": "/tmp/two.db", "message_rate": 3, "timezone": "America/Montreal"},
+		"Reloading": {"Channels": ["general", "random"]}
+	}`)
+	assert.NoError(t, bot.ReloadConfig())
+	assert.Equal(t, []string{"general", "random"}, plugin.channels)
+	assert.Equal(t, 3.0, bot.Config().MessageRate)
+	assert.Equal(t, "America/Montreal", bot.Scheduler.Location.String())
+	assert.Equal(t, "xoxb-1", bot.Config().ApiToken, "the connection settings need a restart")
+	assert.Equal(t, "/tmp/one.db", bot.Config().DBPath, "the storage settings need a restart")
+
+	writeConfig(`{"Slack": {"api_token": "xoxb-1", "timezone": "Nowhere/Land"}, "Reloading": {}}`)
+	assert.Error(t, bot.ReloadConfig())
+	assert.Equal(t, 3.0, bot.Config().MessageRate, "invalid configs are rejected")
+	assert.Equal(t, []string{"general", "random"}, plugin.channels, "plugins aren't reloaded with invalid configs")
+
+	writeConfig(`{"Slack": {"api_token": "xoxb-1"`)
+	assert.Error(t, bot.ReloadConfig())
+	assert.Equal(t, 3.0, bot.Config().MessageRate)
+
+	plugin.err = errors.New("no such channel")
+	writeConfig(`{"Slack": {"api_token": "xoxb-1"}, "Reloading": {"Channels": ["nope"]}}`)
+	assert.EqualError(t, bot.ReloadConfig(), "slick.reloadingPlugin: no such channel")
+	assert.Equal(t, 0.0, bot.Config().MessageRate, "the bot's config is applied even when a plugin fails")
+
+	// Each bot reads its own config file
+	otherFile := filepath.Join(dir, "other.json")
+	assert.NoError(t, ioutil.WriteFile(otherFile, []byte(`{"Slack": {"api_token": "xoxb-9", "message_rate": 9}}`), 0600))
+	other := New(otherFile)
+	other.loadBaseConfig()
+
+	plugin.err = nil
+	assert.NoError(t, bot.ReloadConfig())
+	assert.Equal(t, 0.0, bot.Config().MessageRate)
+	assert.Equal(t, 9.0, other.Config().MessageRate)
+}
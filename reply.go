@@ -1,6 +1,7 @@
 package slick
 
 import (
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -12,12 +13,34 @@ import (
 //
 type Reply struct {
 	*slack.OutgoingMessage
-	bot *Bot
+	bot  *Bot
+	team *Team
+
+	// options are posted through the Web API, see `Bot.Send`.
+	options *ReplyOptions
+
+	lock       sync.Mutex
+	attempts   int
+	sentAt     time.Time
+	err        error
+	errorFuncs []func(err error)
+}
+
+// ReplyOption tweaks an outgoing message before it is sent. See
+// `Bot.SendOutgoingMessage`.
+type ReplyOption func(*slack.OutgoingMessage)
+
+// InThread posts the message in the thread started by the message
+// with timestamp `ts`, instead of the channel's top level.
+func InThread(ts string) ReplyOption {
+	return func(msg *slack.OutgoingMessage) {
+		msg.ThreadTimestamp = ts
+	}
 }
 
 func (r *Reply) AddReaction(emoji string) *Reply {
 	r.OnAck(func(ev *slack.AckMessage) {
-		go r.bot.Slack.AddReaction(emoji, slack.NewRefToMessage(r.Channel, ev.Timestamp))
+		go r.team.Transport.AddReaction(r.bot.ctx, emoji, slack.NewRefToMessage(r.Channel, ev.Timestamp))
 	})
 	return r
 }
@@ -27,8 +50,12 @@ func (r *Reply) DeleteAfter(duration string) *Reply {
 
 	r.OnAck(func(ev *slack.AckMessage) {
 		go func() {
-			time.Sleep(timeDur)
-			r.bot.Slack.DeleteMessage(r.Channel, ev.Timestamp)
+			select {
+			case <-time.After(timeDur):
+			case <-r.bot.ctx.Done():
+				return
+			}
+			r.team.Transport.DeleteMessage(r.bot.ctx, r.Channel, ev.Timestamp)
 		}()
 	})
 
@@ -36,8 +63,10 @@ func (r *Reply) DeleteAfter(duration string) *Reply {
 }
 
 func (r *Reply) ListenReaction(reactListen *ReactionListener) {
+	plugin := callerPlugin()
 	r.OnAck(func(ackEv *slack.AckMessage) {
 		listen := reactListen.newListener()
+		listen.plugin = plugin
 		listen.EventHandlerFunc = func(_ *Listener, event interface{}) {
 			re := ParseReactionEvent(event)
 			if re == nil {
@@ -48,7 +77,7 @@ func (r *Reply) ListenReaction(reactListen *ReactionListener) {
 				return
 			}
 
-			if re.User == r.bot.Myself.ID {
+			if re.User == r.team.Myself.ID {
 				return
 			}
 
@@ -62,6 +91,7 @@ func (r *Reply) ListenReaction(reactListen *ReactionListener) {
 
 			reactListen.HandlerFunc(reactListen, re)
 		}
+		listen.team = r.team
 		r.bot.Listen(listen)
 	})
 }
@@ -71,14 +101,18 @@ func (r *Reply) ListenReaction(reactListen *ReactionListener) {
 // catch the confirmation message with the message_id.
 //
 // With the message_id, you can modify your reply, add reactions to it
-// or delete it.
+// or delete it. `f` isn't called if the message can't be sent, see
+// OnError.
 func (r *Reply) OnAck(f func(ack *slack.AckMessage)) {
 	r.bot.Listen(&Listener{
-		ListenDuration: 20 * time.Second,
+		team:           r.team,
+		ListenDuration: ackTimeout,
 		EventHandlerFunc: func(subListen *Listener, event interface{}) {
 			if ev, ok := event.(*slack.AckMessage); ok {
 				if ev.ReplyTo == r.ID {
-					f(ev)
+					if ev.Ok {
+						f(ev)
+					}
 					subListen.Close()
 				}
 			}
@@ -90,6 +124,43 @@ func (r *Reply) OnAck(f func(ack *slack.AckMessage)) {
 	})
 }
 
+// OnError calls `f` if the message can't be sent, like when the
+// channel doesn't exist, or Slack still rate limits the bot after a
+// few retries. Call it immediately after sending, like OnAck.
+func (r *Reply) OnError(f func(err error)) *Reply {
+	r.lock.Lock()
+	err := r.err
+	if err == nil {
+		r.errorFuncs = append(r.errorFuncs, f)
+	}
+	r.lock.Unlock()
+
+	if err != nil {
+		go f(err)
+	}
+	return r
+}
+
+// fail calls the OnError callbacks, and closes the OnAck listeners
+// with a failed acknowledgement.
+func (r *Reply) fail(err error) {
+	r.lock.Lock()
+	r.err = err
+	errorFuncs := r.errorFuncs
+	r.errorFuncs = nil
+	r.lock.Unlock()
+
+	go func() {
+		for _, f := range errorFuncs {
+			f(err)
+		}
+	}()
+
+	ack := &slack.AckMessage{ReplyTo: r.ID, Text: r.Text}
+	ack.Error = &slack.RTMError{Msg: err.Error()}
+	go r.team.injectEvent(slack.RTMEvent{Type: "ack", Data: ack})
+}
+
 // Updateable returns an instance of UpdateableReply, which has a few
 // methods to update a message after the fact.  It is safe to use in
 // different goroutines no matter when.
@@ -120,9 +191,37 @@ func (r *Reply) Listen(listen *Listener) error {
 		log.Println("Reply.Listen(): Invalid Listener: ", err)
 		return err
 	}
+	listen.plugin = callerPlugin()
 
 	r.OnAck(func(ev *slack.AckMessage) {
 		listen.replyAck = ev
+		listen.team = r.team
+		r.bot.addListener(listen)
+	})
+
+	return nil
+}
+
+// ListenThread is like Listen, but only dispatches the messages
+// posted in the thread of this Reply: the thread it was sent to, or
+// the one starting under it.
+func (r *Reply) ListenThread(listen *Listener) error {
+	listen.Bot = r.bot
+
+	err := listen.checkParams()
+	if err != nil {
+		log.Println("Reply.ListenThread(): Invalid Listener: ", err)
+		return err
+	}
+	listen.plugin = callerPlugin()
+
+	r.OnAck(func(ev *slack.AckMessage) {
+		listen.replyAck = ev
+		listen.team = r.team
+		listen.InThread = r.ThreadTimestamp
+		if listen.InThread == "" {
+			listen.InThread = ev.Timestamp
+		}
 		r.bot.addListener(listen)
 	})
 
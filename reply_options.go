@@ -0,0 +1,266 @@
+package slick
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// Special mentions, notifying the active members of a channel, or all
+// of them.
+const (
+	MentionHere    = "<!here>"
+	MentionChannel = "<!channel>"
+)
+
+// MentionUser returns the mention of a user, by ID. Slack shows it
+// with the user's current display name, and notifies them.
+func MentionUser(userID string) string {
+	return "<@" + userID + ">"
+}
+
+// LinkChannel returns a link to a channel, by ID.
+func LinkChannel(channelID string) string {
+	return "<#" + channelID + ">"
+}
+
+// ReplyOptions describes a message richer than text, sent through the
+// Web API with `Bot.Send` or `Message.ReplyWith`. Build it with
+// NewReply and its methods:
+//
+//	msg.ReplyWith(slick.NewReply("Deployed %s", version).
+//		Attach("good", changelog).
+//		Unfurl(false))
+type ReplyOptions struct {
+	Text        string
+	Attachments []slack.Attachment
+	Blocks      []Block
+
+	// UnfurlLinks and UnfurlMedia control the previews of the links,
+	// left to Slack when nil.
+	UnfurlLinks *bool
+	UnfurlMedia *bool
+
+	// Ephemeral is the ID of the only user who sees the message. Such
+	// messages can't be updated, deleted or reacted to.
+	Ephemeral string
+
+	ThreadTimestamp string
+}
+
+// NewReply returns ReplyOptions with the text, formatted like
+// `Message.Reply`.
+func NewReply(text string, v ...interface{}) *ReplyOptions {
+	return &ReplyOptions{Text: Format(text, v...)}
+}
+
+// Attach adds an attachment with a colored bar: "good", "warning",
+// "danger" or a hex color like "#439FE0".
+func (o *ReplyOptions) Attach(color, text string) *ReplyOptions {
+	return o.AddAttachment(slack.Attachment{Color: color, Text: text, Fallback: text})
+}
+
+// AddAttachment adds an attachment, with a title, fields, or the
+// other settings of `slack.Attachment`.
+func (o *ReplyOptions) AddAttachment(attachment slack.Attachment) *ReplyOptions {
+	o.Attachments = append(o.Attachments, attachment)
+	return o
+}
+
+// WithBlocks adds Block Kit blocks. The text becomes their fallback,
+// shown in notifications.
+func (o *ReplyOptions) WithBlocks(blocks ...Block) *ReplyOptions {
+	o.Blocks = append(o.Blocks, blocks...)
+	return o
+}
+
+// Unfurl shows or hides the previews of the links and media.
+func (o *ReplyOptions) Unfurl(unfurl bool) *ReplyOptions {
+	o.UnfurlLinks = &unfurl
+	o.UnfurlMedia = &unfurl
+	return o
+}
+
+// EphemeralTo only shows the message to the user `userID`, in the
+// channel it is sent to.
+func (o *ReplyOptions) EphemeralTo(userID string) *ReplyOptions {
+	o.Ephemeral = userID
+	return o
+}
+
+// InThread posts the message in the thread of the message with
+// timestamp `ts`.
+func (o *ReplyOptions) InThread(ts string) *ReplyOptions {
+	o.ThreadTimestamp = ts
+	return o
+}
+
+// Here mentions the active members of the channel, before the text.
+func (o *ReplyOptions) Here() *ReplyOptions {
+	return o.prefix(MentionHere)
+}
+
+// AtChannel mentions all the members of the channel, before the text.
+func (o *ReplyOptions) AtChannel() *ReplyOptions {
+	return o.prefix(MentionChannel)
+}
+
+// Mention mentions a user, by ID, before the text.
+func (o *ReplyOptions) Mention(userID string) *ReplyOptions {
+	return o.prefix(MentionUser(userID))
+}
+
+func (o *ReplyOptions) prefix(mention string) *ReplyOptions {
+	if o.Text == "" {
+		o.Text = mention
+	} else {
+		o.Text = mention + " " + o.Text
+	}
+	return o
+}
+
+// Send posts a message built with ReplyOptions to the channel `to`,
+// through the Web API whatever the Transport. It is paced and
+// acknowledged like `SendOutgoingMessage`, and returns a Reply which
+// can be listened on.
+func (team *Team) Send(to string, opts *ReplyOptions) *Reply {
+	outMsg := team.Transport.NewOutgoingMessage(opts.Text, to)
+	outMsg.ThreadTimestamp = opts.ThreadTimestamp
+	return team.queueReply(outMsg, opts)
+}
+
+// queueReply queues `outMsg` to be posted through the Web API, with the
+// attachments, blocks and settings of `opts`. The channel, text and
+// thread are those of `outMsg`.
+func (team *Team) queueReply(outMsg *slack.OutgoingMessage, opts *ReplyOptions) *Reply {
+	reply := &Reply{OutgoingMessage: outMsg, bot: team.bot, team: team, options: opts}
+	team.outgoingMsgCh <- reply
+	return reply
+}
+
+// postReply posts a Reply queued by Send or SendBlocks, and
+// acknowledges it like the Transport does. The next Replies of its
+// channel wait for the acknowledgement, see `outgoing.posted`.
+func (team *Team) postReply(r *Reply) {
+	opts := r.options
+	values := url.Values{
+		"channel": {r.Channel},
+		"text":    {r.Text},
+	}
+	if r.ThreadTimestamp != "" {
+		values.Set("thread_ts", r.ThreadTimestamp)
+	}
+	if r.ThreadBroadcast {
+		values.Set("reply_broadcast", "true")
+	}
+	if opts.UnfurlLinks != nil {
+		values.Set("unfurl_links", strconv.FormatBool(*opts.UnfurlLinks))
+	}
+	if opts.UnfurlMedia != nil {
+		values.Set("unfurl_media", strconv.FormatBool(*opts.UnfurlMedia))
+	}
+
+	method := "chat.postMessage"
+	if opts.Ephemeral != "" {
+		method = "chat.postEphemeral"
+		values.Set("user", opts.Ephemeral)
+	}
+
+	var resp struct {
+		Timestamp        string `json:"ts"`
+		MessageTimestamp string `json:"message_ts"`
+	}
+	var err error
+	if len(opts.Attachments) != 0 {
+		err = setJSON(values, "attachments", opts.Attachments)
+	}
+	if err == nil && len(opts.Blocks) != 0 {
+		err = setJSON(values, "blocks", opts.Blocks)
+	}
+	if err == nil {
+		ctx, cancel := context.WithTimeout(team.bot.Context(), ackTimeout)
+		err = team.callWebAPI(ctx, method, values, &resp)
+		cancel()
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Type":    "PostMessageError",
+			"Channel": r.Channel,
+		}).WithError(err).Error("Error posting message.")
+
+		team.injectEvent(slack.RTMEvent{Type: "ack_error", Data: &slack.AckErrorEvent{ErrorObj: &SendError{ReplyTo: r.ID, Err: err}}})
+		return
+	}
+
+	ack := &slack.AckMessage{ReplyTo: r.ID, Timestamp: resp.Timestamp, Text: r.Text}
+	if ack.Timestamp == "" {
+		ack.Timestamp = resp.MessageTimestamp
+	}
+	ack.Ok = true
+	team.injectEvent(slack.RTMEvent{Type: "ack", Data: ack})
+}
+
+// setJSON sets `key` to `v` marshalled as JSON.
+func setJSON(values url.Values, key string, v interface{}) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	values.Set(key, string(content))
+	return nil
+}
+
+// ReplyWith replies with a message built with ReplyOptions to the
+// source the message came from.
+func (msg *Message) ReplyWith(opts *ReplyOptions) *Reply {
+	return msg.Workspace().Send(msg.replyTo(), opts)
+}
+
+// ReplyEphemeral replies with a message only the author of the message
+// sees.
+func (msg *Message) ReplyEphemeral(text string, v ...interface{}) *Reply {
+	return msg.ReplyWith(NewReply(text, v...).EphemeralTo(msg.User))
+}
+
+// notifyColors maps the colors of the HipChat-era Notify to attachment
+// colors.
+var notifyColors = map[string]string{
+	"green":  "good",
+	"yellow": "warning",
+	"red":    "danger",
+	"purple": "#7d3c98",
+	"gray":   "#9e9e9e",
+	"random": "",
+}
+
+// Notify posts `msg` in a colored attachment to the channel `room`,
+// given by name or ID. `color` is "green", "yellow", "red", "purple",
+// "gray", or an attachment color. With `notify`, the message is also
+// the text, so it shows in the notifications. `format` is kept for
+// compatibility: the text is always formatted by Slack.
+func (bot *Bot) Notify(room, color, format, msg string, notify bool) error {
+	channel := bot.GetChannelByName(room)
+	if channel == nil {
+		if c, ok := bot.Channels.Get(room); ok {
+			channel = &c
+		}
+	}
+	if channel == nil {
+		return fmt.Errorf("notify: unknown channel %q", room)
+	}
+
+	if mapped, ok := notifyColors[color]; ok {
+		color = mapped
+	}
+	opts := &ReplyOptions{}
+	if notify {
+		opts.Text = msg
+	}
+	bot.Send(channel.ID, opts.Attach(color, msg))
+	return nil
+}
@@ -0,0 +1,137 @@
+package slick
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendReplyOptions(t *testing.T) {
+	posted := make(chan url.Values, 1)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		r.Form.Set("method", r.URL.Path)
+		posted <- r.Form
+		if r.URL.Path == "/chat.postEphemeral" {
+			fmt.Fprint(w, `{"ok":true,"message_ts":"1234.0001"}`)
+			return
+		}
+		fmt.Fprint(w, `{"ok":true,"channel":"C1","ts":"1234.5678"}`)
+	}))
+	defer api.Close()
+
+	defaultAPI := slack.SLACK_API
+	slack.SLACK_API = api.URL + "/"
+	defer func() { slack.SLACK_API = defaultAPI }()
+
+	bot := New("")
+	bot.Transport = newFakeTransport()
+	bot.Channels.Set(Channel{ID: "C1", Name: "deploys", IsChannel: true})
+	go bot.replyHandler()
+
+	expectAck := func(reply *Reply, ts string) url.Values {
+		select {
+		case event := <-bot.internalEvents:
+			ack := event.Data.(*slack.AckMessage)
+			assert.Equal(t, reply.ID, ack.ReplyTo)
+			assert.Equal(t, ts, ack.Timestamp)
+			// The event loop lets the next post of the channel go
+			bot.Team.handleRTMEvent(&event)
+		case <-time.After(time.Second):
+			t.Fatal("reply not acknowledged")
+		}
+		return <-posted
+	}
+
+	reply := bot.Send("C1", NewReply("deployed %s", "v2").Here().Attach("good", "all green").Unfurl(false))
+	values := expectAck(reply, "1234.5678")
+	assert.Equal(t, "/chat.postMessage", values.Get("method"))
+	assert.Equal(t, "<!here> deployed v2", values.Get("text"))
+	assert.JSONEq(t, `[{"color":"good","text":"all green","fallback":"all green"}]`, values.Get("attachments"))
+	assert.Equal(t, "false", values.Get("unfurl_links"))
+	assert.Empty(t, values.Get("blocks"))
+
+	reply = bot.Send("C1", NewReply("only for you").EphemeralTo("U1"))
+	values = expectAck(reply, "1234.0001")
+	assert.Equal(t, "/chat.postEphemeral", values.Get("method"))
+	assert.Equal(t, "U1", values.Get("user"))
+	assert.Empty(t, values.Get("attachments"))
+	assert.Empty(t, values.Get("unfurl_links"), "unfurling is left to Slack")
+
+	// Notify finds rooms by name, and maps the legacy colors
+	assert.NoError(t, bot.Notify("deploys", "purple", "text", "locked", false))
+	values = <-posted
+	assert.Equal(t, "C1", values.Get("channel"))
+	assert.Empty(t, values.Get("text"))
+	assert.JSONEq(t, `[{"color":"#7d3c98","text":"locked","fallback":"locked"}]`, values.Get("attachments"))
+
+	assert.Error(t, bot.Notify("nowhere", "red", "text", "locked", true))
+}
+
+func TestMentions(t *testing.T) {
+	assert.Equal(t, "<@U1>", MentionUser("U1"))
+	assert.Equal(t, "<#C1>", LinkChannel("C1"))
+	assert.Equal(t, "<!channel> <@U1> ping", NewReply("ping").Mention("U1").AtChannel().Text)
+	assert.Equal(t, "<!here>", NewReply("").Here().Text)
+}
+
+func TestSendRetriesRateLimited(t *testing.T) {
+	calls := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"ok":false,"error":"ratelimited"}`)
+			return
+		}
+		fmt.Fprint(w, `{"ok":true,"channel":"C1","ts":"1234.5678"}`)
+	}))
+	defer api.Close()
+
+	defaultAPI := slack.SLACK_API
+	slack.SLACK_API = api.URL + "/"
+	defer func() { slack.SLACK_API = defaultAPI }()
+
+	bot := New("")
+	bot.Transport = newFakeTransport()
+	go bot.replyHandler()
+	defer close(bot.stopCh)
+
+	nextEvent := func() slack.RTMEvent {
+		select {
+		case event := <-bot.internalEvents:
+			return event
+		case <-time.After(3 * time.Second):
+			t.Fatal("no answer from the Web API")
+		}
+		return slack.RTMEvent{}
+	}
+
+	reply := bot.Send("C1", NewReply("deployed"))
+	event := nextEvent()
+	if assert.Equal(t, "ack_error", event.Type) {
+		err := event.Data.(*slack.AckErrorEvent).ErrorObj
+		delay, ok := retryDelay(err, 1)
+		assert.True(t, ok, "429 responses are retried")
+		assert.Equal(t, time.Second, delay, "Retry-After is honored")
+	}
+
+	// The event loop hands the error to the outgoing queue
+	bot.Team.handleRTMEvent(&event)
+	event = nextEvent()
+	if assert.Equal(t, "ack", event.Type) {
+		assert.Equal(t, reply.ID, event.Data.(*slack.AckMessage).ReplyTo)
+	}
+	assert.Equal(t, 2, calls)
+
+	_, ok := retryDelay(errors.New("chat.postMessage: ratelimited"), 1)
+	assert.True(t, ok, "the error code of the Web API is matched")
+}
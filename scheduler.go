@@ -0,0 +1,610 @@
+package slick
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const schedulerNamespace = "scheduler"
+
+// CatchUpPolicy decides what happens to the runs of a Job missed while
+// the bot was down.
+type CatchUpPolicy int
+
+const (
+	// SkipMissed forgets about missed runs, and waits for the next
+	// one.
+	SkipMissed CatchUpPolicy = iota
+	// RunMissed runs the job once at startup if any run was missed.
+	RunMissed
+)
+
+// Job is a unit of work run by the Scheduler, either recurring (with
+// `Spec`) or one-shot (with `At`).
+type Job struct {
+	// ID identifies the job in `!jobs`. It is the Name of recurring
+	// jobs, and generated for one-shot jobs.
+	ID string
+	// Name describes the job.
+	Name string
+	// Team is the name of the team whose `!jobs` lists the job, empty
+	// for the primary team and the bot's own jobs.
+	Team string `json:",omitempty"`
+
+	// Spec is the schedule of recurring jobs, see `ParseSchedule`.
+	Spec string
+	// At is the run time of a one-shot job.
+	At time.Time
+	// Location is the time zone in which `Spec` is evaluated. It
+	// defaults to the Scheduler's.
+	Location *time.Location `json:"-"`
+
+	CatchUp CatchUpPolicy
+
+	// Func runs recurring jobs, and one-shot jobs created with
+	// `Scheduler.Schedule`.
+	Func func(*Job) `json:"-"`
+
+	// Kind names the handler, registered with `Scheduler.HandleFunc`,
+	// which runs a persisted one-shot job. See `Scheduler.At`.
+	Kind string
+	// Data is the JSON payload of a persisted one-shot job, decoded
+	// with `Job.Decode`.
+	Data json.RawMessage `json:",omitempty"`
+
+	Next    time.Time
+	LastRun time.Time
+
+	schedule Schedule
+}
+
+// Decode unmarshals the payload of a persisted one-shot job into `v`.
+func (job *Job) Decode(v interface{}) error {
+	if len(job.Data) == 0 {
+		return errors.New("job has no data")
+	}
+	return json.Unmarshal(job.Data, v)
+}
+
+func (job *Job) isPersisted() bool {
+	return job.Kind != ""
+}
+
+func (job *Job) describe() string {
+	if job.Spec != "" {
+		return fmt.Sprintf("`%s`", job.Spec)
+	}
+	return "once"
+}
+
+// Scheduler runs jobs on a cron schedule or at a given time. Recurring
+// jobs are registered by plugins at startup, while one-shot jobs
+// created with `At` are persisted, and survive restarts.
+type Scheduler struct {
+	// Location is the default time zone of the jobs, from the
+	// `timezone` Slack config. It defaults to the system's.
+	Location *time.Location
+
+	bot      *Bot
+	lock     sync.Mutex
+	jobs     map[string]*Job
+	handlers map[string]func(*Job)
+	loaded   bool
+	started  bool
+	stopped  bool
+	running  sync.WaitGroup
+	wakeup   chan bool
+	stop     chan bool
+	now      func() time.Time
+}
+
+func newScheduler(bot *Bot) *Scheduler {
+	return &Scheduler{
+		Location: time.Local,
+		bot:      bot,
+		jobs:     make(map[string]*Job),
+		handlers: make(map[string]func(*Job)),
+		wakeup:   make(chan bool, 1),
+		stop:     make(chan bool),
+		now:      time.Now,
+	}
+}
+
+// setLocation changes the default time zone of the jobs scheduled
+// afterwards.
+func (s *Scheduler) setLocation(location *time.Location) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Location = location
+}
+
+// Cron registers a recurring job named `name`, running `f` on the
+// schedule `spec` (see `ParseSchedule`) in the Scheduler's time zone.
+func (s *Scheduler) Cron(name, spec string, f func()) (*Job, error) {
+	return s.Schedule(&Job{
+		ID:   name,
+		Name: name,
+		Spec: spec,
+		Func: func(*Job) { f() },
+	})
+}
+
+// HandleFunc registers the handler running the persisted jobs of type
+// `kind`. Plugins must register their handlers in `InitPlugin`, for
+// jobs persisted before a restart to find them.
+func (s *Scheduler) HandleFunc(kind string, f func(*Job)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.handlers[kind] = f
+}
+
+// At schedules a one-shot job run by the `kind` handler at `when`,
+// with `data` JSON encoded in `Job.Data`. The job is persisted: if the
+// bot is down at `when`, it runs at the next startup.
+func (s *Scheduler) At(when time.Time, kind, name string, data interface{}) (*Job, error) {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Schedule(&Job{
+		Name:    name,
+		At:      when,
+		Kind:    kind,
+		Data:    content,
+		CatchUp: RunMissed,
+	})
+}
+
+// Schedule adds a job. Recurring jobs need a `Spec` and a `Func`;
+// one-shot jobs need `At`, and either a `Func` or a `Kind`, in which
+// case they are persisted.
+func (s *Scheduler) Schedule(job *Job) (*Job, error) {
+	if job.Spec == "" && job.At.IsZero() {
+		return nil, errors.New("job needs a Spec or an At time")
+	}
+	if job.Spec != "" && !job.At.IsZero() {
+		return nil, errors.New("job can't have both a Spec and an At time")
+	}
+	if job.Func == nil && job.Kind == "" {
+		return nil, errors.New("job needs a Func or a Kind")
+	}
+	if job.Spec != "" && job.Kind != "" {
+		return nil, errors.New("recurring jobs can't be persisted, use a Func")
+	}
+
+	if job.Spec != "" {
+		schedule, err := ParseSchedule(job.Spec)
+		if err != nil {
+			return nil, err
+		}
+		job.schedule = schedule
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Persisted jobs own their IDs, even before the Scheduler starts
+	s.loadPersisted()
+
+	if job.Location == nil {
+		job.Location = s.Location
+	}
+	if job.ID == "" {
+		job.ID = s.newID()
+	}
+	if _, exists := s.jobs[job.ID]; exists {
+		return nil, fmt.Errorf("job %q already scheduled", job.ID)
+	}
+
+	if job.isPersisted() {
+		if err := s.saveJob(job); err != nil {
+			return nil, err
+		}
+	}
+	if s.started {
+		s.planJob(job, s.now())
+		s.notify()
+	}
+	s.jobs[job.ID] = job
+
+	return job, nil
+}
+
+// Cancel removes a job. Recurring jobs are registered again at the
+// next startup.
+func (s *Scheduler) Cancel(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return fmt.Errorf("no job with ID %q", id)
+	}
+
+	delete(s.jobs, id)
+	if job.isPersisted() {
+		if err := s.deleteJob(id); err != nil {
+			return err
+		}
+	}
+	s.notify()
+
+	return nil
+}
+
+// job returns a copy of the job `id`.
+func (s *Scheduler) job(id string) (Job, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Jobs returns a copy of the scheduled jobs, by next run time.
+func (s *Scheduler) Jobs() []Job {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var jobs []Job
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Next.Before(jobs[j].Next) })
+	return jobs
+}
+
+// start loads the persisted jobs, applies the catch-up policies, and
+// launches the scheduling loop.
+func (s *Scheduler) start() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	s.loadPersisted()
+
+	for _, job := range s.jobs {
+		if job.schedule != nil {
+			job.LastRun = s.loadLastRun(job.ID)
+		}
+		s.planJob(job, now)
+	}
+
+	s.started = true
+	go s.loop()
+}
+
+// loadPersisted adds the persisted jobs, once the storage is open. Must
+// be called with the lock held.
+func (s *Scheduler) loadPersisted() {
+	if s.loaded || s.store() == nil {
+		return
+	}
+	s.loaded = true
+
+	persisted, err := s.loadJobs()
+	if err != nil {
+		log.WithError(err).Error("Couldn't load the scheduled jobs.")
+	}
+	for _, job := range persisted {
+		if _, exists := s.jobs[job.ID]; exists {
+			log.WithFields(log.Fields{
+				"Type": "Scheduler",
+				"Job":  job.ID,
+			}).Warn("A job with the ID of a persisted job is scheduled already, dropping the persisted one.")
+			continue
+		}
+		job.Location = s.Location
+		s.jobs[job.ID] = job
+	}
+}
+
+// planJob computes the next run of the job, applying its CatchUp
+// policy to runs missed before `now`.
+func (s *Scheduler) planJob(job *Job, now time.Time) {
+	if job.schedule == nil {
+		job.Next = job.At
+		if job.Next.Before(now) && job.CatchUp == SkipMissed {
+			job.Next = time.Time{}
+		}
+		return
+	}
+
+	job.Next = job.schedule.Next(now.In(job.Location))
+	if !job.LastRun.IsZero() && job.CatchUp == RunMissed {
+		if missed := job.schedule.Next(job.LastRun.In(job.Location)); missed.Before(now) {
+			job.Next = now
+		}
+	}
+}
+
+func (s *Scheduler) loop() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		s.lock.Lock()
+		now := s.now()
+		var due []*Job
+		var next time.Time
+		for id, job := range s.jobs {
+			if job.Next.IsZero() {
+				// Missed one-shot job, skipped
+				delete(s.jobs, id)
+				if job.isPersisted() {
+					s.deleteJob(id)
+				}
+				continue
+			}
+			if !job.Next.After(now) {
+				due = append(due, job)
+				continue
+			}
+			if next.IsZero() || job.Next.Before(next) {
+				next = job.Next
+			}
+		}
+
+		for _, job := range due {
+			s.runJob(job, now)
+			if !job.Next.IsZero() && (next.IsZero() || job.Next.Before(next)) {
+				next = job.Next
+			}
+		}
+		s.lock.Unlock()
+
+		wait := time.Hour
+		if !next.IsZero() {
+			wait = next.Sub(now)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-timer.C:
+		case <-s.wakeup:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// runJob launches a due job, and plans its next run. Must be called
+// with the lock held.
+func (s *Scheduler) runJob(job *Job, now time.Time) {
+	job.LastRun = now
+
+	handler := job.Func
+	if handler == nil {
+		handler = s.handlers[job.Kind]
+	}
+
+	if job.schedule != nil {
+		job.Next = job.schedule.Next(now.In(job.Location))
+		s.saveLastRun(job.ID, now)
+	} else {
+		delete(s.jobs, job.ID)
+		if job.isPersisted() {
+			s.deleteJob(job.ID)
+		}
+	}
+
+	if handler == nil {
+		log.WithFields(log.Fields{
+			"Type": "SchedulerNoHandler",
+			"Job":  job.ID,
+			"Kind": job.Kind,
+		}).Error("No handler registered for scheduled job.")
+		return
+	}
+
+	run := *job
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				log.WithFields(log.Fields{
+					"Type": "SchedulerPanic",
+					"Job":  run.ID,
+				}).Errorf("Scheduled job panicked: %v", r)
+			}
+		}()
+		handler(&run)
+	}()
+}
+
+// shutdown stops the Scheduler's loop, and waits for the running jobs
+// until `ctx` is done.
+func (s *Scheduler) shutdown(ctx context.Context) error {
+	s.lock.Lock()
+	if s.started && !s.stopped {
+		close(s.stop)
+	}
+	s.stopped = true
+	s.lock.Unlock()
+
+	done := make(chan bool)
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// notify wakes the loop up, to account for added or removed jobs.
+func (s *Scheduler) notify() {
+	select {
+	case s.wakeup <- true:
+	default:
+	}
+}
+
+// newID returns a random ID, not used by the scheduled jobs. Must be
+// called with the lock held.
+func (s *Scheduler) newID() string {
+	for {
+		var buf [4]byte
+		if _, err := rand.Read(buf[:]); err != nil {
+			panic(err)
+		}
+		id := hex.EncodeToString(buf[:])
+		if _, exists := s.jobs[id]; !exists {
+			return id
+		}
+	}
+}
+
+// store returns the Scheduler's storage namespace, or nil when the bot
+// has no storage.
+func (s *Scheduler) store() Store {
+	if s.bot.StorageBackend == nil {
+		return nil
+	}
+	return s.bot.Storage(schedulerNamespace)
+}
+
+func (s *Scheduler) saveJob(job *Job) error {
+	store := s.store()
+	if store == nil {
+		return nil
+	}
+	return store.Put("job:"+job.ID, job)
+}
+
+func (s *Scheduler) deleteJob(id string) error {
+	store := s.store()
+	if store == nil {
+		return nil
+	}
+	return store.Delete("job:" + id)
+}
+
+func (s *Scheduler) loadJobs() ([]*Job, error) {
+	var jobs []*Job
+
+	store := s.store()
+	if store == nil {
+		return nil, nil
+	}
+
+	err := store.List("job:", func(key string, value StoredValue) error {
+		job := &Job{}
+		if err := value.Decode(job); err != nil {
+			log.WithError(err).Warnf("Dropping unreadable scheduled job %q", key)
+			return nil
+		}
+		jobs = append(jobs, job)
+		return nil
+	})
+
+	return jobs, err
+}
+
+func (s *Scheduler) saveLastRun(id string, when time.Time) {
+	store := s.store()
+	if store == nil {
+		return
+	}
+	if err := store.Put("lastrun:"+id, when); err != nil {
+		log.WithError(err).Warnf("Couldn't save the last run of job %q", id)
+	}
+}
+
+func (s *Scheduler) loadLastRun(id string) (lastRun time.Time) {
+	store := s.store()
+	if store == nil {
+		return
+	}
+	store.Get("lastrun:"+id, &lastRun)
+	return
+}
+
+// PermissionManageJobs is needed for the `!jobs` command. Until the
+// config defines roles, the command only works in the `admin_channel`.
+const PermissionManageJobs = "jobs.manage"
+
+// listenJobsCommand registers the `!jobs` command.
+func (s *Scheduler) listenJobsCommand() {
+	s.bot.Command(&Command{
+		Name: "jobs",
+		Args: []CommandArg{
+			{Name: "action", Type: ArgWord, Optional: true},
+			{Name: "id", Type: ArgWord, Optional: true},
+		},
+		Usage:      "lists the scheduled jobs, or cancels one with `!jobs cancel <id>`",
+		Permission: PermissionManageJobs,
+		// Jobs of the core and of the plugins can be cancelled
+		Restricted:  true,
+		HandlerFunc: s.jobsCommand,
+	})
+}
+
+func (s *Scheduler) jobsCommand(cmd *Command, msg *Message, args CommandArgs) {
+	switch args.String("action") {
+	case "":
+		var jobs []Job
+		for _, job := range s.Jobs() {
+			if job.Team == msg.Workspace().Name {
+				jobs = append(jobs, job)
+			}
+		}
+		if len(jobs) == 0 {
+			msg.Reply("No jobs scheduled.")
+			return
+		}
+
+		out := []string{"Scheduled jobs:"}
+		for _, job := range jobs {
+			line := fmt.Sprintf("• `%s` %s: %s, next run %s", job.ID, job.Name, job.describe(), job.Next.In(job.Location).Format("Mon Jan 2 15:04 MST"))
+			if !job.LastRun.IsZero() {
+				line += fmt.Sprintf(", last run %s", job.LastRun.In(job.Location).Format("Mon Jan 2 15:04 MST"))
+			}
+			out = append(out, line)
+		}
+		msg.Reply(strings.Join(out, "\n"))
+
+	case "cancel":
+		if !args.Has("id") {
+			msg.ReplyMention("which job? usage: `!jobs cancel <id>`")
+			return
+		}
+		if job, ok := s.job(args.String("id")); ok && job.Team != msg.Workspace().Name {
+			msg.ReplyMention("no job with ID %q", args.String("id"))
+			return
+		}
+		if err := s.Cancel(args.String("id")); err != nil {
+			msg.ReplyMention("%s", err)
+			return
+		}
+		msg.ReplyMention("job `%s` cancelled", args.String("id"))
+
+	default:
+		msg.ReplyMention("usage: `%s`", cmd.Synopsis())
+	}
+}
@@ -0,0 +1,132 @@
+package slick
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestScheduler(storage StorageBackend) *Scheduler {
+	s := newScheduler(&Bot{StorageBackend: storage})
+	s.Location = time.UTC
+	return s
+}
+
+func TestSchedulerRunsOneShotJob(t *testing.T) {
+	s := newTestScheduler(NewMemoryStorage())
+	s.start()
+	defer close(s.stop)
+
+	done := make(chan *Job, 1)
+	job, err := s.Schedule(&Job{
+		Name: "test",
+		At:   time.Now().Add(20 * time.Millisecond),
+		Func: func(job *Job) { done <- job },
+	})
+	assert.NoError(t, err)
+	assert.Len(t, s.Jobs(), 1)
+
+	select {
+	case ran := <-done:
+		assert.Equal(t, job.ID, ran.ID)
+	case <-time.After(time.Second):
+		t.Fatal("job didn't run")
+	}
+	assert.Len(t, s.Jobs(), 0)
+}
+
+func TestSchedulerRunsMissedPersistedJobs(t *testing.T) {
+	storage := NewMemoryStorage()
+
+	s := newTestScheduler(storage)
+	s.start()
+	job, err := s.At(time.Now().Add(time.Hour), "remind", "reminder", map[string]string{"text": "hello"})
+	assert.NoError(t, err)
+	close(s.stop)
+
+	// Restart, after the job was due
+	s = newTestScheduler(storage)
+	s.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	done := make(chan *Job, 1)
+	s.HandleFunc("remind", func(job *Job) { done <- job })
+	s.start()
+	defer close(s.stop)
+
+	select {
+	case ran := <-done:
+		assert.Equal(t, job.ID, ran.ID)
+		var data map[string]string
+		assert.NoError(t, ran.Decode(&data))
+		assert.Equal(t, "hello", data["text"])
+	case <-time.After(time.Second):
+		t.Fatal("missed job didn't run")
+	}
+}
+
+func TestSchedulerCatchUpPolicies(t *testing.T) {
+	s := newScheduler(&Bot{})
+	s.Location = time.UTC
+	now := time.Date(2019, time.March, 6, 10, 30, 0, 0, time.UTC)
+	lastRun := time.Date(2019, time.March, 5, 12, 0, 0, 0, time.UTC)
+
+	skip, err := s.Schedule(&Job{ID: "skip", Spec: "0 12 * * *", Func: func(*Job) {}})
+	assert.NoError(t, err)
+	skip.LastRun = lastRun
+	s.planJob(skip, now)
+	assert.Equal(t, time.Date(2019, time.March, 6, 12, 0, 0, 0, time.UTC), skip.Next)
+
+	run, err := s.Schedule(&Job{ID: "run", Spec: "0 12 * * *", CatchUp: RunMissed, Func: func(*Job) {}})
+	assert.NoError(t, err)
+	run.LastRun = lastRun.Add(-24 * time.Hour)
+	s.planJob(run, now)
+	assert.Equal(t, now, run.Next)
+
+	_, err = s.Schedule(&Job{ID: "run", Spec: "0 12 * * *", Func: func(*Job) {}})
+	assert.Error(t, err)
+	assert.NoError(t, s.Cancel("run"))
+	assert.Error(t, s.Cancel("run"))
+}
+
+func TestSchedulerJobIDs(t *testing.T) {
+	storage := NewMemoryStorage()
+	s := newTestScheduler(storage)
+	frozen := time.Date(2019, time.March, 6, 10, 30, 0, 0, time.UTC)
+	s.now = func() time.Time { return frozen }
+
+	first, err := s.At(frozen.Add(time.Hour), "remind", "first", nil)
+	assert.NoError(t, err)
+	second, err := s.At(frozen.Add(time.Hour), "remind", "second", nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.ID, second.ID, "IDs don't depend on the clock")
+
+	// Persisted jobs are known before the restarted Scheduler starts
+	s = newTestScheduler(storage)
+	_, err = s.Schedule(&Job{ID: first.ID, At: frozen.Add(time.Hour), Func: func(*Job) {}})
+	assert.Error(t, err)
+	assert.Len(t, s.Jobs(), 2)
+}
+
+func TestSchedulerJobsCommandAccess(t *testing.T) {
+	bot := newCommandTestBot()
+	bot.Transport = newFakeTransport()
+	bot.Channels.Set(Channel{ID: "C2", Name: "admin", IsChannel: true})
+	bot.setConfig(SlackConfig{AdminChannel: "admin"})
+	bot.Scheduler.listenJobsCommand()
+	bot.Scheduler.Schedule(&Job{ID: "storage-expiry", At: time.Now().Add(time.Hour), Func: func(*Job) {}})
+
+	cancel := func(channel string) {
+		msg := &Message{Msg: &slack.Msg{Text: "!jobs cancel storage-expiry", Channel: channel, User: "U1"}, bot: bot, team: bot.Team}
+		msg.resolve()
+		bot.runCommand(bot.GetCommand("jobs"), msg, "cancel storage-expiry")
+	}
+
+	cancel("C1")
+	assert.Len(t, bot.Scheduler.Jobs(), 1, "without roles, only the admin channel cancels jobs")
+
+	bot.setRoles(map[string]Role{"ops": {Permissions: []string{PermissionManageJobs}, Users: []string{"U1"}}})
+	cancel("C1")
+	assert.Empty(t, bot.Scheduler.Jobs(), "roles grant it anywhere")
+}
@@ -0,0 +1,220 @@
+package slick
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultShutdownTimeout is how long the bot waits for the plugins,
+// the outgoing messages and the web server when it receives SIGINT or
+// SIGTERM.
+const DefaultShutdownTimeout = 30 * time.Second
+
+// Shutdown stops the bot gracefully, and makes `Run` return. It stops
+// the Scheduler, calls the PluginStopper plugins, waits for the
+// outgoing messages to be sent, then cancels the contexts of the
+// Listeners, disconnects the Transport, stops the listeners, the
+// WebServer, writes the pending entries of the audit log, and finally
+// closes the storage.
+//
+// Shutdown gives up waiting once `ctx` is done, and returns its error.
+// It is called on SIGINT and SIGTERM. Calling it again waits for the
+// first call and returns the same result.
+func (bot *Bot) Shutdown(ctx context.Context) error {
+	bot.shutdownOnce.Do(func() {
+		bot.shutdownErr = bot.shutdown(ctx)
+	})
+	return bot.shutdownErr
+}
+
+func (bot *Bot) shutdown(ctx context.Context) error {
+	log.WithFields(log.Fields{
+		"Type": "Shutdown",
+	}).Info("Shutting down.")
+
+	var firstErr error
+	check := func(step string, err error) {
+		if err == nil {
+			return
+		}
+		log.WithFields(log.Fields{
+			"Type": "ShutdownError",
+			"Step": step,
+		}).WithError(err).Error("Error shutting down.")
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	check("scheduler", bot.Scheduler.shutdown(ctx))
+
+	if bot.pluginsStarted {
+		for _, plugin := range bot.EnabledPlugins() {
+			if plugin == bot.WebServer {
+				continue
+			}
+			check("plugins", stopPlugin(ctx, plugin))
+		}
+	}
+
+	if bot.handlersStarted {
+		for _, team := range bot.Teams() {
+			check("outgoing", team.drainOutgoing(ctx))
+		}
+	}
+
+	// Cancel the contexts of the handlers, so their long operations
+	// stop. Until now, the plugins could still use them to save their
+	// data, and the queued messages to be posted.
+	bot.cancel()
+
+	if bot.Transport != nil {
+		select {
+		case <-bot.connectDone:
+		default:
+			bot.Transport.Disconnect()
+		}
+	}
+	for _, team := range bot.teams {
+		team.Transport.Disconnect()
+	}
+
+	close(bot.stopCh)
+	if bot.handlersStarted {
+		select {
+		case <-bot.handlersDone:
+		case <-ctx.Done():
+			check("listeners", ctx.Err())
+		}
+	}
+
+	if bot.WebServer != nil && bot.pluginsStarted {
+		check("web server", stopPlugin(ctx, bot.WebServer))
+	}
+
+	check("audit", bot.flushAudit(ctx))
+	if bot.StorageBackend != nil {
+		log.Warnf("Storage is closing")
+		check("storage", bot.StorageBackend.Close())
+	}
+
+	log.WithFields(log.Fields{
+		"Type": "Shutdown",
+	}).Info("Bot stopped.")
+
+	return firstErr
+}
+
+// stopPlugin calls the plugin's StopPlugin, if it has one, and
+// recovers from its panics.
+func stopPlugin(ctx context.Context, plugin Plugin) (err error) {
+	stopper, ok := plugin.(PluginStopper)
+	if !ok {
+		return nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.WithFields(log.Fields{
+				"Type":   "PluginStopPanic",
+				"Plugin": pluginName(plugin),
+			}).Errorf("Plugin panicked while stopping: %v", r)
+		}
+	}()
+
+	if err := stopper.StopPlugin(ctx); err != nil {
+		log.WithFields(log.Fields{
+			"Type":   "PluginStopError",
+			"Plugin": pluginName(plugin),
+		}).WithError(err).Error("Plugin failed to stop.")
+		return err
+	}
+	return nil
+}
+
+// drainOutgoing waits for the queued messages of the team to be sent
+// and acknowledged.
+func (team *Team) drainOutgoing(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for len(team.outgoingMsgCh) != 0 || team.outgoing.queued() != 0 || team.outgoing.waitingAcks() != 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// stopListeners stops the workers and timeout managers of all the
+// listeners. It is called by the event loop when it exits.
+func (bot *Bot) stopListeners() {
+	for {
+		select {
+		case listen := <-bot.addListenerCh:
+			bot.listeners = append(bot.listeners, listen)
+		default:
+			for _, listen := range bot.listeners {
+				listen.cancelContext()
+				bot.stopListener(listen)
+				if listen.doneCh != nil {
+					select {
+					case listen.doneCh <- true:
+					default:
+					}
+				}
+			}
+			bot.listeners = nil
+			return
+		}
+	}
+}
+
+// handleSignals shuts the bot down on SIGINT or SIGTERM. A second
+// signal exits right away. SIGHUP reloads the config, see ReloadConfig.
+func (bot *Bot) handleSignals() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+	for stopping := false; !stopping; {
+		select {
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				go bot.reloadConfigFrom("SIGHUP")
+				continue
+			}
+			log.WithFields(log.Fields{
+				"Type":   "Signal",
+				"Signal": sig.String(),
+			}).Warn("Received signal, shutting down.")
+			stopping = true
+		case <-bot.stopCh:
+			signal.Stop(signals)
+			return
+		}
+	}
+
+	go func() {
+		for sig := range signals {
+			if sig == syscall.SIGHUP {
+				continue
+			}
+			log.WithFields(log.Fields{
+				"Type":   "Signal",
+				"Signal": sig.String(),
+			}).Error("Received second signal, exiting now.")
+			os.Exit(1)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
+	defer cancel()
+	bot.Shutdown(ctx)
+}
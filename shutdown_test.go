@@ -0,0 +1,87 @@
+package slick
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+type stoppingPlugin struct {
+	stopped  chan string
+	deadline time.Time
+	bot      *Bot
+	botErr   error
+}
+
+func (p *stoppingPlugin) StopPlugin(ctx context.Context) error {
+	p.deadline, _ = ctx.Deadline()
+	if p.bot != nil {
+		p.botErr = p.bot.Context().Err()
+	}
+	p.stopped <- "plugin"
+	return nil
+}
+
+func TestShutdown(t *testing.T) {
+	plugin := &stoppingPlugin{stopped: make(chan string, 1)}
+	defer func(plugins []Plugin) { registeredPlugins = plugins }(registeredPlugins)
+	registeredPlugins = []Plugin{plugin}
+
+	transport := newFakeTransport()
+	bot := New("")
+	plugin.bot = bot
+	bot.Transport = transport
+	bot.StorageBackend = NewMemoryStorage()
+	bot.pluginsStarted = true
+	bot.setupHandlers()
+
+	// Acknowledge the sent messages, late
+	go func() {
+		for msg := range transport.sent {
+			time.Sleep(50 * time.Millisecond)
+			ack := &slack.AckMessage{ReplyTo: msg.ID}
+			ack.Ok = true
+			transport.events <- slack.RTMEvent{Type: "ack", Data: ack}
+		}
+	}()
+
+	managed := &Listener{ListenDuration: time.Hour, EventHandlerFunc: func(*Listener, interface{}) {}}
+	bot.Listen(managed)
+	bot.SendOutgoingMessage("goodbye", "C1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, bot.Shutdown(ctx))
+
+	assert.Equal(t, "plugin", <-plugin.stopped)
+	deadline, _ := ctx.Deadline()
+	assert.Equal(t, deadline, plugin.deadline, "plugins get the shutdown deadline")
+	assert.NoError(t, plugin.botErr, "the bot's context is cancelled once the plugins stopped")
+	assert.Equal(t, context.Canceled, bot.Context().Err())
+	assert.Equal(t, 0, bot.outgoing.waitingAcks(), "outgoing messages are drained")
+	assert.Len(t, bot.listeners, 0)
+
+	select {
+	case <-bot.handlersDone:
+	default:
+		t.Fatal("event loop still running")
+	}
+
+	assert.NoError(t, bot.Shutdown(ctx), "Shutdown can be called again")
+}
+
+func TestShutdownGivesUp(t *testing.T) {
+	bot := New("")
+	bot.Transport = newFakeTransport()
+	bot.setupHandlers()
+
+	// Never acknowledged
+	bot.SendOutgoingMessage("hello?", "C1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, bot.Shutdown(ctx))
+}
@@ -0,0 +1,306 @@
+// Package slicktest runs a real slick Bot against an in-process fake
+// Slack, to test plugins end to end.
+//
+//	func TestTodo(t *testing.T) {
+//		h := slicktest.New(t)
+//		defer h.Close()
+//
+//		h.Post("alice", "#general", "!todo add buy milk")
+//		h.ExpectMessage("#general", "added:")
+//	}
+//
+// All plugins registered in the test binary (through their `init()`) are
+// loaded, like in a real bot.
+package slicktest
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CapstoneLabs/slick"
+	"github.com/nlopes/slack"
+)
+
+// DefaultTimeout is how long the Expect* methods wait for the bot.
+var DefaultTimeout = 2 * time.Second
+
+// DefaultUsers are seeded when no users are given to New.
+var DefaultUsers = []User{
+	{ID: "UALICE", Name: "alice", RealName: "Alice Liddell", Email: "alice@example.com"},
+	{ID: "UBOB", Name: "bob", RealName: "Bob Morane", Email: "bob@example.com"},
+}
+
+// DefaultChannels are seeded when no channels are given to New.
+var DefaultChannels = []Channel{
+	{ID: "CGENERAL", Name: "general", Members: []string{"UALICE", "UBOB", "USLICK"}},
+	{ID: "CRANDOM", Name: "random", Members: []string{"UALICE", "USLICK"}},
+}
+
+// Harness holds a running Bot connected to a fake Slack Server.
+type Harness struct {
+	T      testing.TB
+	Server *Server
+	Bot    *slick.Bot
+
+	dir string
+	// slackAPI is the `slack.SLACK_API` restored by Close.
+	slackAPI string
+}
+
+// Options configures the Harness.
+type Options struct {
+	Users    []User
+	Channels []Channel
+
+	// Config is merged into the generated config file, with sections
+	// as keys (ex: "Recognition": map[string]interface{}{...}). The
+	// "Slack" section is always overridden for the api_token, the
+	// db_path and the message rates.
+	Config map[string]interface{}
+}
+
+// New starts a fake Slack server with the default users and channels,
+// and a Bot connected to it.
+func New(t testing.TB) *Harness {
+	return NewWithOptions(t, Options{})
+}
+
+// NewWithOptions starts a fake Slack server seeded as specified in
+// `opts`, and a Bot connected to it. It returns once the bot has loaded
+// users and channels.
+func NewWithOptions(t testing.TB, opts Options) *Harness {
+	if opts.Users == nil {
+		opts.Users = DefaultUsers
+	}
+	if opts.Channels == nil {
+		opts.Channels = DefaultChannels
+	}
+
+	dir, err := ioutil.TempDir("", "slicktest")
+	if err != nil {
+		t.Fatalf("slicktest: creating temp dir: %s", err)
+	}
+
+	self := User{ID: "USLICK", Name: "slick", RealName: "Slick", IsBot: true}
+	h := &Harness{
+		T:        t,
+		Server:   NewServer(self, opts.Users, opts.Channels),
+		dir:      dir,
+		slackAPI: slack.SLACK_API,
+	}
+
+	configFile, err := h.writeConfig(opts.Config)
+	if err != nil {
+		h.Close()
+		t.Fatalf("slicktest: writing config: %s", err)
+	}
+
+	slack.SLACK_API = h.Server.APIURL()
+
+	h.Bot = slick.New(configFile)
+
+	ready := make(chan bool, 1)
+	h.Bot.Listen(&slick.Listener{
+		EventHandlerFunc: func(listen *slick.Listener, event interface{}) {
+			if _, ok := event.(*slack.HelloEvent); ok {
+				ready <- true
+				listen.Close()
+			}
+		},
+	})
+
+	go h.Bot.Run()
+
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		h.Close()
+		t.Fatal("slicktest: bot didn't connect to the fake Slack server")
+	}
+
+	return h
+}
+
+func (h *Harness) writeConfig(extra map[string]interface{}) (string, error) {
+	conf := map[string]interface{}{}
+	for k, v := range extra {
+		conf[k] = v
+	}
+	conf["Slack"] = map[string]interface{}{
+		"api_token": "xoxb-slicktest",
+		"db_path":   filepath.Join(h.dir, "slick.bolt.db"),
+		// Tests post faster than Slack allows.
+		"message_rate":         1000,
+		"channel_message_rate": 1000,
+	}
+
+	content, err := json.Marshal(conf)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(h.dir, "config.json")
+	return path, ioutil.WriteFile(path, content, 0600)
+}
+
+// Close disconnects the bot, stops the fake Slack server, and points
+// the Slack client back to the real Slack.
+func (h *Harness) Close() {
+	if h.Bot != nil && h.Bot.Transport != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		h.Bot.Shutdown(ctx)
+	}
+	h.Server.Close()
+	os.RemoveAll(h.dir)
+	slack.SLACK_API = h.slackAPI
+}
+
+// UserID resolves a user name (or ID) to a seeded user ID.
+func (h *Harness) UserID(name string) string {
+	name = strings.TrimPrefix(name, "@")
+	for _, u := range append([]User{h.Server.Self}, h.Server.Users...) {
+		if u.Name == name || u.ID == name {
+			return u.ID
+		}
+	}
+	h.T.Fatalf("slicktest: unknown user %q", name)
+	return ""
+}
+
+// ChannelID resolves "#name" or a channel ID to a seeded channel ID.
+// Use "@username" to target the direct messages with that user.
+func (h *Harness) ChannelID(name string) string {
+	if strings.HasPrefix(name, "@") {
+		return h.Server.IMChannel(h.UserID(name))
+	}
+	name = strings.TrimPrefix(name, "#")
+	for _, c := range h.Server.Channels {
+		if c.Name == name || c.ID == name {
+			return c.ID
+		}
+	}
+	if strings.HasPrefix(name, "D") {
+		return name
+	}
+	h.T.Fatalf("slicktest: unknown channel %q", name)
+	return ""
+}
+
+// Post sends `text` as `user` in `channel`, and returns the timestamp
+// of the message. Use "@username" as the channel for direct messages
+// to the bot.
+func (h *Harness) Post(user, channel, text string) string {
+	ts, err := h.Server.PostMessage(h.UserID(user), h.ChannelID(channel), text, "")
+	if err != nil {
+		h.T.Fatalf("slicktest: posting message: %s", err)
+	}
+	return ts
+}
+
+// PostInThread sends `text` as `user` in the thread started by the
+// message at `threadTS` in `channel`, and returns the timestamp of the
+// message.
+func (h *Harness) PostInThread(user, channel, threadTS, text string) string {
+	ts, err := h.Server.PostMessage(h.UserID(user), h.ChannelID(channel), text, threadTS)
+	if err != nil {
+		h.T.Fatalf("slicktest: posting message: %s", err)
+	}
+	return ts
+}
+
+// React adds the `emoji` reaction as `user` on the message at `ts`.
+func (h *Harness) React(user, channel, ts, emoji string) {
+	err := h.Server.AddReaction(h.UserID(user), h.ChannelID(channel), ts, emoji)
+	if err != nil {
+		h.T.Fatalf("slicktest: adding reaction: %s", err)
+	}
+}
+
+// ExpectMessage waits for the next message the bot sends in `channel`,
+// and fails the test unless it contains `contains`. Messages sent to
+// other channels in the meantime are discarded.
+func (h *Harness) ExpectMessage(channel, contains string) SentMessage {
+	channelID := h.ChannelID(channel)
+	timeout := time.After(DefaultTimeout)
+	for {
+		select {
+		case msg := <-h.Server.Messages:
+			if msg.Channel != channelID {
+				continue
+			}
+			if !strings.Contains(msg.Text, contains) {
+				h.T.Fatalf("slicktest: expected message containing %q in %s, got %q", contains, channel, msg.Text)
+			}
+			return msg
+		case <-timeout:
+			h.T.Fatalf("slicktest: no message containing %q received in %s", contains, channel)
+			return SentMessage{}
+		}
+	}
+}
+
+// ExpectNoMessage fails the test if the bot sends any message within
+// `d`.
+func (h *Harness) ExpectNoMessage(d time.Duration) {
+	select {
+	case msg := <-h.Server.Messages:
+		h.T.Fatalf("slicktest: expected no message, got %q in %s", msg.Text, msg.Channel)
+	case <-time.After(d):
+	}
+}
+
+// ExpectReaction waits for the bot to add `emoji` on the message at
+// `ts`.
+func (h *Harness) ExpectReaction(ts, emoji string) Reaction {
+	timeout := time.After(DefaultTimeout)
+	for {
+		select {
+		case re := <-h.Server.Reactions:
+			if re.Timestamp == ts && re.Emoji == emoji && !re.Removed {
+				return re
+			}
+		case <-timeout:
+			h.T.Fatalf("slicktest: no %q reaction added on %s", emoji, ts)
+			return Reaction{}
+		}
+	}
+}
+
+// ExpectEdit waits for the bot to update the message at `ts`.
+func (h *Harness) ExpectEdit(ts string) Edit {
+	timeout := time.After(DefaultTimeout)
+	for {
+		select {
+		case edit := <-h.Server.Edits:
+			if edit.Timestamp == ts {
+				return edit
+			}
+		case <-timeout:
+			h.T.Fatalf("slicktest: message %s was not edited", ts)
+			return Edit{}
+		}
+	}
+}
+
+// ExpectDeleted waits for the bot to delete the message at `ts`.
+func (h *Harness) ExpectDeleted(ts string, within time.Duration) {
+	timeout := time.After(within)
+	for {
+		select {
+		case del := <-h.Server.Deletions:
+			if del.Timestamp == ts {
+				return
+			}
+		case <-timeout:
+			h.T.Fatalf("slicktest: message %s was not deleted", ts)
+			return
+		}
+	}
+}
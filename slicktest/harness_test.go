@@ -0,0 +1,49 @@
+package slicktest_test
+
+import (
+	"testing"
+
+	"github.com/CapstoneLabs/slick"
+	"github.com/CapstoneLabs/slick/slicktest"
+	"github.com/nlopes/slack"
+)
+
+type echo struct{}
+
+func init() {
+	slick.RegisterPlugin(&echo{})
+}
+
+func (e *echo) InitPlugin(bot *slick.Bot) {
+	bot.Listen(&slick.Listener{
+		MentionsMeOnly: true,
+		MessageHandlerFunc: func(listen *slick.Listener, msg *slick.Message) {
+			msg.AddReaction("eyes")
+			msg.ReplyMention("you said: %s", msg.Text)
+		},
+	})
+}
+
+func TestHarnessRoundtrip(t *testing.T) {
+	h := slicktest.New(t)
+	defer h.Close()
+
+	ts := h.Post("alice", "#general", "hello <@USLICK>")
+	h.ExpectReaction(ts, "eyes")
+	h.ExpectMessage("#general", "you said: hello")
+
+	h.Post("bob", "@bob", "in private")
+	h.ExpectMessage("@bob", "you said: in private")
+}
+
+func TestHarnessRestoresSlackAPI(t *testing.T) {
+	api := slack.SLACK_API
+	h := slicktest.New(t)
+	if slack.SLACK_API == api {
+		t.Error("the bot doesn't target the fake Slack server")
+	}
+	h.Close()
+	if slack.SLACK_API != api {
+		t.Errorf("slack.SLACK_API is %q after Close, expected %q", slack.SLACK_API, api)
+	}
+}
@@ -0,0 +1,338 @@
+package slicktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nlopes/slack"
+)
+
+// User is a user seeded in the fake Slack server.
+type User struct {
+	ID       string
+	Name     string
+	RealName string
+	Email    string
+	IsBot    bool
+}
+
+// Channel is a public channel seeded in the fake Slack server.
+type Channel struct {
+	ID      string
+	Name    string
+	Members []string
+}
+
+// SentMessage is a message the bot sent to the fake Slack server.
+type SentMessage struct {
+	Channel   string
+	Text      string
+	Timestamp string
+	ThreadTS  string
+	// Blocks is the JSON of the Block Kit blocks, if any.
+	Blocks string
+}
+
+// Reaction is an emoji reaction the bot added or removed.
+type Reaction struct {
+	Channel   string
+	Timestamp string
+	Emoji     string
+	Removed   bool
+}
+
+// Edit is an update the bot made to one of its messages.
+type Edit struct {
+	Channel   string
+	Timestamp string
+	Text      string
+}
+
+// Deletion is a message the bot deleted.
+type Deletion struct {
+	Channel   string
+	Timestamp string
+}
+
+// Server is an in-process fake of the Slack Web API and RTM websocket,
+// with a fixed set of users and channels. It records everything the
+// bot does, for tests to assert on.
+type Server struct {
+	*httptest.Server
+
+	Self     User
+	Users    []User
+	Channels []Channel
+
+	Messages  chan SentMessage
+	Reactions chan Reaction
+	Edits     chan Edit
+	Deletions chan Deletion
+
+	upgrader websocket.Upgrader
+
+	lock   sync.Mutex
+	conn   *websocket.Conn
+	ims    map[string]string // user ID -> IM channel ID
+	tsBase int64
+	tsSeq  int
+}
+
+// NewServer starts a fake Slack server, with `self` being the bot's
+// own user.
+func NewServer(self User, users []User, channels []Channel) *Server {
+	s := &Server{
+		Self:      self,
+		Users:     users,
+		Channels:  channels,
+		Messages:  make(chan SentMessage, 100),
+		Reactions: make(chan Reaction, 100),
+		Edits:     make(chan Edit, 100),
+		Deletions: make(chan Deletion, 100),
+		ims:       make(map[string]string),
+		tsBase:    time.Now().Unix(),
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/", s.handleAPI)
+	mux.HandleFunc("/ws", s.handleWebsocket)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// APIURL is the value for `slack.SLACK_API` to target this server.
+func (s *Server) APIURL() string {
+	return s.URL + "/api/"
+}
+
+// nextTimestamp returns a new, unique message timestamp.
+func (s *Server) nextTimestamp() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tsSeq++
+	return fmt.Sprintf("%d.%06d", s.tsBase, s.tsSeq)
+}
+
+// IMChannel returns the ID of the direct message channel with the
+// given user ID.
+func (s *Server) IMChannel(userID string) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	id, ok := s.ims[userID]
+	if !ok {
+		id = "D" + strings.TrimPrefix(userID, "U")
+		s.ims[userID] = id
+	}
+	return id
+}
+
+// SendEvent pushes a raw RTM event to the connected bot.
+func (s *Server) SendEvent(event interface{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.conn == nil {
+		return fmt.Errorf("bot not connected")
+	}
+	return s.conn.WriteJSON(event)
+}
+
+// PostMessage sends a message from `userID` to `channelID` as if typed
+// in Slack, and returns its timestamp.
+func (s *Server) PostMessage(userID, channelID, text, threadTS string) (string, error) {
+	ts := s.nextTimestamp()
+	err := s.SendEvent(map[string]interface{}{
+		"type":      "message",
+		"channel":   channelID,
+		"user":      userID,
+		"text":      text,
+		"ts":        ts,
+		"thread_ts": threadTS,
+	})
+	return ts, err
+}
+
+// AddReaction sends a reaction_added event from `userID` on the
+// message at `ts`.
+func (s *Server) AddReaction(userID, channelID, ts, emoji string) error {
+	return s.SendEvent(map[string]interface{}{
+		"type":     "reaction_added",
+		"user":     userID,
+		"reaction": emoji,
+		"item": map[string]string{
+			"type":    "message",
+			"channel": channelID,
+			"ts":      ts,
+		},
+		"event_ts": s.nextTimestamp(),
+	})
+}
+
+func (s *Server) handleWebsocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	s.lock.Lock()
+	s.conn = conn
+	s.lock.Unlock()
+
+	s.SendEvent(map[string]string{"type": "hello"})
+
+	for {
+		var in struct {
+			ID       int    `json:"id"`
+			Type     string `json:"type"`
+			Channel  string `json:"channel"`
+			Text     string `json:"text"`
+			ThreadTS string `json:"thread_ts"`
+		}
+		if err := conn.ReadJSON(&in); err != nil {
+			return
+		}
+
+		switch in.Type {
+		case "ping":
+			s.SendEvent(map[string]interface{}{"type": "pong", "reply_to": in.ID})
+		case "message":
+			ts := s.nextTimestamp()
+			s.Messages <- SentMessage{Channel: in.Channel, Text: in.Text, Timestamp: ts, ThreadTS: in.ThreadTS}
+			s.SendEvent(map[string]interface{}{"ok": true, "reply_to": in.ID, "ts": ts, "text": in.Text})
+		}
+	}
+}
+
+func (s *Server) handleAPI(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	method := strings.TrimPrefix(r.URL.Path, "/api/")
+
+	var out interface{}
+	switch method {
+	case "auth.test":
+		out = map[string]interface{}{"ok": true, "user": s.Self.Name, "user_id": s.Self.ID, "team_id": "T0TEST"}
+	case "rtm.connect", "rtm.start":
+		out = map[string]interface{}{
+			"ok":   true,
+			"url":  "ws" + strings.TrimPrefix(s.URL, "http") + "/ws",
+			"self": map[string]string{"id": s.Self.ID, "name": s.Self.Name},
+			"team": map[string]string{"id": "T0TEST", "domain": "slicktest"},
+		}
+	case "users.list":
+		out = map[string]interface{}{"ok": true, "members": s.slackUsers()}
+	case "conversations.list":
+		out = map[string]interface{}{
+			"ok":                true,
+			"channels":          append(s.slackChannels(), s.slackIMs()...),
+			"response_metadata": map[string]string{"next_cursor": ""},
+		}
+	case "users.info":
+		out = map[string]interface{}{"ok": false, "error": "user_not_found"}
+		for _, user := range s.slackUsers() {
+			if user.ID == r.Form.Get("user") {
+				out = map[string]interface{}{"ok": true, "user": user}
+			}
+		}
+	case "conversations.info":
+		out = map[string]interface{}{"ok": false, "error": "channel_not_found"}
+		for _, channel := range append(s.slackChannels(), s.slackIMs()...) {
+			if channel["id"] == r.Form.Get("channel") {
+				out = map[string]interface{}{"ok": true, "channel": channel}
+			}
+		}
+	case "conversations.members":
+		out = map[string]interface{}{"ok": false, "error": "channel_not_found"}
+		for _, c := range s.Channels {
+			if c.ID == r.Form.Get("channel") {
+				members := c.Members
+				if members == nil {
+					members = []string{}
+				}
+				out = map[string]interface{}{
+					"ok":                true,
+					"members":           members,
+					"response_metadata": map[string]string{"next_cursor": ""},
+				}
+			}
+		}
+	case "im.open":
+		out = map[string]interface{}{"ok": true, "channel": map[string]string{"id": s.IMChannel(r.Form.Get("user"))}}
+	case "channels.join":
+		out = map[string]interface{}{"ok": true}
+	case "chat.postMessage":
+		ts := s.nextTimestamp()
+		s.Messages <- SentMessage{Channel: r.Form.Get("channel"), Text: r.Form.Get("text"), Timestamp: ts, ThreadTS: r.Form.Get("thread_ts"), Blocks: r.Form.Get("blocks")}
+		out = map[string]interface{}{"ok": true, "channel": r.Form.Get("channel"), "ts": ts}
+	case "reactions.add", "reactions.remove":
+		s.Reactions <- Reaction{
+			Channel:   r.Form.Get("channel"),
+			Timestamp: r.Form.Get("timestamp"),
+			Emoji:     r.Form.Get("name"),
+			Removed:   method == "reactions.remove",
+		}
+		out = map[string]interface{}{"ok": true}
+	case "chat.update":
+		s.Edits <- Edit{Channel: r.Form.Get("channel"), Timestamp: r.Form.Get("ts"), Text: r.Form.Get("text")}
+		out = map[string]interface{}{"ok": true, "channel": r.Form.Get("channel"), "ts": r.Form.Get("ts"), "text": r.Form.Get("text")}
+	case "chat.delete":
+		s.Deletions <- Deletion{Channel: r.Form.Get("channel"), Timestamp: r.Form.Get("ts")}
+		out = map[string]interface{}{"ok": true, "channel": r.Form.Get("channel"), "ts": r.Form.Get("ts")}
+	default:
+		out = map[string]interface{}{"ok": false, "error": "unknown_method"}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+func (s *Server) slackUsers() []slack.User {
+	var out []slack.User
+	for _, u := range append([]User{s.Self}, s.Users...) {
+		user := slack.User{
+			ID:       u.ID,
+			Name:     u.Name,
+			RealName: u.RealName,
+			IsBot:    u.IsBot,
+		}
+		user.Profile.RealName = u.RealName
+		user.Profile.Email = u.Email
+		out = append(out, user)
+	}
+	return out
+}
+
+func (s *Server) slackChannels() []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, c := range s.Channels {
+		out = append(out, map[string]interface{}{
+			"id":         c.ID,
+			"name":       c.Name,
+			"is_channel": true,
+			"is_member":  true,
+			"members":    c.Members,
+		})
+	}
+	return out
+}
+
+func (s *Server) slackIMs() []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, u := range s.Users {
+		out = append(out, map[string]interface{}{
+			"id":      s.IMChannel(u.ID),
+			"is_im":   true,
+			"user":    u.ID,
+			"is_open": true,
+		})
+	}
+	return out
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/CapstoneLabs/slick"
+	log "github.com/sirupsen/logrus"
 )
 
 var sectionRegexp = regexp.MustCompile(`(?mi)^!(yesterday|today|blocking)`)
@@ -40,6 +41,10 @@ func extractSectionAndText(input string, res [][]int) []sectionMatch {
 }
 
 func (standup *Standup) TriggerReminders(msg *slick.Message, section string) {
+	if msg.FromUser == nil {
+		// Users missing from the directory, like bots, can't be reminded.
+		return
+	}
 	standup.sectionUpdates <- sectionUpdate{section, msg}
 }
 
@@ -54,8 +59,6 @@ func (standup *Standup) manageUpdatesInteraction() {
 	for {
 		select {
 		case update := <-standup.sectionUpdates:
-			// update.msg.FromUser appears to be nil and is causing a segmentation violation
-			// Update: &slick.Message{Msg:(*slack.Msg)(0xc0002d1680), SubMessage:(*slack.Msg)(nil), bot:(*slick.Bot)(0xc000182180), MentionsMe:true, IsEdit:false, FromMe:false, FromUser:(*slack.User)(nil), FromChannel:(*slick.Channel)(nil), Match:[]string(nil)}
 			userEmail := update.msg.FromUser.Profile.Email
 			progress := userProgressMap[userEmail]
 			if progress == nil {
@@ -65,8 +68,8 @@ func (standup *Standup) manageUpdatesInteraction() {
 				}
 				userProgressMap[userEmail] = progress
 				progress.sectionsDone[update.section] = true
-				go progress.waitAndCheckProgress(update.msg, remindCh)
-				go progress.waitForReset(update.msg, resetCh)
+				go progress.waitAndCheckProgress(update.msg, progress.cancelTimer, remindCh)
+				progress.resetJob = standup.scheduleReset(update.msg, resetCh)
 			} else {
 				close(progress.cancelTimer)
 
@@ -75,9 +78,12 @@ func (standup *Standup) manageUpdatesInteraction() {
 				if numDone == 3 {
 					update.msg.ReplyMention("got it!")
 					delete(userProgressMap, update.msg.FromUser.Profile.Email)
+					if progress.resetJob != "" {
+						standup.bot.Scheduler.Cancel(progress.resetJob)
+					}
 				} else {
 					progress.cancelTimer = make(chan bool)
-					go progress.waitAndCheckProgress(update.msg, remindCh)
+					go progress.waitAndCheckProgress(update.msg, progress.cancelTimer, remindCh)
 				}
 			}
 
@@ -127,19 +133,38 @@ var userProgressMap = make(map[string]*userProgress)
 type userProgress struct {
 	sectionsDone map[string]bool
 	cancelTimer  chan bool
+	// resetJob is the ID of the Scheduler job which stops the
+	// reminders.
+	resetJob string
 }
 
-func (up *userProgress) waitAndCheckProgress(msg *slick.Message, remindCh chan *slick.Message) {
+// waitAndCheckProgress reminds the user after a while, unless `cancel`
+// is closed. The loop replaces `up.cancelTimer`, so it is passed here.
+func (up *userProgress) waitAndCheckProgress(msg *slick.Message, cancel chan bool, remindCh chan *slick.Message) {
 	select {
 	case <-time.After(90 * time.Second):
 		remindCh <- msg
-	case <-up.cancelTimer:
+	case <-cancel:
 		return
 	}
 }
 
-// waitForReset waits a couple of minutes and stops listening to that user altogether.  We want to poke the user once or twice if he's slow.. but not eternally.
-func (up *userProgress) waitForReset(msg *slick.Message, resetCh chan *slick.Message) {
-	<-time.After(15 * time.Minute)
-	resetCh <- msg
+// scheduleReset stops listening to that user altogether after a
+// couple of minutes, with a Scheduler job. We want to poke the user
+// once or twice if they're slow.. but not eternally.
+func (standup *Standup) scheduleReset(msg *slick.Message, resetCh chan *slick.Message) string {
+	job, err := standup.bot.Scheduler.Schedule(&slick.Job{
+		Name: "standup reminders of " + msg.FromUser.Name,
+		Team: msg.Workspace().Name,
+		At:   time.Now().Add(15 * time.Minute),
+		Func: func(*slick.Job) { resetCh <- msg },
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Plugin": "standup",
+			"Type":   "ScheduleReset",
+		}).WithError(err).Error("Couldn't schedule the end of the reminders.")
+		return ""
+	}
+	return job.ID
 }
@@ -1,6 +1,12 @@
 package standup
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CapstoneLabs/slick/slicktest"
+)
 
 func TestRegexpMatch(t *testing.T) {
 	input := `!blocking this is good
@@ -20,3 +26,41 @@ func TestRegexpMatch(t *testing.T) {
 		t.Error("res[1].text should be 'thank you'")
 	}
 }
+
+func TestStandupFlow(t *testing.T) {
+	h := slicktest.New(t)
+	defer h.Close()
+
+	h.Post("alice", "#general", "!yesterday fixed the build")
+	h.Post("alice", "#general", "!today\nreview the PRs")
+	h.ExpectNoMessage(100 * time.Millisecond)
+
+	if jobs := reminderJobs(h); len(jobs) != 1 || jobs[0] != "standup reminders of alice" {
+		t.Fatalf("expected the job ending alice's reminders, got %v", jobs)
+	}
+
+	h.Post("alice", "#general", "!blocking nothing")
+	msg := h.ExpectMessage("#general", "got it!")
+	if !strings.HasPrefix(msg.Text, "<@UALICE>") {
+		t.Errorf("expected a mention of alice, got %q", msg.Text)
+	}
+	if jobs := reminderJobs(h); len(jobs) != 0 {
+		t.Errorf("the reminders of alice are still scheduled: %v", jobs)
+	}
+
+	// All the sections at once
+	h.Post("bob", "#general", "!yesterday tests\n!today more tests\n!blocking flaky tests")
+	h.ExpectMessage("#general", "got it!")
+}
+
+// reminderJobs returns the names of the scheduled jobs ending the
+// reminders.
+func reminderJobs(h *slicktest.Harness) []string {
+	var names []string
+	for _, job := range h.Bot.Scheduler.Jobs() {
+		if strings.HasPrefix(job.Name, "standup reminders") {
+			names = append(names, job.Name)
+		}
+	}
+	return names
+}
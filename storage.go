@@ -0,0 +1,116 @@
+package slick
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrNotFound is returned by `Store.Get` for missing or expired keys.
+var ErrNotFound = errors.New("not found")
+
+// StorageBackend holds the namespaced stores of the bot and its
+// plugins. Slick ships with a BoltDB backend (the default), an
+// in-memory backend for tests, and a database/sql backend for SQLite.
+type StorageBackend interface {
+	// Namespace returns the Store of a namespace, created on first
+	// write.
+	Namespace(name string) Store
+	Close() error
+}
+
+// StoreTx holds the operations on a namespace. Values are JSON
+// encoded.
+type StoreTx interface {
+	// Get unmarshals the value of `key` into `v`, or returns
+	// ErrNotFound.
+	Get(key string, v interface{}) error
+	Put(key string, v interface{}) error
+	// PutWithTTL stores a key which expires after `ttl`.
+	PutWithTTL(key string, v interface{}, ttl time.Duration) error
+	// Delete removes a key. Deleting a missing key is not an error.
+	Delete(key string) error
+	// List calls `f` for each key starting with `prefix`, in key
+	// order. Returning an error from `f` stops the listing.
+	List(prefix string, f func(key string, value StoredValue) error) error
+}
+
+// Store is a namespace of a StorageBackend, obtained with
+// `Bot.Storage`.
+type Store interface {
+	StoreTx
+
+	// Update runs `f` in a transaction: if it returns an error, none
+	// of its writes are applied. Use `tx`, not the Store, inside `f`.
+	Update(f func(tx StoreTx) error) error
+}
+
+// StoredValue is a raw value passed to `List` callbacks.
+type StoredValue []byte
+
+// Decode unmarshals the value into `v`.
+func (value StoredValue) Decode(v interface{}) error {
+	return json.Unmarshal(value, v)
+}
+
+// Storage returns the Store of `namespace`. Each plugin should use its
+// own namespace, usually its name.
+func (bot *Bot) Storage(namespace string) Store {
+	if bot.StorageBackend == nil {
+		panic("slick: Storage called before the bot runs")
+	}
+	return bot.StorageBackend.Namespace(namespace)
+}
+
+// openStorage creates the StorageBackend from the `storage` config.
+func (bot *Bot) openStorage() (StorageBackend, error) {
+	switch bot.Config().Storage {
+	case "", "bolt":
+		return NewBoltStorage(bot.Config().DBPath)
+
+	case "memory":
+		return NewMemoryStorage(), nil
+
+	case "sqlite":
+		return OpenSQLStorage(SQLiteDriver, bot.Config().StorageDSN)
+
+	default:
+		return nil, fmt.Errorf("unknown storage %q, expected \"bolt\", \"memory\" or \"sqlite\"", bot.Config().Storage)
+	}
+}
+
+// storageExpirer is implemented by the backends which can delete
+// their expired keys, instead of only skipping them on reads.
+type storageExpirer interface {
+	purgeExpired() error
+}
+
+// scheduleStorageExpiry purges the expired keys every hour.
+func (bot *Bot) scheduleStorageExpiry() {
+	expirer, ok := bot.StorageBackend.(storageExpirer)
+	if !ok {
+		return
+	}
+
+	bot.Scheduler.Cron("storage-expiry", "@hourly", func() {
+		if err := expirer.purgeExpired(); err != nil {
+			log.WithError(err).Error("Couldn't purge the expired storage keys.")
+		}
+	})
+}
+
+// expiryTime returns when a key stored now with `ttl` expires, as Unix
+// nanoseconds. Zero means never.
+func expiryTime(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return time.Now().Add(ttl).UnixNano()
+}
+
+func isExpired(expiry int64) bool {
+	return expiry != 0 && expiry <= time.Now().UnixNano()
+}
@@ -0,0 +1,231 @@
+package slick
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// boltExpiryBucket holds the expiry times of TTL keys, in a
+// sub-bucket per namespace, so the namespaces' buckets only hold the
+// JSON values.
+var boltExpiryBucket = []byte("slick:expiry")
+
+// BoltStorage is the BoltDB StorageBackend. Each namespace is a
+// bucket of the same name.
+type BoltStorage struct {
+	DB *bolt.DB
+}
+
+// NewBoltStorage opens the BoltDB file at `path`.
+func NewBoltStorage(path string) (*BoltStorage, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &BoltStorage{DB: db}, nil
+}
+
+func (s *BoltStorage) Namespace(name string) Store {
+	return &boltStore{db: s.DB, name: []byte(name)}
+}
+
+func (s *BoltStorage) Close() error {
+	return s.DB.Close()
+}
+
+// purgeExpired deletes the expired keys of all namespaces.
+func (s *BoltStorage) purgeExpired() error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		expiries := tx.Bucket(boltExpiryBucket)
+		if expiries == nil {
+			return nil
+		}
+
+		var names [][]byte
+		expiries.ForEach(func(name, _ []byte) error {
+			names = append(names, name)
+			return nil
+		})
+
+		for _, name := range names {
+			var expired []string
+			expiries.Bucket(name).ForEach(func(k, v []byte) error {
+				if isExpired(decodeBoltExpiry(v)) {
+					expired = append(expired, string(k))
+				}
+				return nil
+			})
+
+			btx := &boltTx{tx: tx, name: name}
+			for _, key := range expired {
+				if err := btx.Delete(key); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+type boltStore struct {
+	db   *bolt.DB
+	name []byte
+}
+
+func (s *boltStore) Get(key string, v interface{}) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return (&boltTx{tx: tx, name: s.name}).Get(key, v)
+	})
+}
+
+func (s *boltStore) Put(key string, v interface{}) error {
+	return s.Update(func(tx StoreTx) error {
+		return tx.Put(key, v)
+	})
+}
+
+func (s *boltStore) PutWithTTL(key string, v interface{}, ttl time.Duration) error {
+	return s.Update(func(tx StoreTx) error {
+		return tx.PutWithTTL(key, v, ttl)
+	})
+}
+
+func (s *boltStore) Delete(key string) error {
+	return s.Update(func(tx StoreTx) error {
+		return tx.Delete(key)
+	})
+}
+
+func (s *boltStore) List(prefix string, f func(key string, value StoredValue) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return (&boltTx{tx: tx, name: s.name}).List(prefix, f)
+	})
+}
+
+func (s *boltStore) Update(f func(tx StoreTx) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return f(&boltTx{tx: tx, name: s.name})
+	})
+}
+
+type boltTx struct {
+	tx   *bolt.Tx
+	name []byte
+}
+
+func (t *boltTx) Get(key string, v interface{}) error {
+	bucket := t.tx.Bucket(t.name)
+	if bucket == nil {
+		return ErrNotFound
+	}
+
+	val := bucket.Get([]byte(key))
+	if val == nil || isExpired(t.expiry(key)) {
+		return ErrNotFound
+	}
+
+	return json.Unmarshal(val, v)
+}
+
+func (t *boltTx) Put(key string, v interface{}) error {
+	return t.PutWithTTL(key, v, 0)
+}
+
+func (t *boltTx) PutWithTTL(key string, v interface{}, ttl time.Duration) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	bucket, err := t.tx.CreateBucketIfNotExists(t.name)
+	if err != nil {
+		return err
+	}
+	if err := bucket.Put([]byte(key), content); err != nil {
+		return err
+	}
+
+	return t.setExpiry(key, expiryTime(ttl))
+}
+
+func (t *boltTx) Delete(key string) error {
+	bucket := t.tx.Bucket(t.name)
+	if bucket == nil {
+		return nil
+	}
+	if err := bucket.Delete([]byte(key)); err != nil {
+		return err
+	}
+
+	return t.setExpiry(key, 0)
+}
+
+func (t *boltTx) List(prefix string, f func(key string, value StoredValue) error) error {
+	bucket := t.tx.Bucket(t.name)
+	if bucket == nil {
+		return nil
+	}
+
+	cursor := bucket.Cursor()
+	for k, v := cursor.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = cursor.Next() {
+		if v == nil || isExpired(t.expiry(string(k))) {
+			// nil values are sub-buckets
+			continue
+		}
+		if err := f(string(k), StoredValue(v)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *boltTx) expiry(key string) int64 {
+	expiries := t.tx.Bucket(boltExpiryBucket)
+	if expiries == nil {
+		return 0
+	}
+	bucket := expiries.Bucket(t.name)
+	if bucket == nil {
+		return 0
+	}
+	return decodeBoltExpiry(bucket.Get([]byte(key)))
+}
+
+func (t *boltTx) setExpiry(key string, expiry int64) error {
+	if expiry == 0 {
+		expiries := t.tx.Bucket(boltExpiryBucket)
+		if expiries == nil {
+			return nil
+		}
+		bucket := expiries.Bucket(t.name)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete([]byte(key))
+	}
+
+	expiries, err := t.tx.CreateBucketIfNotExists(boltExpiryBucket)
+	if err != nil {
+		return err
+	}
+	bucket, err := expiries.CreateBucketIfNotExists(t.name)
+	if err != nil {
+		return err
+	}
+
+	content := make([]byte, 8)
+	binary.BigEndian.PutUint64(content, uint64(expiry))
+	return bucket.Put([]byte(key), content)
+}
+
+func decodeBoltExpiry(content []byte) int64 {
+	if len(content) != 8 {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(content))
+}
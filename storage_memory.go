@@ -0,0 +1,164 @@
+package slick
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStorage is an in-memory StorageBackend, for tests and bots
+// which don't need persistence.
+type MemoryStorage struct {
+	lock       sync.Mutex
+	namespaces map[string]map[string]memoryValue
+}
+
+type memoryValue struct {
+	content []byte
+	expiry  int64
+}
+
+// NewMemoryStorage returns an empty MemoryStorage.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{namespaces: make(map[string]map[string]memoryValue)}
+}
+
+func (s *MemoryStorage) Namespace(name string) Store {
+	return &memoryStore{storage: s, name: name}
+}
+
+func (s *MemoryStorage) Close() error {
+	return nil
+}
+
+func (s *MemoryStorage) purgeExpired() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, values := range s.namespaces {
+		for key, value := range values {
+			if isExpired(value.expiry) {
+				delete(values, key)
+			}
+		}
+	}
+	return nil
+}
+
+type memoryStore struct {
+	storage *MemoryStorage
+	name    string
+}
+
+func (s *memoryStore) Get(key string, v interface{}) error {
+	var err error
+	s.view(func(tx *memoryTx) { err = tx.Get(key, v) })
+	return err
+}
+
+func (s *memoryStore) Put(key string, v interface{}) error {
+	return s.Update(func(tx StoreTx) error {
+		return tx.Put(key, v)
+	})
+}
+
+func (s *memoryStore) PutWithTTL(key string, v interface{}, ttl time.Duration) error {
+	return s.Update(func(tx StoreTx) error {
+		return tx.PutWithTTL(key, v, ttl)
+	})
+}
+
+func (s *memoryStore) Delete(key string) error {
+	return s.Update(func(tx StoreTx) error {
+		return tx.Delete(key)
+	})
+}
+
+// List calls `f` on a snapshot of the matching keys, without holding
+// the storage lock, so `f` can use the store.
+func (s *memoryStore) List(prefix string, f func(key string, value StoredValue) error) error {
+	snapshot := &memoryTx{values: make(map[string]memoryValue)}
+	s.view(func(tx *memoryTx) {
+		for key, value := range tx.values {
+			if strings.HasPrefix(key, prefix) {
+				snapshot.values[key] = value
+			}
+		}
+	})
+	return snapshot.List(prefix, f)
+}
+
+func (s *memoryStore) view(f func(tx *memoryTx)) {
+	s.storage.lock.Lock()
+	defer s.storage.lock.Unlock()
+
+	f(&memoryTx{values: s.storage.namespaces[s.name]})
+}
+
+// Update runs `f` on a copy of the namespace, which replaces it if `f`
+// succeeds.
+func (s *memoryStore) Update(f func(tx StoreTx) error) error {
+	s.storage.lock.Lock()
+	defer s.storage.lock.Unlock()
+
+	values := make(map[string]memoryValue)
+	for key, value := range s.storage.namespaces[s.name] {
+		values[key] = value
+	}
+
+	if err := f(&memoryTx{values: values}); err != nil {
+		return err
+	}
+
+	s.storage.namespaces[s.name] = values
+	return nil
+}
+
+type memoryTx struct {
+	values map[string]memoryValue
+}
+
+func (t *memoryTx) Get(key string, v interface{}) error {
+	value, ok := t.values[key]
+	if !ok || isExpired(value.expiry) {
+		return ErrNotFound
+	}
+	return json.Unmarshal(value.content, v)
+}
+
+func (t *memoryTx) Put(key string, v interface{}) error {
+	return t.PutWithTTL(key, v, 0)
+}
+
+func (t *memoryTx) PutWithTTL(key string, v interface{}, ttl time.Duration) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	t.values[key] = memoryValue{content: content, expiry: expiryTime(ttl)}
+	return nil
+}
+
+func (t *memoryTx) Delete(key string) error {
+	delete(t.values, key)
+	return nil
+}
+
+func (t *memoryTx) List(prefix string, f func(key string, value StoredValue) error) error {
+	var keys []string
+	for key, value := range t.values {
+		if strings.HasPrefix(key, prefix) && !isExpired(value.expiry) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := f(key, StoredValue(t.values[key].content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package slick
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SQLiteDriver is the database/sql driver of the "sqlite" storage.
+// Slick doesn't link a cgo driver itself: the bot's main package
+// registers it, with `import _ "github.com/mattn/go-sqlite3"`.
+const SQLiteDriver = "sqlite3"
+
+const sqlStorageSchema = `CREATE TABLE IF NOT EXISTS slick_storage (
+	namespace  TEXT NOT NULL,
+	key        TEXT NOT NULL,
+	value      BLOB NOT NULL,
+	expires_at INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (namespace, key)
+)`
+
+// SQLStorage is a database/sql StorageBackend, storing all the
+// namespaces in a `slick_storage` table. Its queries target SQLite:
+// the bot binary must import a driver, see SQLiteDriver.
+type SQLStorage struct {
+	DB *sql.DB
+}
+
+// OpenSQLStorage opens the database with `driver` and `dsn`, and
+// creates the `slick_storage` table if needed.
+func OpenSQLStorage(driver, dsn string) (*SQLStorage, error) {
+	if err := checkSQLDriver(driver); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	storage, err := NewSQLStorage(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return storage, nil
+}
+
+// checkSQLDriver tells when the bot's binary lacks the driver `name`,
+// instead of the bare error of database/sql.
+func checkSQLDriver(name string) error {
+	for _, driver := range sql.Drivers() {
+		if driver == name {
+			return nil
+		}
+	}
+	if name == SQLiteDriver {
+		return fmt.Errorf("the sqlite storage needs the %q database/sql driver: add `import _ \"github.com/mattn/go-sqlite3\"` to the bot's main package", name)
+	}
+	return fmt.Errorf("the %q database/sql driver isn't registered", name)
+}
+
+// NewSQLStorage uses an open database, and creates the
+// `slick_storage` table if needed.
+func NewSQLStorage(db *sql.DB) (*SQLStorage, error) {
+	if _, err := db.Exec(sqlStorageSchema); err != nil {
+		return nil, err
+	}
+	return &SQLStorage{DB: db}, nil
+}
+
+func (s *SQLStorage) Namespace(name string) Store {
+	return &sqlStore{db: s.DB, name: name}
+}
+
+func (s *SQLStorage) Close() error {
+	return s.DB.Close()
+}
+
+func (s *SQLStorage) purgeExpired() error {
+	_, err := s.DB.Exec(`DELETE FROM slick_storage WHERE expires_at != 0 AND expires_at <= ?`, time.Now().UnixNano())
+	return err
+}
+
+// sqlQuerier is implemented by both *sql.DB and *sql.Tx.
+type sqlQuerier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+type sqlStore struct {
+	db   *sql.DB
+	name string
+}
+
+func (s *sqlStore) tx() *sqlTx {
+	return &sqlTx{q: s.db, name: s.name}
+}
+
+func (s *sqlStore) Get(key string, v interface{}) error {
+	return s.tx().Get(key, v)
+}
+
+func (s *sqlStore) Put(key string, v interface{}) error {
+	return s.tx().Put(key, v)
+}
+
+func (s *sqlStore) PutWithTTL(key string, v interface{}, ttl time.Duration) error {
+	return s.tx().PutWithTTL(key, v, ttl)
+}
+
+func (s *sqlStore) Delete(key string) error {
+	return s.tx().Delete(key)
+}
+
+func (s *sqlStore) List(prefix string, f func(key string, value StoredValue) error) error {
+	return s.tx().List(prefix, f)
+}
+
+func (s *sqlStore) Update(f func(tx StoreTx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := f(&sqlTx{q: tx, name: s.name}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type sqlTx struct {
+	q    sqlQuerier
+	name string
+}
+
+func (t *sqlTx) Get(key string, v interface{}) error {
+	var content []byte
+	var expiry int64
+	err := t.q.QueryRow(`SELECT value, expires_at FROM slick_storage WHERE namespace = ? AND key = ?`, t.name, key).Scan(&content, &expiry)
+	if err == sql.ErrNoRows || (err == nil && isExpired(expiry)) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(content, v)
+}
+
+func (t *sqlTx) Put(key string, v interface{}) error {
+	return t.PutWithTTL(key, v, 0)
+}
+
+func (t *sqlTx) PutWithTTL(key string, v interface{}, ttl time.Duration) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.q.Exec(`INSERT OR REPLACE INTO slick_storage (namespace, key, value, expires_at) VALUES (?, ?, ?, ?)`, t.name, key, content, expiryTime(ttl))
+	return err
+}
+
+func (t *sqlTx) Delete(key string) error {
+	_, err := t.q.Exec(`DELETE FROM slick_storage WHERE namespace = ? AND key = ?`, t.name, key)
+	return err
+}
+
+func (t *sqlTx) List(prefix string, f func(key string, value StoredValue) error) error {
+	rows, err := t.q.Query(`SELECT key, value, expires_at FROM slick_storage WHERE namespace = ? AND substr(key, 1, length(?)) = ? ORDER BY key`, t.name, prefix, prefix)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var content []byte
+		var expiry int64
+		if err := rows.Scan(&key, &content, &expiry); err != nil {
+			return err
+		}
+		if isExpired(expiry) {
+			continue
+		}
+		if err := f(key, StoredValue(content)); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,203 @@
+package slick
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSQLDriver runs the queries of SQLStorage on a map, so the shared
+// store tests run against it without a cgo SQLite driver. Each DSN is
+// a database, and transactions work on a copy of it.
+type fakeSQLDriver struct {
+	lock      sync.Mutex
+	databases map[string]fakeSQLTable
+}
+
+type fakeSQLKey struct{ namespace, key string }
+
+type fakeSQLRow struct {
+	value  []byte
+	expiry int64
+}
+
+type fakeSQLTable map[fakeSQLKey]fakeSQLRow
+
+func (t fakeSQLTable) copy() fakeSQLTable {
+	out := make(fakeSQLTable, len(t))
+	for key, row := range t {
+		out[key] = row
+	}
+	return out
+}
+
+var testSQLDriver = &fakeSQLDriver{databases: make(map[string]fakeSQLTable)}
+
+func init() {
+	sql.Register("slick-fake-sql", testSQLDriver)
+}
+
+func (d *fakeSQLDriver) Open(dsn string) (driver.Conn, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.databases[dsn] == nil {
+		d.databases[dsn] = make(fakeSQLTable)
+	}
+	return &fakeSQLConn{driver: d, dsn: dsn}, nil
+}
+
+type fakeSQLConn struct {
+	driver *fakeSQLDriver
+	dsn    string
+	// tx is the copy of the table a transaction works on.
+	tx fakeSQLTable
+}
+
+func (c *fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeSQLStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeSQLConn) Close() error { return nil }
+
+func (c *fakeSQLConn) Begin() (driver.Tx, error) {
+	c.driver.lock.Lock()
+	c.tx = c.driver.databases[c.dsn].copy()
+	c.driver.lock.Unlock()
+	return c, nil
+}
+
+func (c *fakeSQLConn) Commit() error {
+	c.driver.lock.Lock()
+	c.driver.databases[c.dsn] = c.tx
+	c.driver.lock.Unlock()
+	c.tx = nil
+	return nil
+}
+
+func (c *fakeSQLConn) Rollback() error {
+	c.tx = nil
+	return nil
+}
+
+// table runs `f` on the table of the transaction, or of the database.
+func (c *fakeSQLConn) table(f func(table fakeSQLTable)) {
+	if c.tx != nil {
+		f(c.tx)
+		return
+	}
+	c.driver.lock.Lock()
+	defer c.driver.lock.Unlock()
+	f(c.driver.databases[c.dsn])
+}
+
+type fakeSQLStmt struct {
+	conn  *fakeSQLConn
+	query string
+}
+
+func (s *fakeSQLStmt) Close() error  { return nil }
+func (s *fakeSQLStmt) NumInput() int { return -1 }
+
+func (s *fakeSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	var affected int64
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE"):
+	case strings.HasPrefix(s.query, "INSERT OR REPLACE"):
+		s.conn.table(func(table fakeSQLTable) {
+			table[fakeSQLKey{args[0].(string), args[1].(string)}] = fakeSQLRow{args[2].([]byte), args[3].(int64)}
+			affected = 1
+		})
+	case strings.HasPrefix(s.query, "DELETE FROM slick_storage WHERE namespace"):
+		s.conn.table(func(table fakeSQLTable) {
+			key := fakeSQLKey{args[0].(string), args[1].(string)}
+			if _, ok := table[key]; ok {
+				delete(table, key)
+				affected = 1
+			}
+		})
+	case strings.HasPrefix(s.query, "DELETE FROM slick_storage WHERE expires_at"):
+		s.conn.table(func(table fakeSQLTable) {
+			for key, row := range table {
+				if row.expiry != 0 && row.expiry <= args[0].(int64) {
+					delete(table, key)
+					affected++
+				}
+			}
+		})
+	default:
+		return nil, errors.New("unexpected query: " + s.query)
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+func (s *fakeSQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows := &fakeSQLRows{}
+	switch {
+	case strings.HasPrefix(s.query, "SELECT value, expires_at"):
+		rows.columns = []string{"value", "expires_at"}
+		s.conn.table(func(table fakeSQLTable) {
+			if row, ok := table[fakeSQLKey{args[0].(string), args[1].(string)}]; ok {
+				rows.values = append(rows.values, []driver.Value{row.value, row.expiry})
+			}
+		})
+	case strings.HasPrefix(s.query, "SELECT key, value, expires_at"):
+		rows.columns = []string{"key", "value", "expires_at"}
+		s.conn.table(func(table fakeSQLTable) {
+			for key, row := range table {
+				if key.namespace == args[0].(string) && strings.HasPrefix(key.key, args[1].(string)) {
+					rows.values = append(rows.values, []driver.Value{key.key, row.value, row.expiry})
+				}
+			}
+		})
+		sort.Slice(rows.values, func(i, j int) bool {
+			return rows.values[i][0].(string) < rows.values[j][0].(string)
+		})
+	default:
+		return nil, errors.New("unexpected query: " + s.query)
+	}
+	return rows, nil
+}
+
+type fakeSQLRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeSQLRows) Columns() []string { return r.columns }
+func (r *fakeSQLRows) Close() error      { return nil }
+
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestSQLStorage(t *testing.T) {
+	backend, err := OpenSQLStorage("slick-fake-sql", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	testStorageBackend(t, backend)
+}
+
+func TestSQLStorageNeedsADriver(t *testing.T) {
+	_, err := OpenSQLStorage(SQLiteDriver, "slick.db")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "github.com/mattn/go-sqlite3")
+	}
+
+	config := SlackConfig{Storage: "sqlite", StorageDSN: "slick.db"}
+	assert.Error(t, config.Validate(), "the config check reports it too")
+}
@@ -0,0 +1,117 @@
+package slick
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/assert"
+)
+
+type storedThing struct {
+	Name  string
+	Count int
+}
+
+func testStorageBackend(t *testing.T, backend StorageBackend) {
+	store := backend.Namespace("things")
+	other := backend.Namespace("others")
+
+	var thing storedThing
+	assert.Equal(t, ErrNotFound, store.Get("a", &thing))
+
+	assert.NoError(t, store.Put("a:1", storedThing{"one", 1}))
+	assert.NoError(t, store.Put("a:2", storedThing{"two", 2}))
+	assert.NoError(t, store.Put("b:1", storedThing{"three", 3}))
+	assert.NoError(t, other.Put("a:3", storedThing{"other", 4}))
+
+	assert.NoError(t, store.Get("a:2", &thing))
+	assert.Equal(t, storedThing{"two", 2}, thing)
+
+	var keys []string
+	err := store.List("a:", func(key string, value StoredValue) error {
+		var thing storedThing
+		assert.NoError(t, value.Decode(&thing))
+		keys = append(keys, key+"="+thing.Name)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a:1=one", "a:2=two"}, keys)
+
+	assert.NoError(t, store.Delete("a:1"))
+	assert.NoError(t, store.Delete("missing"))
+	assert.Equal(t, ErrNotFound, store.Get("a:1", &thing))
+
+	// Failed transactions are rolled back
+	failure := errors.New("failure")
+	err = store.Update(func(tx StoreTx) error {
+		assert.NoError(t, tx.Put("a:2", storedThing{"changed", 0}))
+		return failure
+	})
+	assert.Equal(t, failure, err)
+	assert.NoError(t, store.Get("a:2", &thing))
+	assert.Equal(t, "two", thing.Name)
+
+	err = store.Update(func(tx StoreTx) error {
+		var thing storedThing
+		if err := tx.Get("a:2", &thing); err != nil {
+			return err
+		}
+		thing.Count++
+		return tx.Put("a:2", thing)
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, store.Get("a:2", &thing))
+	assert.Equal(t, 3, thing.Count)
+
+	// TTL keys
+	assert.NoError(t, store.PutWithTTL("temp", "soon gone", time.Millisecond))
+	assert.NoError(t, store.PutWithTTL("kept", "still here", time.Hour))
+	time.Sleep(5 * time.Millisecond)
+	var text string
+	assert.Equal(t, ErrNotFound, store.Get("temp", &text))
+	assert.NoError(t, store.Get("kept", &text))
+
+	assert.NoError(t, backend.(storageExpirer).purgeExpired())
+	assert.NoError(t, store.Get("kept", &text))
+	assert.Equal(t, "still here", text)
+}
+
+func TestMemoryStorage(t *testing.T) {
+	backend := NewMemoryStorage()
+	testStorageBackend(t, backend)
+
+	// List callbacks can use the store
+	store := backend.Namespace("things")
+	err := store.List("a:", func(key string, value StoredValue) error {
+		return store.Delete(key)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, ErrNotFound, store.Get("a:2", &storedThing{}))
+}
+
+func TestBoltStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slick-storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	backend, err := NewBoltStorage(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	testStorageBackend(t, backend)
+
+	// Namespaces are plain buckets of JSON values
+	backend.DB.View(func(tx *bolt.Tx) error {
+		assert.Equal(t, `{"Name":"two","Count":3}`, string(tx.Bucket([]byte("things")).Get([]byte("a:2"))))
+		return nil
+	})
+}
@@ -1,6 +1,7 @@
 package tabularasa
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"sync"
@@ -35,34 +36,35 @@ func (tabula *TabulaRasa) InitWebPlugin(bot *slick.Bot, privRouter *mux.Router,
 
 	privRouter.HandleFunc("/plugins/tabularasa", func(w http.ResponseWriter, r *http.Request) {
 
-		tabula.TabulaRasta()
+		// The tasks are updated after the request is served.
+		tabula.TabulaRasta(bot.Context())
 
 	})
 
 }
 
-func (tabula *TabulaRasa) TabulaRasta() {
+func (tabula *TabulaRasa) TabulaRasta(ctx context.Context) {
 
 	taskhose := make(chan asana.Task, 100)
 
 	wg := &sync.WaitGroup{}
 
-	users, err := tabula.asanaClient.GetUsers()
+	users, err := tabula.asanaClient.GetUsersContext(ctx)
 
 	if err != nil {
 		fmt.Println("anasa Client: ", err)
 		return
 	}
 
-	go tabula.SpinUpTaskWorker(taskhose)
-	go tabula.SpinUpTaskWorker(taskhose)
-	go tabula.SpinUpTaskWorker(taskhose)
-	go tabula.SpinUpTaskWorker(taskhose)
+	go tabula.SpinUpTaskWorker(ctx, taskhose)
+	go tabula.SpinUpTaskWorker(ctx, taskhose)
+	go tabula.SpinUpTaskWorker(ctx, taskhose)
+	go tabula.SpinUpTaskWorker(ctx, taskhose)
 
 	for _, user := range users {
 		wg.Add(1)
 		fmt.Println(user)
-		go tabula.GetFullTasksByAssignee(user, taskhose, wg)
+		go tabula.GetFullTasksByAssignee(ctx, user, taskhose, wg)
 
 	}
 
@@ -71,11 +73,11 @@ func (tabula *TabulaRasa) TabulaRasta() {
 
 }
 
-func (tabula *TabulaRasa) GetFullTasksByAssignee(user asana.User, taskhose chan asana.Task, wg *sync.WaitGroup) {
+func (tabula *TabulaRasa) GetFullTasksByAssignee(ctx context.Context, user asana.User, taskhose chan asana.Task, wg *sync.WaitGroup) {
 
 	defer wg.Done()
 
-	tasks, err := tabula.asanaClient.GetTasksByAssignee(user)
+	tasks, err := tabula.asanaClient.GetTasksByAssigneeContext(ctx, user)
 
 	if err != nil {
 		fmt.Println("Error acquiring task ids in GetFullTasksByAssignee", err)
@@ -84,7 +86,7 @@ func (tabula *TabulaRasa) GetFullTasksByAssignee(user asana.User, taskhose chan
 
 	for _, task := range tasks {
 
-		fulltask, err := tabula.asanaClient.GetTaskByID(task.ID)
+		fulltask, err := tabula.asanaClient.GetTaskByIDContext(ctx, task.ID)
 
 		if err != nil {
 			fmt.Println("Error acquiring full task GetFullTasksByAssignee", err)
@@ -96,13 +98,13 @@ func (tabula *TabulaRasa) GetFullTasksByAssignee(user asana.User, taskhose chan
 
 }
 
-func (tabula *TabulaRasa) SpinUpTaskWorker(taskhose chan asana.Task) {
+func (tabula *TabulaRasa) SpinUpTaskWorker(ctx context.Context, taskhose chan asana.Task) {
 
 	for task := range taskhose {
 		if task.Completed {
 			continue
 		}
-		updatedTask, err := tabula.asanaClient.UpdateTask("assignee=null", task)
+		updatedTask, err := tabula.asanaClient.UpdateTaskContext(ctx, "assignee=null", task)
 
 		if err != nil {
 			fmt.Println("Error updating task ", task)
@@ -0,0 +1,252 @@
+package slick
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// Team is a Slack workspace the bot is connected to, with its own
+// connection, users, channels, outgoing queues and storage namespaces.
+//
+// The Bot embeds its primary team, configured in the Slack section, so
+// that `bot.Users` or `bot.SendOutgoingMessage` are those of the
+// primary team. The other teams are configured by name in the Teams
+// section, with the connection settings of the Slack section:
+//
+//	"Teams": {
+//	  "acme": {"api_token": "xoxb-...", "join_channels": ["general"]},
+//	  "globex": {"connection_mode": "socket", "api_token": "xoxb-...", "app_token": "xapp-..."}
+//	}
+//
+// Every Message carries its Team: reply with the Message, and use
+// `msg.Workspace().Storage` to keep the data of the teams apart.
+type Team struct {
+	// ID is the Slack ID of the team, known once connected.
+	ID string
+	// Name is the key of the team in the Teams section, or "" for the
+	// primary team.
+	Name string
+
+	// config is read with Config, and replaced when the config is
+	// reloaded.
+	configLock sync.RWMutex
+	config     SlackConfig
+
+	// Slack connectivity
+	Slack *slack.Client
+	// Transport carries events in and messages out. It defaults to
+	// the RTM websocket when left nil before `Run()`.
+	Transport Transport
+	// Users and Channels are kept up to date by the event loop, and
+	// can be read from any goroutine.
+	Users    *UserDirectory
+	Channels *ChannelDirectory
+	Myself   slack.UserDetails
+
+	bot           *Bot
+	outgoingMsgCh chan *Reply
+	outgoing      *outgoing
+	userGroups    userGroups
+	// pluginScopes are the scopes of the plugins, by package, resolved
+	// for the team. They are guarded by the bot's pluginsLock.
+	pluginScopes map[string]pluginScope
+
+	// connected is set by the first ConnectedEvent, to count the
+	// reconnections. Only the event loop uses it.
+	connected bool
+}
+
+// teamEvent is an event of one of the secondary teams.
+type teamEvent struct {
+	team  *Team
+	event slack.RTMEvent
+}
+
+func newTeam(bot *Bot, name string) *Team {
+	team := &Team{
+		Name:          name,
+		Users:         NewUserDirectory(),
+		Channels:      NewChannelDirectory(),
+		bot:           bot,
+		outgoingMsgCh: make(chan *Reply, 500),
+	}
+	team.outgoing = newOutgoing(team)
+	team.Users.fetch = team.fetchUser
+	team.Channels.fetch = team.fetchChannel
+	return team
+}
+
+// Config returns the current config of the team. Reloading the config
+// replaces it, so read it again rather than keeping a copy around.
+func (team *Team) Config() SlackConfig {
+	team.configLock.RLock()
+	defer team.configLock.RUnlock()
+	return team.config
+}
+
+func (team *Team) setConfig(config SlackConfig) {
+	team.configLock.Lock()
+	defer team.configLock.Unlock()
+	team.config = config
+}
+
+// IsPrimary tells if the team is the one configured in the Slack
+// section, embedded in the Bot.
+func (team *Team) IsPrimary() bool {
+	return team.bot == nil || team == team.bot.Team
+}
+
+func (team *Team) String() string {
+	if team.Name == "" {
+		return team.ID
+	}
+	return fmt.Sprintf("%s (%s)", team.Name, team.ID)
+}
+
+// Storage returns the Store of a namespace for this team. The primary
+// team uses the namespace as is, like `Bot.Storage`, and the others
+// prefix it with their name, like "acme:todo".
+func (team *Team) Storage(namespace string) Store {
+	if team.IsPrimary() {
+		return team.bot.Storage(namespace)
+	}
+	return team.bot.Storage(team.Name + ":" + namespace)
+}
+
+// injectEvent feeds an event to the event loop, as if it came from the
+// team's Transport.
+func (team *Team) injectEvent(event slack.RTMEvent) {
+	if team.IsPrimary() {
+		team.bot.internalEvents <- event
+		return
+	}
+	team.bot.teamEvents <- teamEvent{team: team, event: event}
+}
+
+// forwardEvents feeds the events of a secondary team's Transport to the
+// event loop, until the bot stops.
+func (team *Team) forwardEvents() {
+	for {
+		select {
+		case event := <-team.Transport.IncomingEvents():
+			select {
+			case team.bot.teamEvents <- teamEvent{team: team, event: event}:
+			case <-team.bot.stopCh:
+				return
+			}
+		case <-team.bot.stopCh:
+			return
+		}
+	}
+}
+
+// Teams returns the teams the bot is connected to, the primary one
+// first.
+func (bot *Bot) Teams() []*Team {
+	return append([]*Team{bot.Team}, bot.teams...)
+}
+
+// TeamByID returns the team with the Slack ID `id`, or nil.
+func (bot *Bot) TeamByID(id string) *Team {
+	for _, team := range bot.Teams() {
+		if team.ID == id {
+			return team
+		}
+	}
+	return nil
+}
+
+// TeamByName returns the team named `name` in the Teams section, the
+// primary team for "", or nil.
+func (bot *Bot) TeamByName(name string) *Team {
+	for _, team := range bot.Teams() {
+		if team.Name == name {
+			return team
+		}
+	}
+	return nil
+}
+
+// Listen registers a Listener restricted to the messages and events
+// of the team, see `Bot.Listen`.
+func (team *Team) Listen(listen *Listener) error {
+	listen.team = team
+	return team.bot.Listen(listen)
+}
+
+// setupTeams creates the secondary teams of the Teams section, with
+// their Slack client and Transport.
+func (bot *Bot) setupTeams(configs map[string]SlackConfig) {
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		team := newTeam(bot, name)
+		team.setConfig(configs[name])
+		team.Slack = slack.New(configs[name].ApiToken)
+		team.Slack.SetDebug(bot.Config().Debug)
+		team.Transport = &auditTransport{Transport: team.newTransport(), team: team}
+		bot.teams = append(bot.teams, team)
+
+		log.WithFields(log.Fields{
+			"Type": "Team",
+			"Team": name,
+		}).Info("Connecting to another team.")
+	}
+}
+
+// checkTeams checks the Teams section of the config, and appends the
+// errors found to `errs`.
+func checkTeams(configs map[string]SlackConfig, errs ConfigErrors) ConfigErrors {
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prefix := "teams." + name
+		config := configs[name]
+		errs = checkFields(prefix, reflect.ValueOf(&config).Elem(), errs)
+		if err := config.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", prefix, err))
+		}
+		if config.ConnectionMode == "events" {
+			errs = append(errs, fmt.Errorf("%s: only the Slack section can use the Events API, use \"rtm\" or \"socket\"", prefix))
+		}
+	}
+	return errs
+}
+
+// checkTeamConfigs warns when a reloaded config changes the Teams
+// section, which needs a restart.
+func (bot *Bot) checkTeamConfigs(configs map[string]SlackConfig) {
+	current := make(map[string]SlackConfig)
+	for _, team := range bot.teams {
+		current[team.Name] = team.Config()
+	}
+	if len(configs) == 0 && len(current) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(configs, current) {
+		log.WithFields(log.Fields{
+			"Type": "Team",
+		}).Warn("Changing the Teams section needs a restart, ignoring it.")
+	}
+}
+
+// connectTeams connects the secondary teams.
+func (bot *Bot) connectTeams() {
+	for _, team := range bot.teams {
+		go team.forwardEvents()
+		go team.Transport.Connect()
+	}
+}
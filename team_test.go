@@ -0,0 +1,105 @@
+package slick
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeams(t *testing.T) {
+	bot := New("")
+	bot.StorageBackend = NewMemoryStorage()
+	bot.Transport = newFakeTransport()
+	bot.Users.Set(slack.User{ID: "U1", Name: "bob"})
+
+	acme := newTeam(bot, "acme")
+	acme.ID = "TACME"
+	transport := newFakeTransport()
+	acme.Transport = transport
+	acme.Myself = slack.UserDetails{ID: "UACMEBOT"}
+	acme.Users.Set(slack.User{ID: "U1", Name: "wile"})
+	acme.Channels.Set(Channel{ID: "C1", Name: "anvils", IsChannel: true})
+	bot.teams = append(bot.teams, acme)
+
+	assert.Equal(t, []*Team{bot.Team, acme}, bot.Teams())
+	assert.Equal(t, acme, bot.TeamByID("TACME"))
+	assert.True(t, bot.IsPrimary())
+	assert.False(t, acme.IsPrimary())
+
+	// Messages carry their team
+	messages := make(chan *Message, 1)
+	bot.listeners = []*Listener{
+		{MessageHandlerFunc: func(_ *Listener, msg *Message) { messages <- msg }},
+		{team: bot.Team, MessageHandlerFunc: func(*Listener, *Message) { t.Error("got the event of another team") }},
+	}
+	acme.handleRTMEvent(messageEvent("C1", "<@UACMEBOT> hello"))
+	waitListeners(t, bot)
+
+	msg := <-messages
+	assert.Equal(t, acme, msg.Workspace())
+	assert.Equal(t, "wile", msg.FromUser.Name, "users are the team's")
+	assert.Equal(t, "anvils", msg.FromChannel.Name, "channels are the team's")
+	assert.True(t, msg.MentionsMe)
+
+	// Replies go out through their team's Transport
+	go acme.replyHandler()
+	defer close(bot.stopCh)
+	msg.Reply("hi")
+	select {
+	case sent := <-transport.sent:
+		assert.Equal(t, "hi", sent.Text)
+		assert.Equal(t, "C1", sent.Channel)
+	case <-time.After(time.Second):
+		t.Error("the reply wasn't sent through the team's Transport")
+	}
+	assert.Len(t, bot.Transport.(*fakeTransport).sent, 0)
+
+	// Storage namespaces are the team's
+	assert.NoError(t, acme.Storage("todos").Put("C1", "acme"))
+	assert.NoError(t, bot.Team.Storage("todos").Put("C1", "primary"))
+
+	var value string
+	assert.NoError(t, bot.Storage("acme:todos").Get("C1", &value))
+	assert.Equal(t, "acme", value)
+	assert.NoError(t, bot.Storage("todos").Get("C1", &value))
+	assert.Equal(t, "primary", value)
+
+	// Each team's first connection isn't a reconnection
+	bot.countConnection(bot.Team)
+	bot.countConnection(acme)
+	assert.Empty(t, bot.metrics.reconnects.collect())
+	bot.countConnection(acme)
+	assert.Equal(t, 1.0, bot.metrics.reconnects.collect()[0].value)
+
+	// Listeners and jobs can belong to a team
+	assert.Equal(t, acme, bot.TeamByName("acme"))
+	assert.Equal(t, bot.Team, bot.TeamByName(""))
+	listen := &Listener{MessageHandlerFunc: func(*Listener, *Message) {}}
+	assert.NoError(t, acme.Listen(listen))
+	assert.Equal(t, acme, (<-bot.addListenerCh).team)
+
+	bot.Scheduler.Schedule(&Job{ID: "primary", At: time.Now().Add(time.Hour), Func: func(*Job) {}})
+	bot.Scheduler.Schedule(&Job{ID: "acme", Team: "acme", At: time.Now().Add(time.Hour), Func: func(*Job) {}})
+	jobs := &Message{Msg: &slack.Msg{Text: "!jobs", Channel: "C1", User: "U1"}, bot: bot, team: acme}
+	bot.Scheduler.jobsCommand(&Command{Name: "jobs"}, jobs, CommandArgs{})
+	select {
+	case sent := <-transport.sent:
+		assert.Contains(t, sent.Text, "`acme`")
+		assert.NotContains(t, sent.Text, "`primary`", "!jobs lists the jobs of its team")
+	case <-time.After(time.Second):
+		t.Error("no reply to !jobs")
+	}
+
+	// Secondary teams can't use the Events API
+	errs := checkTeams(map[string]SlackConfig{
+		"acme":    {ApiToken: "xoxb-1"},
+		"globex":  {ApiToken: "xoxb-2", ConnectionMode: "events", SigningSecret: "s"},
+		"initech": {},
+	}, nil)
+	if assert.Len(t, errs, 2) {
+		assert.Contains(t, errs[0].Error(), "teams.globex")
+		assert.Contains(t, errs[1].Error(), "teams.initech.api_token")
+	}
+}
@@ -1,15 +1,11 @@
 package todo
 
 import (
-	log "github.com/sirupsen/logrus"
-
 	"github.com/CapstoneLabs/slick"
-	"github.com/boltdb/bolt"
 )
 
 type Plugin struct {
-	bot   *slick.Bot
-	store Store
+	bot *slick.Bot
 }
 
 func init() {
@@ -18,15 +14,5 @@ func init() {
 
 func (p *Plugin) InitPlugin(bot *slick.Bot) {
 	p.bot = bot
-
-	err := bot.DB.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists(bucketName)
-		return err
-	})
-	if err != nil {
-		log.Fatalln("Couldn't create the `todos` bucket")
-	}
-
-	p.store = &boltStore{db: bot.DB}
 	p.listenTodo()
 }
@@ -1,49 +1,37 @@
 package todo
 
 import (
-	"encoding/json"
-
-	log "github.com/sirupsen/logrus"
-
-	"github.com/boltdb/bolt"
+	"github.com/CapstoneLabs/slick"
 )
 
 type Store interface {
-	Get(channel string) Todo
-	Put(channel string, t Todo)
+	Get(channel string) (Todo, error)
+	Put(channel string, t Todo) error
 }
 
-type boltStore struct {
-	db *bolt.DB
+// namespace is also the name of the BoltDB bucket the todos were
+// stored in before the storage API.
+const namespace = "todos"
+
+// storeFor returns the Store of the team `msg` comes from, so each team
+// has its own todos.
+func (p *Plugin) storeFor(msg *slick.Message) Store {
+	return &slickStore{store: msg.Workspace().Storage(namespace)}
 }
 
-var bucketName = []byte("todos")
+type slickStore struct {
+	store slick.Store
+}
 
-func (s *boltStore) Get(channel string) (t Todo) {
-	err := s.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(bucketName))
-		return json.Unmarshal(b.Get([]byte(channel)), &t)
-	})
-	if err != nil {
-		return make(Todo, 0)
+func (s *slickStore) Get(channel string) (Todo, error) {
+	t := make(Todo, 0)
+	err := s.store.Get(channel, &t)
+	if err == slick.ErrNotFound {
+		return t, nil
 	}
-	return
+	return t, err
 }
 
-func (s *boltStore) Put(channel string, t Todo) {
-
-	err := s.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(bucketName))
-		cnt, err := json.Marshal(t)
-		if err != nil {
-			return err
-		}
-
-		b.Put([]byte(channel), cnt)
-
-		return nil
-	})
-	if err != nil {
-		log.Println("ERROR saving Todo:", err)
-	}
+func (s *slickStore) Put(channel string, t Todo) error {
+	return s.store.Put(channel, t)
 }
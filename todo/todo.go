@@ -11,49 +11,62 @@ import (
 	"time"
 
 	"github.com/CapstoneLabs/slick"
+	log "github.com/sirupsen/logrus"
 )
 
+// PermissionScratchAny is needed to scratch the tasks created by
+// others.
+const PermissionScratchAny = "todo.scratch-any"
+
 func (p *Plugin) listenTodo() {
-	p.bot.Listen(&slick.Listener{
-		Matches:            regexp.MustCompile(`^!todo.*`),
-		MessageHandlerFunc: p.handleTodo,
+	p.bot.Command(&slick.Command{
+		Name: "todo",
+		Args: []slick.CommandArg{
+			{Name: "action", Type: slick.ArgWord, Optional: true},
+			{Name: "text", Type: slick.ArgRest, Optional: true},
+		},
+		Usage:       "lists the tasks of the channel, or runs `add`, `scratch`, `append` or `help` on them",
+		HandlerFunc: p.handleTodo,
 	})
 }
 
-func (p *Plugin) handleTodo(listen *slick.Listener, msg *slick.Message) {
-
-	idFormat := regexp.MustCompile(`^[a-z]{2}$`)
-	match := msg.Match
-	parts := strings.Split(match[0], " ")
-	if len(parts) == 1 {
+func (p *Plugin) handleTodo(_ *slick.Command, msg *slick.Message, args slick.CommandArgs) {
+	if !args.Has("action") {
 		p.listTasks(msg)
 		return
 	}
-	act := parts[1]
+	act := args.String("action")
+	text := args.String("text")
+
+	idFormat := regexp.MustCompile(`^[a-z]{2}$`)
+	id, rest := text, ""
+	if idx := strings.IndexAny(text, " \t\n"); idx != -1 {
+		id, rest = text[:idx], strings.TrimSpace(text[idx+1:])
+	}
 
 	switch act {
 	case "add":
-		if len(parts) < 2 {
+		if text == "" {
 			msg.ReplyMention("Add a task with `!todo add [some text]`")
 			return
 		}
-		p.createTask(msg, strings.Join(parts[2:], " "))
+		p.createTask(msg, text)
 
 	case "scratch":
-		if len(parts) < 3 || !idFormat.MatchString(parts[2]) {
+		if !idFormat.MatchString(id) {
 			msg.ReplyMention(fmt.Sprintf("Please %s a task with `!todo %s ID`", act, act))
 			return
 		}
 
-		p.deleteTask(msg, parts[2], false)
+		p.deleteTask(msg, id, rest, false)
 
 	case "append":
-		if len(parts) < 4 || !idFormat.MatchString(parts[2]) {
+		if rest == "" || !idFormat.MatchString(id) {
 			msg.ReplyMention(fmt.Sprintf("Please %s a task with `!todo %s ID [more notes]`", act, act))
 			return
 		}
 
-		p.appendToTask(msg, parts[2], strings.Join(parts[3:], " "))
+		p.appendToTask(msg, id, rest)
 
 	case "help":
 		p.replyHelp(msg, "")
@@ -68,7 +81,11 @@ func (p *Plugin) handleTodo(listen *slick.Listener, msg *slick.Message) {
 }
 
 func (p *Plugin) detailTask(msg *slick.Message, id string) {
-	todo := p.store.Get(msg.Channel)
+	todo, err := p.storeFor(msg).Get(msg.Channel)
+	if err != nil {
+		p.replyStorageError(msg, err)
+		return
+	}
 	index, err := getTaskIndex(id, todo)
 	if err != nil {
 		msg.ReplyMention("Task not found...")
@@ -79,11 +96,15 @@ func (p *Plugin) detailTask(msg *slick.Message, id string) {
 }
 
 func printTaskDetails(task *Task) string {
-	return fmt.Sprintf("%s\n> Created %s by <@%s>", task.String(), task.CreatedAt.Format("2006-01-02 15:04:05"), task.CreatedBy)
+	return fmt.Sprintf("%s\n> Created %s by %s", task.String(), task.CreatedAt.Format("2006-01-02 15:04:05"), slick.MentionUser(task.CreatedBy))
 }
 
 func (p *Plugin) createTask(msg *slick.Message, content string) {
-	todo := p.store.Get(msg.Channel)
+	todo, err := p.storeFor(msg).Get(msg.Channel)
+	if err != nil {
+		p.replyStorageError(msg, err)
+		return
+	}
 
 	if len(todo) > 600 {
 		msg.ReplyMention("Gosh you have over 600 tasks!!! Clean some up first.")
@@ -94,16 +115,25 @@ func (p *Plugin) createTask(msg *slick.Message, content string) {
 	task := &Task{
 		ID:        id,
 		CreatedAt: time.Now(),
-		// CreatedBy: msg.FromUser.ID,
-		Text: []string{content},
+		Text:      []string{content},
+	}
+	if msg.FromUser != nil {
+		task.CreatedBy = msg.FromUser.ID
 	}
 	todo = append(todo, task)
-	p.store.Put(msg.Channel, todo)
+	if err := p.storeFor(msg).Put(msg.Channel, todo); err != nil {
+		p.replyStorageError(msg, err)
+		return
+	}
 	msg.ReplyMention("added: " + task.String())
 }
 
 func (p *Plugin) appendToTask(msg *slick.Message, id, text string) {
-	todo := p.store.Get(msg.Channel)
+	todo, err := p.storeFor(msg).Get(msg.Channel)
+	if err != nil {
+		p.replyStorageError(msg, err)
+		return
+	}
 	index, err := getTaskIndex(id, todo)
 	if err != nil {
 		msg.ReplyMention("Task not found...")
@@ -112,13 +142,20 @@ func (p *Plugin) appendToTask(msg *slick.Message, id, text string) {
 
 	task := todo[index]
 	task.Text = append(task.Text, strings.Split(text, " // ")...)
-	p.store.Put(msg.Channel, todo)
+	if err := p.storeFor(msg).Put(msg.Channel, todo); err != nil {
+		p.replyStorageError(msg, err)
+		return
+	}
 
 	msg.ReplyMention("updated " + task.String())
 }
 
 func (p *Plugin) listTasks(msg *slick.Message) {
-	todo := p.store.Get(msg.Channel)
+	todo, err := p.storeFor(msg).Get(msg.Channel)
+	if err != nil {
+		p.replyStorageError(msg, err)
+		return
+	}
 	sort.Sort(byID(todo))
 
 	var answer []string
@@ -131,7 +168,7 @@ func (p *Plugin) listTasks(msg *slick.Message) {
 		}
 	}
 	if len(toDelete) != 0 {
-		p.deleteTask(msg, strings.Join(toDelete, ","), true)
+		p.deleteTask(msg, strings.Join(toDelete, ","), "", true)
 	}
 	if len(answer) == 0 {
 		msg.ReplyMention("Nothing to do... Coffee time?")
@@ -140,13 +177,16 @@ func (p *Plugin) listTasks(msg *slick.Message) {
 	}
 }
 
-func (p *Plugin) deleteTask(msg *slick.Message, ids string, silent bool) {
-	todo := p.store.Get(msg.Channel)
+func (p *Plugin) deleteTask(msg *slick.Message, ids, closingNotes string, silent bool) {
+	todo, err := p.storeFor(msg).Get(msg.Channel)
+	if err != nil {
+		p.replyStorageError(msg, err)
+		return
+	}
 
-	parts := strings.Split(msg.Match[0], " ")
-	var closingNotes string
-	if len(parts) > 3 {
-		closingNotes = strings.Join(parts[3:], " ")
+	// Scratching the tasks of others needs a permission
+	if !silent && !p.ownsTasks(msg, ids, todo) && !p.bot.RequirePermission(msg, PermissionScratchAny) {
+		return
 	}
 
 	var out []string
@@ -167,11 +207,46 @@ func (p *Plugin) deleteTask(msg *slick.Message, ids string, silent bool) {
 		}
 	}
 
-	p.store.Put(msg.Channel, todo)
+	entry := slick.AuditEntry{
+		Action:  "todo.scratch",
+		Channel: msg.Channel,
+		Target:  ids,
+		Outcome: slick.AuditOK,
+		Team:    msg.Workspace().Name,
+	}
+	if msg.FromUser != nil {
+		entry.Actor = msg.FromUser.ID
+	}
+	err = p.storeFor(msg).Put(msg.Channel, todo)
+	if err != nil {
+		entry.Outcome, entry.Error = slick.AuditFailed, err.Error()
+	}
+	if !silent {
+		p.bot.Audit(msg.Context(), entry)
+	}
+	if err != nil {
+		p.replyStorageError(msg, err)
+		return
+	}
 
 	msg.Reply(strings.Join(out, "\n"))
 }
 
+// ownsTasks tells if the author of `msg` created all the tasks `ids`.
+// Tasks without a creator belong to everyone.
+func (p *Plugin) ownsTasks(msg *slick.Message, ids string, todo Todo) bool {
+	for _, id := range strings.Split(ids, ",") {
+		index, err := getTaskIndex(id, todo)
+		if err != nil || todo[index].CreatedBy == "" {
+			continue
+		}
+		if msg.FromUser == nil || todo[index].CreatedBy != msg.FromUser.ID {
+			return false
+		}
+	}
+	return true
+}
+
 func getTaskIndex(id string, todo Todo) (int, error) {
 	for i, task := range todo {
 		if task.ID == id {
@@ -221,3 +296,8 @@ func idInList(id string, todo Todo) bool {
 	}
 	return false
 }
+
+func (p *Plugin) replyStorageError(msg *slick.Message, err error) {
+	log.WithError(err).Error("todo: storage error")
+	msg.ReplyMention("sorry, I couldn't access the todo list: %s", err)
+}
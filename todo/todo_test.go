@@ -0,0 +1,82 @@
+package todo
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/CapstoneLabs/slick/slicktest"
+)
+
+func TestTodoFlow(t *testing.T) {
+	h := slicktest.New(t)
+	defer h.Close()
+
+	h.Post("alice", "#general", "!todo")
+	h.ExpectMessage("#general", "Nothing to do")
+
+	h.Post("alice", "#general", "!todo add buy milk")
+	added := h.ExpectMessage("#general", "added: `")
+	id := regexp.MustCompile("`([a-z]{2})`").FindStringSubmatch(added.Text)[1]
+
+	h.Post("bob", "#general", "!todo append "+id+" and bread")
+	h.ExpectMessage("#general", "buy milk // and bread")
+
+	h.Post("bob", "#random", "!todo")
+	h.ExpectMessage("#random", "Nothing to do")
+
+	h.Post("bob", "#general", "!todo scratch "+id+" done")
+	h.ExpectMessage("#general", "~buy milk // and bread~ _done_")
+
+	h.Post("alice", "#general", "!todo")
+	h.ExpectMessage("#general", "Nothing to do")
+}
+
+func TestTodoScratchPermission(t *testing.T) {
+	h := slicktest.NewWithOptions(t, slicktest.Options{
+		Config: map[string]interface{}{
+			"Roles": map[string]interface{}{
+				"admins": map[string]interface{}{"permissions": []string{"todo.*"}, "users": []string{"UALICE"}},
+			},
+		},
+	})
+	defer h.Close()
+
+	h.Post("bob", "#general", "!todo add water plants")
+	added := h.ExpectMessage("#general", "added: `")
+	id := regexp.MustCompile("`([a-z]{2})`").FindStringSubmatch(added.Text)[1]
+
+	h.Post("bob", "#general", "!todo add feed cat")
+	other := h.ExpectMessage("#general", "added: `")
+	otherID := regexp.MustCompile("`([a-z]{2})`").FindStringSubmatch(other.Text)[1]
+
+	h.Post("bob", "#general", "!todo scratch "+id)
+	h.ExpectMessage("#general", "~water plants~")
+
+	h.Post("alice", "#general", "!todo add buy milk")
+	milk := h.ExpectMessage("#general", "added: `")
+	milkID := regexp.MustCompile("`([a-z]{2})`").FindStringSubmatch(milk.Text)[1]
+
+	h.Post("bob", "#general", "!todo scratch "+milkID)
+	h.ExpectMessage("#general", "you need the `todo.scratch-any` permission")
+
+	h.Post("alice", "#general", "!todo scratch "+otherID)
+	h.ExpectMessage("#general", "~feed cat~")
+}
+
+func TestTodoDeniedChannel(t *testing.T) {
+	h := slicktest.NewWithOptions(t, slicktest.Options{
+		Config: map[string]interface{}{
+			"Plugins": map[string]interface{}{
+				"todo": map[string]interface{}{"deny_channels": []string{"random"}},
+			},
+		},
+	})
+	defer h.Close()
+
+	h.Post("alice", "#random", "!todo")
+	h.ExpectNoMessage(200 * time.Millisecond)
+
+	h.Post("alice", "#general", "!todo")
+	h.ExpectMessage("#general", "Nothing to do")
+}
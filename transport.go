@@ -0,0 +1,140 @@
+package slick
+
+import (
+	"context"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// Transport is the link between the Bot and Slack. It connects, receives
+// events, and carries out all the write operations the bot needs to
+// reply to users.
+//
+// The default Transport is the RTM websocket (see NewRTMTransport),
+// but anything that can feed `slack.RTMEvent`s and send messages can
+// be plugged in `Bot.Transport` before calling `Run()`, like an
+// in-memory fake in tests.
+//
+// Transports receiving events over HTTP also implement `http.Handler`,
+// and are mounted on the WebServer's public router at EventsAPIPath.
+type Transport interface {
+	// Connect establishes the connection, and keeps it alive until
+	// Disconnect is called. It blocks for the lifetime of the
+	// connection.
+	Connect()
+
+	// Disconnect closes the connection, and makes Connect return.
+	Disconnect() error
+
+	// IncomingEvents is the stream of events received by the
+	// transport. Acknowledgements of sent messages must come through
+	// as `*slack.AckMessage`, with the ID of the sent message in
+	// `ReplyTo`.
+	IncomingEvents() <-chan slack.RTMEvent
+
+	// NewOutgoingMessage prepares a message, with a unique ID, to be
+	// sent to `channel` with SendMessage.
+	NewOutgoingMessage(text string, channel string) *slack.OutgoingMessage
+
+	// SendMessage sends a message prepared by NewOutgoingMessage.
+	SendMessage(msg *slack.OutgoingMessage)
+
+	// UpdateMessage replaces the text of a message previously sent.
+	// The calls below give up when `ctx` is done.
+	UpdateMessage(ctx context.Context, channel, timestamp, text string) error
+
+	// DeleteMessage removes a message previously sent.
+	DeleteMessage(ctx context.Context, channel, timestamp string) error
+
+	// AddReaction adds an emoji reaction to an item.
+	AddReaction(ctx context.Context, name string, item slack.ItemRef) error
+
+	// RemoveReaction removes an emoji reaction from an item.
+	RemoveReaction(ctx context.Context, name string, item slack.ItemRef) error
+
+	// OpenIMChannel opens a direct conversation with a user, and
+	// returns its channel ID.
+	OpenIMChannel(ctx context.Context, user string) (string, error)
+}
+
+// newTransport returns the Transport for the configured
+// `connection_mode`.
+func (team *Team) newTransport() Transport {
+	switch team.Config().ConnectionMode {
+	case "", "rtm":
+		return NewRTMTransport(team.Slack)
+	case "socket":
+		if team.Config().AppToken == "" {
+			log.Fatalln("Socket Mode needs an app_token in the Slack config.")
+		}
+		return NewSocketModeTransport(team.Slack, team.Config().AppToken)
+	case "events":
+		if team.Config().SigningSecret == "" {
+			log.Fatalln("The Events API needs a signing_secret in the Slack config.")
+		}
+		return NewEventsAPITransport(team.Slack, team.Config().SigningSecret)
+	}
+
+	log.Fatalf("Unknown connection_mode %q, use one of \"rtm\", \"socket\" or \"events\".", team.Config().ConnectionMode)
+	return nil
+}
+
+// rtmTransport is the Transport over Slack's RTM websocket. Writes
+// other than messages go through the Web API.
+type rtmTransport struct {
+	client *slack.Client
+	rtm    *slack.RTM
+}
+
+// NewRTMTransport returns a Transport using Slack's RTM websocket API
+// with the given client.
+func NewRTMTransport(client *slack.Client) Transport {
+	return &rtmTransport{
+		client: client,
+		rtm:    client.NewRTM(),
+	}
+}
+
+func (t *rtmTransport) Connect() {
+	t.rtm.ManageConnection()
+}
+
+func (t *rtmTransport) Disconnect() error {
+	return t.rtm.Disconnect()
+}
+
+func (t *rtmTransport) IncomingEvents() <-chan slack.RTMEvent {
+	return t.rtm.IncomingEvents
+}
+
+func (t *rtmTransport) NewOutgoingMessage(text string, channel string) *slack.OutgoingMessage {
+	return t.rtm.NewOutgoingMessage(text, channel)
+}
+
+func (t *rtmTransport) SendMessage(msg *slack.OutgoingMessage) {
+	t.rtm.SendMessage(msg)
+}
+
+func (t *rtmTransport) UpdateMessage(ctx context.Context, channel, timestamp, text string) error {
+	_, _, _, err := t.client.UpdateMessageContext(ctx, channel, timestamp, text)
+	return err
+}
+
+func (t *rtmTransport) DeleteMessage(ctx context.Context, channel, timestamp string) error {
+	_, _, err := t.client.DeleteMessageContext(ctx, channel, timestamp)
+	return err
+}
+
+func (t *rtmTransport) AddReaction(ctx context.Context, name string, item slack.ItemRef) error {
+	return t.client.AddReactionContext(ctx, name, item)
+}
+
+func (t *rtmTransport) RemoveReaction(ctx context.Context, name string, item slack.ItemRef) error {
+	return t.client.RemoveReactionContext(ctx, name, item)
+}
+
+func (t *rtmTransport) OpenIMChannel(ctx context.Context, user string) (string, error) {
+	_, _, channelID, err := t.client.OpenIMChannelContext(ctx, user)
+	return channelID, err
+}
@@ -0,0 +1,147 @@
+package slick
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// EventsAPIPath is where the Events API transport receives Slack's
+// requests, on the WebServer's public router.
+const EventsAPIPath = "/public/slack/events"
+
+// eventsAPIMaxAge is how old a signed request can be before it is
+// refused, to prevent replays.
+const eventsAPIMaxAge = 5 * time.Minute
+
+// eventsAPITransport receives events over HTTP, from Slack's Events
+// API. It is an `http.Handler`, mounted by the Bot on EventsAPIPath.
+// Writes go through the Web API.
+type eventsAPITransport struct {
+	webAPITransport
+	signingSecret string
+
+	done     chan bool
+	doneOnce sync.Once
+}
+
+// NewEventsAPITransport returns a Transport receiving events from the
+// Events API, verified with the app's `signingSecret`. It needs a
+// WebServer plugin to receive the requests.
+func NewEventsAPITransport(client *slack.Client, signingSecret string) Transport {
+	return &eventsAPITransport{
+		webAPITransport: newWebAPITransport(client),
+		signingSecret:   signingSecret,
+		done:            make(chan bool),
+	}
+}
+
+func (t *eventsAPITransport) Connect() {
+	for attempt := 1; ; attempt++ {
+		err := t.connected()
+		if err == nil {
+			break
+		}
+
+		t.events <- slack.RTMEvent{Type: "connection_error", Data: &slack.ConnectionErrorEvent{
+			Attempt:  attempt,
+			ErrorObj: err,
+		}}
+
+		select {
+		case <-t.done:
+			return
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+
+	<-t.done
+	t.events <- slack.RTMEvent{Type: "disconnected", Data: &slack.DisconnectedEvent{Intentional: true}}
+}
+
+func (t *eventsAPITransport) Disconnect() error {
+	t.doneOnce.Do(func() { close(t.done) })
+	return nil
+}
+
+func (t *eventsAPITransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "couldn't read body", http.StatusBadRequest)
+		return
+	}
+
+	err = verifySlackSignature(r.Header, body, t.signingSecret, time.Now())
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Type":   "EventsAPISignature",
+			"Remote": r.RemoteAddr,
+		}).WithError(err).Warn("Refused Events API request.")
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	if payload.Type == "url_verification" {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(payload.Challenge))
+		return
+	}
+
+	// Always acknowledge, or Slack retries the event.
+	if err := t.dispatchCallback(body); err != nil {
+		log.WithError(err).Warn("Couldn't decode Events API event.")
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// verifySlackSignature checks a request was signed by Slack with the
+// app's signing secret, less than `eventsAPIMaxAge` ago.
+func verifySlackSignature(header http.Header, body []byte, signingSecret string, now time.Time) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	signature := header.Get("X-Slack-Signature")
+	if timestamp == "" || signature == "" {
+		return errors.New("missing signature headers")
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid request timestamp %q", timestamp)
+	}
+
+	age := now.Sub(time.Unix(seconds, 0))
+	if age > eventsAPIMaxAge || age < -eventsAPIMaxAge {
+		return fmt.Errorf("request timestamp is too old (%s)", age)
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errors.New("signature mismatch")
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package slick
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func signRequest(req *http.Request, body, secret string, at time.Time) {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestVerifySlackSignature(t *testing.T) {
+	now := time.Now()
+	body := `{"type":"event_callback"}`
+
+	req := httptest.NewRequest("POST", EventsAPIPath, nil)
+	signRequest(req, body, "s3cr3t", now)
+	assert.NoError(t, verifySlackSignature(req.Header, []byte(body), "s3cr3t", now))
+	assert.Error(t, verifySlackSignature(req.Header, []byte(body), "other", now))
+	assert.Error(t, verifySlackSignature(req.Header, []byte(body+" "), "s3cr3t", now))
+	assert.Error(t, verifySlackSignature(req.Header, []byte(body), "s3cr3t", now.Add(10*time.Minute)))
+	assert.Error(t, verifySlackSignature(http.Header{}, []byte(body), "s3cr3t", now))
+}
+
+func TestEventsAPITransport(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/chat.postMessage":
+			fmt.Fprintf(w, `{"ok":true,"channel":%q,"ts":"1234.5678"}`, r.FormValue("channel"))
+		default:
+			fmt.Fprint(w, `{"ok":false,"error":"unknown_method"}`)
+		}
+	}))
+	defer api.Close()
+
+	defaultAPI := slack.SLACK_API
+	slack.SLACK_API = api.URL + "/"
+	defer func() { slack.SLACK_API = defaultAPI }()
+
+	transport := NewEventsAPITransport(slack.New("xoxb-test"), "s3cr3t")
+	handler := transport.(http.Handler)
+
+	post := func(body, secret string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", EventsAPIPath, strings.NewReader(body))
+		signRequest(req, body, secret, time.Now())
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post(`{"type":"url_verification","challenge":"chall3nge"}`, "s3cr3t")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "chall3nge", rec.Body.String())
+
+	rec = post(`{"type":"url_verification","challenge":"chall3nge"}`, "wrong")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = post(`{"type":"event_callback","event":{"type":"message","channel":"C1","user":"U1","text":"hello","ts":"1.1"}}`, "s3cr3t")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	select {
+	case event := <-transport.IncomingEvents():
+		msg, ok := event.Data.(*slack.MessageEvent)
+		if assert.True(t, ok) {
+			assert.Equal(t, "hello", msg.Text)
+			assert.Equal(t, "C1", msg.Channel)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not dispatched")
+	}
+
+	out := transport.NewOutgoingMessage("pong", "C1")
+	go transport.SendMessage(out)
+
+	select {
+	case event := <-transport.IncomingEvents():
+		ack, ok := event.Data.(*slack.AckMessage)
+		if assert.True(t, ok) {
+			assert.Equal(t, out.ID, ack.ReplyTo)
+			assert.Equal(t, "1234.5678", ack.Timestamp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not acknowledged")
+	}
+}
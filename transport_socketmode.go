@@ -0,0 +1,204 @@
+package slick
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// socketModeTransport receives events over a Socket Mode websocket,
+// opened with an app-level token. Writes go through the Web API.
+type socketModeTransport struct {
+	webAPITransport
+	appToken string
+
+	lock         sync.Mutex
+	conn         *websocket.Conn
+	disconnect   chan bool
+	disconnected bool
+}
+
+// NewSocketModeTransport returns a Transport receiving events through
+// Slack's Socket Mode, using the app-level token `appToken` (xapp-...)
+// to open connections, and `client` for everything else.
+func NewSocketModeTransport(client *slack.Client, appToken string) Transport {
+	return &socketModeTransport{
+		webAPITransport: newWebAPITransport(client),
+		appToken:        appToken,
+		disconnect:      make(chan bool),
+	}
+}
+
+// socketEnvelope wraps everything Slack sends over a Socket Mode
+// connection.
+type socketEnvelope struct {
+	EnvelopeID string          `json:"envelope_id"`
+	Type       string          `json:"type"`
+	Reason     string          `json:"reason"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+func (t *socketModeTransport) Connect() {
+	attempt := 0
+	for {
+		attempt++
+		t.events <- slack.RTMEvent{Type: "connecting", Data: &slack.ConnectingEvent{
+			Attempt:         attempt,
+			ConnectionCount: t.connectionCount,
+		}}
+
+		err := t.connectOnce()
+
+		select {
+		case <-t.disconnect:
+			t.events <- slack.RTMEvent{Type: "disconnected", Data: &slack.DisconnectedEvent{Intentional: true}}
+			return
+		default:
+		}
+
+		if err == nil {
+			// Slack asked us to reconnect
+			attempt = 0
+			continue
+		}
+
+		t.events <- slack.RTMEvent{Type: "connection_error", Data: &slack.ConnectionErrorEvent{
+			Attempt:  attempt,
+			ErrorObj: err,
+		}}
+
+		backoff := time.Duration(attempt) * time.Second
+		if backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+
+		select {
+		case <-t.disconnect:
+			t.events <- slack.RTMEvent{Type: "disconnected", Data: &slack.DisconnectedEvent{Intentional: true}}
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// connectOnce opens a websocket and handles envelopes until the
+// connection drops. It returns nil when Slack asked for a reconnection.
+func (t *socketModeTransport) connectOnce() error {
+	wsURL, err := t.openConnection()
+	if err != nil {
+		return err
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	t.lock.Lock()
+	if t.disconnected {
+		t.lock.Unlock()
+		return nil
+	}
+	t.conn = conn
+	t.lock.Unlock()
+
+	for {
+		var envelope socketEnvelope
+		if err := conn.ReadJSON(&envelope); err != nil {
+			return err
+		}
+
+		if envelope.EnvelopeID != "" {
+			err := conn.WriteJSON(map[string]string{"envelope_id": envelope.EnvelopeID})
+			if err != nil {
+				return err
+			}
+		}
+
+		switch envelope.Type {
+		case "hello":
+			if err := t.connected(); err != nil {
+				return err
+			}
+
+		case "disconnect":
+			log.Printf("Socket Mode asked to reconnect, reason=%s", envelope.Reason)
+			return nil
+
+		case "events_api":
+			if err := t.dispatchCallback(envelope.Payload); err != nil {
+				log.WithError(err).Warn("Couldn't decode Socket Mode event.")
+			}
+
+		case "interactive":
+			// Acknowledged above, like the HTTP path does with an
+			// empty response
+			events, err := parseInteraction(envelope.Payload)
+			if err != nil {
+				log.WithError(err).Warn("Couldn't decode Socket Mode interaction.")
+				continue
+			}
+			for _, ev := range events {
+				t.events <- slack.RTMEvent{Type: string(ev.Type), Data: ev}
+			}
+
+		default:
+			log.Debugf("Ignoring Socket Mode envelope of type %q", envelope.Type)
+		}
+	}
+}
+
+// openConnection calls `apps.connections.open` to get a websocket URL.
+func (t *socketModeTransport) openConnection() (string, error) {
+	req, err := http.NewRequest("POST", slack.SLACK_API+"apps.connections.open", strings.NewReader(url.Values{}.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.appToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error"`
+		URL   string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+	if !out.Ok {
+		return "", errors.New("apps.connections.open: " + out.Error)
+	}
+
+	return out.URL, nil
+}
+
+func (t *socketModeTransport) Disconnect() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.disconnected {
+		return nil
+	}
+	t.disconnected = true
+	close(t.disconnect)
+
+	if t.conn != nil {
+		return t.conn.Close()
+	}
+	return nil
+}
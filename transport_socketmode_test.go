@@ -0,0 +1,90 @@
+package slick
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSocketModeTransport(t *testing.T) {
+	acks := make(chan string, 10)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apps.connections.open":
+			fmt.Fprintf(w, `{"ok":true,"url":%q}`, "ws"+strings.TrimPrefix(server.URL, "http")+"/link")
+		case "/auth.test":
+			fmt.Fprint(w, `{"ok":true,"user_id":"USLICK","team_id":"T1"}`)
+		case "/link":
+			conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			conn.WriteJSON(map[string]interface{}{"type": "hello"})
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"envelope_id":"E1","type":"events_api","payload":{"type":"event_callback","event":{"type":"message","channel":"C1","user":"U1","text":"hello","ts":"1.1"}}}`))
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"envelope_id":"E2","type":"interactive","payload":{"type":"block_actions","user":{"id":"U1"},"channel":{"id":"C1"},"container":{"message_ts":"1.2"},"actions":[{"action_id":"approve","block_id":"deploy","value":"v2"}]}}`))
+			for {
+				var ack struct {
+					EnvelopeID string `json:"envelope_id"`
+				}
+				if err := conn.ReadJSON(&ack); err != nil {
+					return
+				}
+				acks <- ack.EnvelopeID
+			}
+		default:
+			fmt.Fprint(w, `{"ok":false,"error":"unknown_method"}`)
+		}
+	}))
+	defer server.Close()
+
+	defaultAPI := slack.SLACK_API
+	slack.SLACK_API = server.URL + "/"
+	defer func() { slack.SLACK_API = defaultAPI }()
+
+	transport := NewSocketModeTransport(slack.New("xoxb-test"), "xapp-test")
+	go transport.Connect()
+	defer transport.Disconnect()
+
+	var interaction *InteractionEvent
+	var gotMessage bool
+	timeout := time.After(3 * time.Second)
+	for interaction == nil {
+		select {
+		case event := <-transport.IncomingEvents():
+			switch ev := event.Data.(type) {
+			case *slack.MessageEvent:
+				gotMessage = true
+				assert.Equal(t, "hello", ev.Text)
+			case *InteractionEvent:
+				interaction = ev
+				assert.Equal(t, string(BlockActions), event.Type)
+			}
+		case <-timeout:
+			t.Fatal("the interaction wasn't received")
+		}
+	}
+
+	assert.True(t, gotMessage)
+	assert.Equal(t, "approve", interaction.ActionID)
+	assert.Equal(t, "v2", interaction.Value)
+	assert.Equal(t, "C1", interaction.Channel)
+	assert.Equal(t, "U1", interaction.User)
+
+	for _, id := range []string{"E1", "E2"} {
+		select {
+		case ack := <-acks:
+			assert.Equal(t, id, ack)
+		case <-time.After(time.Second):
+			t.Errorf("envelope %s wasn't acknowledged", id)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package slick
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTransport is an in-memory Transport, recording everything the
+// bot sends.
+type fakeTransport struct {
+	sync.Mutex
+	events    chan slack.RTMEvent
+	sent      chan *slack.OutgoingMessage
+	reactions []string
+	nextID    int
+}
+
+func newFakeTransport() *fakeTransport {
+	return &fakeTransport{
+		events: make(chan slack.RTMEvent, 10),
+		sent:   make(chan *slack.OutgoingMessage, 10),
+	}
+}
+
+func (t *fakeTransport) Connect()                              {}
+func (t *fakeTransport) Disconnect() error                     { return nil }
+func (t *fakeTransport) IncomingEvents() <-chan slack.RTMEvent { return t.events }
+
+func (t *fakeTransport) NewOutgoingMessage(text string, channel string) *slack.OutgoingMessage {
+	t.Lock()
+	defer t.Unlock()
+	t.nextID++
+	return &slack.OutgoingMessage{ID: t.nextID, Type: "message", Channel: channel, Text: text}
+}
+
+func (t *fakeTransport) SendMessage(msg *slack.OutgoingMessage) {
+	t.sent <- msg
+}
+
+func (t *fakeTransport) UpdateMessage(ctx context.Context, channel, timestamp, text string) error {
+	return nil
+}
+func (t *fakeTransport) DeleteMessage(ctx context.Context, channel, timestamp string) error {
+	return nil
+}
+
+func (t *fakeTransport) AddReaction(ctx context.Context, name string, item slack.ItemRef) error {
+	t.Lock()
+	defer t.Unlock()
+	t.reactions = append(t.reactions, name)
+	return nil
+}
+
+func (t *fakeTransport) RemoveReaction(ctx context.Context, name string, item slack.ItemRef) error {
+	return nil
+}
+func (t *fakeTransport) OpenIMChannel(ctx context.Context, user string) (string, error) {
+	return "D" + user, nil
+}
+
+func TestBotDispatchesThroughTransport(t *testing.T) {
+	transport := newFakeTransport()
+	bot := New("")
+	bot.Transport = transport
+	bot.Users.Set(slack.User{ID: "U1", Name: "bob"})
+	bot.Channels.Set(Channel{ID: "C1", Name: "general", IsChannel: true})
+
+	bot.Listen(&Listener{
+		Contains: "ping",
+		MessageHandlerFunc: func(listen *Listener, msg *Message) {
+			msg.Reply("pong")
+		},
+	})
+	// Register the listener before any event can race it.
+	bot.listeners = append(bot.listeners, <-bot.addListenerCh)
+	bot.setupHandlers()
+
+	transport.events <- slack.RTMEvent{Type: "message", Data: &slack.MessageEvent{
+		Msg: slack.Msg{Type: "message", Channel: "C1", User: "U1", Text: "ping", Timestamp: "1.1"},
+	}}
+
+	select {
+	case out := <-transport.sent:
+		assert.Equal(t, "pong", out.Text)
+		assert.Equal(t, "C1", out.Channel)
+	case <-time.After(time.Second):
+		t.Fatal("no reply sent through the transport")
+	}
+}
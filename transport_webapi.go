@@ -0,0 +1,171 @@
+package slick
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// webAPITransport is the common part of the transports which only
+// receive events (Socket Mode and the Events API): everything the bot
+// sends goes through the Web API.
+//
+// Sent messages are acknowledged with a synthetic `*slack.AckMessage`,
+// so `Reply.OnAck` and friends work the same as with the RTM.
+type webAPITransport struct {
+	client *slack.Client
+	events chan slack.RTMEvent
+
+	idLock          sync.Mutex
+	nextID          int
+	connectionCount int
+}
+
+func newWebAPITransport(client *slack.Client) webAPITransport {
+	return webAPITransport{
+		client: client,
+		events: make(chan slack.RTMEvent, 50),
+	}
+}
+
+func (t *webAPITransport) IncomingEvents() <-chan slack.RTMEvent {
+	return t.events
+}
+
+func (t *webAPITransport) NewOutgoingMessage(text string, channel string) *slack.OutgoingMessage {
+	t.idLock.Lock()
+	defer t.idLock.Unlock()
+	t.nextID++
+
+	return &slack.OutgoingMessage{
+		ID:      t.nextID,
+		Type:    "message",
+		Channel: channel,
+		Text:    text,
+	}
+}
+
+func (t *webAPITransport) SendMessage(msg *slack.OutgoingMessage) {
+	params := slack.NewPostMessageParameters()
+	params.EscapeText = false
+	params.ThreadTimestamp = msg.ThreadTimestamp
+
+	_, timestamp, err := t.client.PostMessage(msg.Channel, msg.Text, params)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Type":    "PostMessageError",
+			"Channel": msg.Channel,
+		}).WithError(err).Error("Error sending message.")
+
+		t.events <- slack.RTMEvent{Type: "ack_error", Data: &slack.AckErrorEvent{ErrorObj: &SendError{ReplyTo: msg.ID, Err: err, Method: "chat.postMessage"}}}
+		return
+	}
+
+	ack := &slack.AckMessage{
+		ReplyTo:   msg.ID,
+		Timestamp: timestamp,
+		Text:      msg.Text,
+	}
+	ack.Ok = true
+	t.events <- slack.RTMEvent{Type: "ack", Data: ack}
+}
+
+func (t *webAPITransport) UpdateMessage(ctx context.Context, channel, timestamp, text string) error {
+	_, _, _, err := t.client.UpdateMessageContext(ctx, channel, timestamp, text)
+	return err
+}
+
+func (t *webAPITransport) DeleteMessage(ctx context.Context, channel, timestamp string) error {
+	_, _, err := t.client.DeleteMessageContext(ctx, channel, timestamp)
+	return err
+}
+
+func (t *webAPITransport) AddReaction(ctx context.Context, name string, item slack.ItemRef) error {
+	return t.client.AddReactionContext(ctx, name, item)
+}
+
+func (t *webAPITransport) RemoveReaction(ctx context.Context, name string, item slack.ItemRef) error {
+	return t.client.RemoveReactionContext(ctx, name, item)
+}
+
+func (t *webAPITransport) OpenIMChannel(ctx context.Context, user string) (string, error) {
+	_, _, channelID, err := t.client.OpenIMChannelContext(ctx, user)
+	return channelID, err
+}
+
+// connected identifies the bot with `auth.test`, and announces the
+// connection the same way the RTM does, with a `*slack.ConnectedEvent`
+// followed by a `*slack.HelloEvent`.
+func (t *webAPITransport) connected() error {
+	auth, err := t.client.AuthTest()
+	if err != nil {
+		return err
+	}
+
+	t.connectionCount++
+	t.events <- slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{
+		ConnectionCount: t.connectionCount,
+		Info: &slack.Info{
+			URL:  auth.URL,
+			User: &slack.UserDetails{ID: auth.UserID, Name: auth.User},
+			Team: &slack.Team{ID: auth.TeamID, Name: auth.Team},
+		},
+	}}
+	t.events <- slack.RTMEvent{Type: "hello", Data: &slack.HelloEvent{}}
+
+	return nil
+}
+
+// dispatchCallback handles an `event_callback` payload, as sent by the
+// Events API or wrapped in a Socket Mode envelope.
+func (t *webAPITransport) dispatchCallback(payload []byte) error {
+	var callback struct {
+		Type  string          `json:"type"`
+		Event json.RawMessage `json:"event"`
+	}
+	if err := json.Unmarshal(payload, &callback); err != nil {
+		return err
+	}
+
+	if callback.Type != "event_callback" {
+		log.Debugf("Ignoring Events API payload of type %q", callback.Type)
+		return nil
+	}
+
+	event, err := parseInnerEvent(callback.Event)
+	if err != nil {
+		return err
+	}
+
+	t.events <- event
+	return nil
+}
+
+// parseInnerEvent converts an Events API event to the RTM event of the
+// same type, so listeners receive the same structs whatever the
+// transport.
+func parseInnerEvent(raw []byte) (slack.RTMEvent, error) {
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return slack.RTMEvent{}, err
+	}
+
+	prototype, ok := slack.EventMapping[header.Type]
+	if !ok {
+		return slack.RTMEvent{}, fmt.Errorf("unmapped event %q", header.Type)
+	}
+
+	data := reflect.New(reflect.TypeOf(prototype)).Interface()
+	if err := json.Unmarshal(raw, data); err != nil {
+		return slack.RTMEvent{}, fmt.Errorf("decoding %q event: %s", header.Type, err)
+	}
+
+	return slack.RTMEvent{Type: header.Type, Data: data}, nil
+}
@@ -26,7 +26,7 @@ func (u *UpdateableReply) dispatch() {
 	}
 
 	if u.newMessage != "" {
-		u.reply.bot.Slack.UpdateMessage(u.reply.OutgoingMessage.Channel, u.msgTimestamp, u.newFormattedMessage())
+		u.reply.team.Transport.UpdateMessage(u.reply.bot.ctx, u.reply.OutgoingMessage.Channel, u.msgTimestamp, u.newFormattedMessage())
 		u.newMessage = ""
 	}
 }
@@ -14,6 +14,7 @@ type Vote struct {
 	bot          *slick.Bot
 	runningVotes map[string][]vote // votes per channel
 	mutex        sync.Mutex
+	votes        *slick.CounterVec
 }
 
 func init() {
@@ -24,10 +25,23 @@ func init() {
 
 func (vote *Vote) InitPlugin(bot *slick.Bot) {
 	vote.bot = bot
+	vote.votes = bot.Metrics.Counter("vote_votes_total", "Votes cast during lunch votes.")
+
+	bot.Command(&slick.Command{
+		Name:        "what-for-lunch",
+		Aliases:     []string{"vote-for-lunch"},
+		Args:        []slick.CommandArg{{Name: "duration", Type: slick.ArgDuration}},
+		Usage:       "opens a vote for lunch, for the given duration (ex: 5m)",
+		PublicOnly:  true,
+		HandlerFunc: vote.startVote,
+	})
 
-	bot.Listen(&slick.Listener{
-		PublicOnly:         true,
-		MessageHandlerFunc: vote.voteHandler,
+	bot.Command(&slick.Command{
+		Name:        "vote",
+		Args:        []slick.CommandArg{{Name: "place", Type: slick.ArgRest}},
+		Usage:       "votes for a place during a lunch vote. A substring of a previous vote counts for it",
+		PublicOnly:  true,
+		HandlerFunc: vote.castVote,
 	})
 }
 
@@ -36,104 +50,82 @@ type vote struct {
 	vote string
 }
 
-func (v *Vote) voteHandler(listen *slick.Listener, msg *slick.Message) {
+func (v *Vote) startVote(_ *slick.Command, msg *slick.Message, args slick.CommandArgs) {
 	v.mutex.Lock()
 	defer v.mutex.Unlock()
 
-	bot := v.bot
-	// TODO:    ok, @kat wants to survey what's for lunch, use "!vote The Food Place http://food-place.url" .. you can vote for the same place with a substring: "!vote food place"
-	// TODO: match "!vote Mucha Dogs http://bigdogs.com"
-	// TODO: match "!vote mucha dogs"
-	// TODO: match "!vote Other place
-
-	if msg.Text == "!what-for-lunch" || msg.Text == "!vote-for-lunch" {
-		msg.ReplyMention("you can say `!what-for-lunch 5m` to get a vote that will last 5 minutes. `!vote-for-lunch` is an alias")
+	if v.runningVotes[msg.FromChannel.ID] != nil {
+		msg.ReplyMention("vote is already running!").DeleteAfter("3s")
 		return
 	}
 
-	if msg.HasPrefix("!what-for-lunch ") || msg.HasPrefix("!vote-for-lunch ") {
-		fmt.Printf("NPD: %v\n", msg)
-		if v.runningVotes[msg.FromChannel.ID] != nil {
-			msg.ReplyMention("vote is already running!").DeleteAfter("3s")
-			return
-		}
-
-		timing := strings.TrimSpace(strings.SplitN(msg.Text, " ", 2)[1])
-		dur, err := time.ParseDuration(timing)
-		if err != nil {
-			msg.ReplyMention(fmt.Sprintf("couldn't parse duration: %s", err))
-			return
-		}
+	dur := args.Duration("duration")
+	v.runningVotes[msg.FromChannel.ID] = make([]vote, 0)
 
-		v.runningVotes[msg.FromChannel.ID] = make([]vote, 0)
+	go func() {
+		time.Sleep(dur)
 
-		go func() {
-			time.Sleep(dur)
+		v.mutex.Lock()
+		defer v.mutex.Unlock()
 
-			v.mutex.Lock()
-			defer v.mutex.Unlock()
-
-			res := make(map[string]int)
-			for _, oneVote := range v.runningVotes[msg.FromChannel.ID] {
-				res[oneVote.vote] = res[oneVote.vote] + 1
-			}
+		res := make(map[string]int)
+		for _, oneVote := range v.runningVotes[msg.FromChannel.ID] {
+			res[oneVote.vote] = res[oneVote.vote] + 1
+		}
 
-			// TODO: print report, clear up
-			if len(res) == 0 {
-				msg.ReplyMention("polls closed, but no one voted")
-			} else {
-				out := []string{"polls closed, here are the results:"}
-				for theVote, count := range res {
-					plural := ""
-					if count > 1 {
-						plural = "s"
-					}
-					out = append(out, fmt.Sprintf("* %s: %d vote%s", theVote, count, plural))
+		if len(res) == 0 {
+			msg.ReplyMention("polls closed, but no one voted")
+		} else {
+			out := []string{"polls closed, here are the results:"}
+			for theVote, count := range res {
+				plural := ""
+				if count > 1 {
+					plural = "s"
 				}
-				msg.ReplyMention(strings.Join(out, "\n"))
+				out = append(out, fmt.Sprintf("* %s: %d vote%s", theVote, count, plural))
 			}
+			msg.ReplyMention(strings.Join(out, "\n"))
+		}
 
-			delete(v.runningVotes, msg.FromChannel.ID)
-		}()
+		delete(v.runningVotes, msg.FromChannel.ID)
+	}()
 
-		msg.Reply("<!channel> okay, what do we eat ? Votes are open. Use `!vote The Food Place http://food-place.url` .. you can vote for the same place with a substring, ex: `!vote food place`")
+	msg.Reply("<!channel> okay, what do we eat ? Votes are open. Use `!vote The Food Place http://food-place.url` .. you can vote for the same place with a substring, ex: `!vote food place`")
+}
 
+func (v *Vote) castVote(_ *slick.Command, msg *slick.Message, args slick.CommandArgs) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	bot := v.bot
+
+	running := v.runningVotes[msg.FromChannel.ID]
+	if running == nil {
+		msg.Reply(bot.WithMood("what vote ?!", "oh you're so cute! voting while there's no vote going on !"))
+		return
 	}
 
-	if msg.HasPrefix("!vote ") {
-		running := v.runningVotes[msg.FromChannel.ID]
-		if running == nil {
-			msg.Reply(bot.WithMood("what vote ?!", "oh you're so cute! voting while there's no vote going on !"))
-			return
-		}
+	voteCast := args.String("place")
 
-		voteCast := strings.TrimSpace(strings.SplitN(msg.Text, " ", 2)[1])
-		if len(voteCast) == 0 {
+	for _, prevVote := range running {
+		if msg.FromUser.ID == prevVote.user {
+			// buzz off if you voted already
+			msg.ReplyMention(bot.WithMood("you voted already", "trying to double vote ! how charming :)"))
 			return
 		}
+	}
 
-		// TODO: check for dupe
-		for _, prevVote := range running {
-			if msg.FromUser.ID == prevVote.user {
-				// buzz off if you voted already
-				msg.ReplyMention(bot.WithMood("you voted already", "trying to double vote ! how charming :)"))
-				return
-			}
-		}
-
-		for _, prevVote := range running {
-			if strings.Contains(strings.ToLower(prevVote.vote), strings.ToLower(voteCast)) {
-				running = append(running, vote{msg.FromUser.ID, prevVote.vote})
-				v.runningVotes[msg.FromChannel.ID] = running
-				msg.ReplyMention(bot.WithMood("okay", "hmmm kaay")).DeleteAfter("2s")
-				return
-			}
+	for _, prevVote := range running {
+		if strings.Contains(strings.ToLower(prevVote.vote), strings.ToLower(voteCast)) {
+			running = append(running, vote{msg.FromUser.ID, prevVote.vote})
+			v.runningVotes[msg.FromChannel.ID] = running
+			v.votes.Inc()
+			msg.ReplyMention(bot.WithMood("okay", "hmmm kaay")).DeleteAfter("2s")
+			return
 		}
-		running = append(running, vote{msg.FromUser.ID, voteCast})
-		v.runningVotes[msg.FromChannel.ID] = running
-		msg.ReplyMention(bot.WithMood("taking note", "taking note! what a creative mind...")).DeleteAfter("2s")
-
-		// TODO: match "!what-for-lunch 1h|5m|50s"
-
 	}
+	running = append(running, vote{msg.FromUser.ID, voteCast})
+	v.runningVotes[msg.FromChannel.ID] = running
+	v.votes.Inc()
+	msg.ReplyMention(bot.WithMood("taking note", "taking note! what a creative mind...")).DeleteAfter("2s")
 }
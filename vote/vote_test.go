@@ -0,0 +1,64 @@
+package vote
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/CapstoneLabs/slick/slicktest"
+)
+
+func TestVoteForLunch(t *testing.T) {
+	h := slicktest.New(t)
+	defer h.Close()
+
+	h.Post("alice", "#general", "!what-for-lunch")
+	h.ExpectMessage("#general", "usage: `!what-for-lunch <duration>`")
+
+	h.Post("alice", "#general", "!vote-for-lunch 500ms")
+	h.ExpectMessage("#general", "Votes are open")
+
+	h.Post("alice", "#general", "!vote Pizza Place")
+	h.ExpectMessage("#general", "taking note")
+	h.Post("bob", "#general", "!vote pizza")
+	h.ExpectMessage("#general", "okay")
+
+	h.ExpectMessage("#general", "* Pizza Place: 2 votes")
+
+	var metrics bytes.Buffer
+	h.Bot.Metrics.WriteTo(&metrics)
+	assert.Contains(t, metrics.String(), "vote_votes_total 2\n")
+
+	h.Post("bob", "#general", "!help")
+	h.ExpectMessage("#general", "`!vote <place...>`")
+}
+
+func TestVoteRules(t *testing.T) {
+	h := slicktest.New(t)
+	defer h.Close()
+
+	h.Post("alice", "#general", "!vote pizza")
+	h.ExpectMessage("#general", "what vote ?!")
+
+	h.Post("alice", "#general", "!vote-for-lunch 1s")
+	h.ExpectMessage("#general", "Votes are open")
+	h.Post("bob", "#general", "!vote-for-lunch 5m")
+	h.ExpectMessage("#general", "vote is already running!")
+
+	h.Post("alice", "#random", "!vote sushi")
+	h.ExpectMessage("#random", "what vote ?!")
+
+	h.Post("alice", "#general", "!vote Sushi Bar")
+	h.ExpectMessage("#general", "taking note")
+	h.Post("alice", "#general", "!vote tacos")
+	h.ExpectMessage("#general", "you voted already")
+
+	results := h.ExpectMessage("#general", "polls closed, here are the results:")
+	assert.True(t, strings.HasSuffix(results.Text, "* Sushi Bar: 1 vote"), results.Text)
+
+	h.Post("bob", "#general", "!vote-for-lunch 100ms")
+	h.ExpectMessage("#general", "Votes are open")
+	h.ExpectMessage("#general", "polls closed, but no one voted")
+}
@@ -1,16 +1,19 @@
 package web
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"html/template"
 	"net/http"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/CapstoneLabs/slick"
 	"github.com/codegangsta/negroni"
-	"github.com/gorilla/context"
+	gcontext "github.com/gorilla/context"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
 	"github.com/nlopes/slack"
@@ -23,6 +26,7 @@ type Webapp struct {
 	store                 *sessions.CookieStore
 	bot                   *slick.Bot
 	handler               *negroni.Negroni
+	server                *http.Server
 	privateRouter         *mux.Router
 	publicRouter          *mux.Router
 	enabledPlugins        []string
@@ -34,6 +38,10 @@ type WebappConfig struct {
 	Listen            string `json:"listen" mapstructure:"listen"`
 	SessionAuthKey    string `json:"session_auth_key" mapstructure:"session_auth_key"`
 	SessionEncryptKey string `json:"session_encrypt_key" mapstructure:"session_encrypt_key"`
+	// MetricsToken lets scrapers read `/metrics` without the auth
+	// middleware, with an `Authorization: Bearer <token>` header.
+	// Without it, `/metrics` is on the private router.
+	MetricsToken string `json:"metrics_token" mapstructure:"metrics_token"`
 }
 
 func init() {
@@ -54,6 +62,7 @@ func (webapp *Webapp) InitWebServer(bot *slick.Bot, enabledPlugins []string) {
 	webapp.publicRouter = mux.NewRouter()
 
 	webapp.privateRouter.HandleFunc("/", webapp.handleRoot)
+	webapp.server = &http.Server{Addr: webapp.config.Listen}
 
 	web = webapp
 }
@@ -88,6 +97,11 @@ func (webapp *Webapp) RunServer() {
 
 	pubMux := http.NewServeMux()
 	pubMux.Handle("/public/", webapp.PublicRouter())
+	if webapp.config.MetricsToken != "" {
+		pubMux.Handle("/metrics", requireBearerToken(webapp.config.MetricsToken, webapp.bot.Metrics))
+	} else {
+		privMux.Handle("/metrics", webapp.bot.Metrics)
+	}
 	if webapp.authMiddleware != nil {
 		pubMux.Handle("/", webapp.authMiddleware(privMux))
 	} else {
@@ -95,9 +109,33 @@ func (webapp *Webapp) RunServer() {
 	}
 
 	webapp.handler = negroni.Classic()
-	webapp.handler.UseHandler(context.ClearHandler(pubMux))
+	webapp.handler.UseHandler(gcontext.ClearHandler(pubMux))
+
+	webapp.server.Handler = webapp.handler
+	log.Printf("Web server listening on %s", webapp.config.Listen)
+	if err := webapp.server.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
+
+// requireBearerToken serves `handler` to the requests authorized with
+// `token`.
+func requireBearerToken(token string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
 
-	webapp.handler.Run(webapp.config.Listen)
+// StopPlugin stops the web server, waiting for the running requests
+// until `ctx` is done.
+func (webapp *Webapp) StopPlugin(ctx context.Context) error {
+	return webapp.server.Shutdown(ctx)
 }
 
 func (webapp *Webapp) GetSession(r *http.Request) *sessions.Session {
@@ -91,7 +91,7 @@ func (mw *OAuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		if r.URL.Path == "/" {
 			log.Println("Not logged in", err)
-			url := mw.oauthCfg.AuthCodeURL("", oauth2.SetAuthURLParam("team", mw.bot.Config.TeamID))
+			url := mw.oauthCfg.AuthCodeURL("", oauth2.SetAuthURLParam("team", mw.bot.Config().TeamID))
 			http.Redirect(w, r, url, http.StatusFound)
 		} else {
 			w.WriteHeader(http.StatusForbidden)
@@ -138,7 +138,7 @@ func (mw *OAuthMiddleware) doOAuth2Roundtrip(w http.ResponseWriter, r *http.Requ
 		return nil, fmt.Errorf("User unauthenticated: %s", err)
 	}
 
-	expectedURL := fmt.Sprintf("https://%s.slack.com/", mw.bot.Config.TeamDomain)
+	expectedURL := fmt.Sprintf("https://%s.slack.com/", mw.bot.Config().TeamDomain)
 	if resp.URL != expectedURL {
 		return nil, fmt.Errorf("Authenticated for wrong domain: %q != %q", resp.URL, expectedURL)
 	}
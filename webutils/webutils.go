@@ -30,7 +30,7 @@ func (utils *Utils) handleGetUsers(w http.ResponseWriter, r *http.Request) {
 	out := struct {
 		Users map[string]slack.User `json:"users"`
 	}{
-		Users: utils.bot.Users,
+		Users: utils.bot.Users.Snapshot(),
 	}
 
 	err := enc.Encode(out)
@@ -47,7 +47,7 @@ func (utils *Utils) handleGetChannels(w http.ResponseWriter, r *http.Request) {
 	out := struct {
 		Channels map[string]slick.Channel `json:"channels"`
 	}{
-		Channels: utils.bot.Channels,
+		Channels: utils.bot.Channels.Snapshot(),
 	}
 
 	err := enc.Encode(out)
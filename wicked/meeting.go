@@ -60,7 +60,7 @@ func NewMeeting(id string, user *slack.User, goal string, bot *slick.Bot, channe
 	}
 	meeting.setTopic = func(topic string) {
 		// TODO: set a topic with Slack.
-		//hipchatv2.SetTopic(bot.Config.HipchatApiToken, roomID, topic)
+		//hipchatv2.SetTopic(bot.Config().HipchatApiToken, roomID, topic)
 	}
 
 	newUser := meeting.ImportUser(user)
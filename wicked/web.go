@@ -148,6 +148,8 @@ func (wicked *Wicked) webGetMeeting(r *http.Request) *Meeting {
 	params := mux.Vars(r)
 	id := params["id"]
 
+	wicked.lock.Lock()
+	defer wicked.lock.Unlock()
 	for _, meetingEl := range wicked.pastMeetings {
 		if meetingEl.ID == id {
 			return meetingEl
@@ -15,7 +15,7 @@ package wicked
 import (
 	"fmt"
 	"regexp"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/CapstoneLabs/slick"
@@ -23,7 +23,10 @@ import (
 
 // Wicked stores the configuration for wicked
 type Wicked struct {
-	bot          *slick.Bot
+	bot *slick.Bot
+	// lock guards the conf rooms and the meetings: the commands and
+	// the logging of the messages run on different listeners.
+	lock         sync.Mutex
 	confRooms    []string
 	meetings     map[string]*Meeting
 	pastMeetings []*Meeting
@@ -31,9 +34,12 @@ type Wicked struct {
 
 var (
 	decisionMatcher = regexp.MustCompile(`(?mi)D(\d+)\+\+`)
-	joinMatcher     = regexp.MustCompile(`!join\s+(?mi)W(\d+)`)
+	meetingMatcher  = regexp.MustCompile(`(?i)^W(\d+)$`)
 )
 
+// PermissionStart is needed to start a meeting.
+const PermissionStart = "wicked.start"
+
 func init() {
 	slick.RegisterPlugin(&Wicked{})
 }
@@ -42,126 +48,201 @@ func (wicked *Wicked) InitPlugin(bot *slick.Bot) {
 	wicked.bot = bot
 	wicked.meetings = make(map[string]*Meeting)
 
+	wicked.ReloadConfig(bot)
+
+	bot.Command(&slick.Command{
+		Name:        "wicked",
+		Args:        []slick.CommandArg{{Name: "goal", Type: slick.ArgRest}},
+		Usage:       "starts a meeting in a free conf room",
+		Permission:  PermissionStart,
+		HandlerFunc: wicked.startMeeting,
+	})
+	bot.Command(&slick.Command{
+		Name:        "join",
+		Args:        []slick.CommandArg{{Name: "meeting", Type: slick.ArgWord}},
+		Usage:       "asks to join a meeting, like W12",
+		HandlerFunc: wicked.joinMeeting,
+	})
+	bot.Command(&slick.Command{
+		Name:        "proposition",
+		Args:        []slick.CommandArg{{Name: "text", Type: slick.ArgRest}},
+		Usage:       "adds a proposition to the meeting of the channel, +1 it with D12++",
+		PublicOnly:  true,
+		HandlerFunc: wicked.addProposition,
+	})
+	bot.Command(&slick.Command{
+		Name:        "ref",
+		Args:        []slick.CommandArg{{Name: "reference", Type: slick.ArgRest}},
+		Usage:       "adds a reference, a link and its description, to the meeting of the channel",
+		PublicOnly:  true,
+		HandlerFunc: wicked.addReference,
+	})
+	bot.Command(&slick.Command{
+		Name:        "conclude",
+		Usage:       "concludes the meeting of the channel",
+		PublicOnly:  true,
+		HandlerFunc: wicked.concludeMeeting,
+	})
+
+	bot.Listen(&slick.Listener{
+		PublicOnly:         true,
+		MessageHandlerFunc: wicked.ChatHandler,
+	})
+}
+
+// ReloadConfig loads the conf rooms, see `slick.ConfigReloader`. The
+// meetings in rooms removed from the config go on until they end.
+func (wicked *Wicked) ReloadConfig(bot *slick.Bot) error {
 	var conf struct {
 		Wicked struct {
 			Confrooms []string `json:"conf_rooms" mapstructure:"conf_rooms"`
 		}
 	}
 
-	bot.LoadConfig(&conf)
-
-	for _, confroom := range conf.Wicked.Confrooms {
-		wicked.confRooms = append(wicked.confRooms, confroom)
+	if err := bot.LoadConfig(&conf); err != nil {
+		return err
 	}
 
-	bot.Listen(&slick.Listener{
-		MessageHandlerFunc: wicked.ChatHandler,
-	})
+	wicked.lock.Lock()
+	wicked.confRooms = conf.Wicked.Confrooms
+	wicked.lock.Unlock()
+	return nil
 }
 
-func (wicked *Wicked) ChatHandler(listen *slick.Listener, msg *slick.Message) {
-	bot := listen.Bot
+func (wicked *Wicked) startMeeting(_ *slick.Command, msg *slick.Message, args slick.CommandArgs) {
+	bot := wicked.bot
 	uuidNow := time.Now()
 
-	if strings.HasPrefix(msg.Text, "!wicked ") {
-		fromRoom := ""
-		if msg.FromChannel != nil {
-			fromRoom = msg.FromChannel.ID
-		}
-
-		availableRoom := wicked.FindAvailableRoom(fromRoom)
+	fromRoom := ""
+	if msg.FromChannel != nil {
+		fromRoom = msg.FromChannel.ID
+	}
 
-		if availableRoom == nil {
-			msg.Reply("No available Wicked Confroom for a meeting! Seems you'll need to create new Wicked Confrooms !")
-			goto continueLogging
-		}
+	wicked.lock.Lock()
+	availableRoom := wicked.findAvailableRoom(fromRoom)
+	if availableRoom == nil {
+		wicked.lock.Unlock()
+		msg.Reply("No available Wicked Confroom for a meeting! Seems you'll need to create new Wicked Confrooms !")
+		return
+	}
 
-		id := wicked.NextMeetingID()
-		meeting := NewMeeting(id, msg.FromUser, msg.Text[7:], bot, availableRoom, uuidNow)
+	id := wicked.NextMeetingID()
+	meeting := NewMeeting(id, msg.FromUser, args.String("goal"), bot, availableRoom, uuidNow)
 
-		wicked.pastMeetings = append(wicked.pastMeetings, meeting)
-		wicked.meetings[availableRoom.ID] = meeting
+	wicked.pastMeetings = append(wicked.pastMeetings, meeting)
+	wicked.meetings[availableRoom.ID] = meeting
+	wicked.lock.Unlock()
 
-		if availableRoom.ID == fromRoom {
-			meeting.sendToRoom(fmt.Sprintf(`Starting wicked meeting W%s in here.`, meeting.ID))
-		} else {
-			msg.Reply(fmt.Sprintf(`Starting wicked meeting W%s in room "%s". Join with !join W%s`, meeting.ID, availableRoom.Name, meeting.ID))
-			initiatedFrom := ""
-			if fromRoom != "" {
-				initiatedFrom = fmt.Sprintf(` in "%s"`, msg.FromChannel.Name)
-			}
-			meeting.sendToRoom(fmt.Sprintf(`*** Wicked meeting initiated by @%s%s. Goal: %s`, msg.FromUser.Name, initiatedFrom, meeting.Goal))
+	if availableRoom.ID == fromRoom {
+		meeting.sendToRoom(fmt.Sprintf(`Starting wicked meeting W%s in here.`, meeting.ID))
+	} else {
+		msg.Reply(fmt.Sprintf(`Starting wicked meeting W%s in room "%s". Join with !join W%s`, meeting.ID, availableRoom.Name, meeting.ID))
+		initiatedFrom := ""
+		if fromRoom != "" {
+			initiatedFrom = fmt.Sprintf(` in "%s"`, msg.FromChannel.Name)
 		}
+		meeting.sendToRoom(fmt.Sprintf(`*** Wicked meeting initiated by @%s%s. Goal: %s`, msg.FromUser.Name, initiatedFrom, meeting.Goal))
+	}
 
-		meeting.sendToRoom(fmt.Sprintf(`Access report at %s/wicked/%s.html`, wicked.bot.Config.WebBaseURL, meeting.ID))
-		meeting.setTopic(fmt.Sprintf(`[Running] W%s goal: %s`, meeting.ID, meeting.Goal))
-	} else if strings.HasPrefix(msg.Text, "!join") {
-		match := joinMatcher.FindStringSubmatch(msg.Text)
-		if match == nil {
-			msg.ReplyMention(`invalid !join syntax. Use something like "!join W123"`)
-		} else {
-			for _, meeting := range wicked.meetings {
-				if match[1] == meeting.ID {
-					meeting.sendToRoom(fmt.Sprintf(`<@%s> asked to join`, msg.FromUser.Name))
-				}
-			}
-		}
+	meeting.sendToRoom(fmt.Sprintf(`Access report at %s/wicked/%s.html`, wicked.bot.Config().WebBaseURL, meeting.ID))
+	meeting.setTopic(fmt.Sprintf(`[Running] W%s goal: %s`, meeting.ID, meeting.Goal))
+}
+
+func (wicked *Wicked) joinMeeting(_ *slick.Command, msg *slick.Message, args slick.CommandArgs) {
+	match := meetingMatcher.FindStringSubmatch(args.String("meeting"))
+	if match == nil {
+		msg.ReplyMention(`invalid !join syntax. Use something like "!join W123"`)
+		return
 	}
 
-continueLogging:
+	wicked.lock.Lock()
+	defer wicked.lock.Unlock()
+	for _, meeting := range wicked.meetings {
+		if match[1] == meeting.ID {
+			meeting.sendToRoom(slick.MentionUser(msg.FromUser.ID) + " asked to join")
+		}
+	}
+}
 
-	//
-	// Public commands and messages
-	//
+// withMeeting calls `f` with the meeting running in the channel of
+// `msg` and its author, holding the lock. It does nothing when there's
+// no meeting in the channel.
+func (wicked *Wicked) withMeeting(msg *slick.Message, f func(meeting *Meeting, user *User)) {
 	if msg.FromChannel == nil {
 		return
 	}
-	room := msg.FromChannel.ID
-	meeting, meetingExists := wicked.meetings[room]
+
+	wicked.lock.Lock()
+	defer wicked.lock.Unlock()
+	meeting, meetingExists := wicked.meetings[msg.FromChannel.ID]
 	if !meetingExists {
 		return
 	}
+	f(meeting, meeting.ImportUser(msg.FromUser))
+}
 
-	user := meeting.ImportUser(msg.FromUser)
-
-	if strings.HasPrefix(msg.Text, "!proposition ") {
-		decision := meeting.AddDecision(user, msg.Text[12:], uuidNow)
+func (wicked *Wicked) addProposition(_ *slick.Command, msg *slick.Message, args slick.CommandArgs) {
+	wicked.withMeeting(msg, func(meeting *Meeting, user *User) {
+		decision := meeting.AddDecision(user, args.String("text"), time.Now())
 		if decision == nil {
 			msg.Reply("Whoops, wrong syntax for !proposition")
 		} else {
 			msg.Reply(fmt.Sprintf("Proposition added, ref: D%s", decision.ID))
 		}
+	})
+}
 
-	} else if strings.HasPrefix(msg.Text, "!ref ") {
-
-		meeting.AddReference(user, msg.Text[4:], uuidNow)
+func (wicked *Wicked) addReference(_ *slick.Command, msg *slick.Message, args slick.CommandArgs) {
+	wicked.withMeeting(msg, func(meeting *Meeting, user *User) {
+		meeting.AddReference(user, args.String("reference"), time.Now())
 		msg.Reply("Ref. added")
+	})
+}
 
-	} else if strings.HasPrefix(msg.Text, "!conclude") {
+func (wicked *Wicked) concludeMeeting(_ *slick.Command, msg *slick.Message, _ slick.CommandArgs) {
+	wicked.withMeeting(msg, func(meeting *Meeting, user *User) {
 		meeting.Conclude()
 		// TODO: kill all waiting goroutines dealing with messaging
-		delete(wicked.meetings, room)
+		delete(wicked.meetings, msg.FromChannel.ID)
 		meeting.sendToRoom("Concluding Wicked meeting, that's all folks!")
 		meeting.setTopic(fmt.Sprintf(`[Concluded] W%s goal: %s`, meeting.ID, meeting.Goal))
+	})
+}
+
+// ChatHandler logs the messages of the meeting rooms, commands
+// included, and counts the D12++ of the propositions.
+func (wicked *Wicked) ChatHandler(listen *slick.Listener, msg *slick.Message) {
+	uuidNow := time.Now()
 
-	} else if match := decisionMatcher.FindStringSubmatch(msg.Text); match != nil {
-		decision := meeting.GetDecisionByID(match[1])
-		if decision != nil {
-			decision.RecordPlusplus(user)
-			msg.ReplyMention("noted")
+	wicked.withMeeting(msg, func(meeting *Meeting, user *User) {
+		if match := decisionMatcher.FindStringSubmatch(msg.Text); match != nil {
+			decision := meeting.GetDecisionByID(match[1])
+			if decision != nil {
+				decision.RecordPlusplus(user)
+				msg.ReplyMention("noted")
+			}
 		}
-	}
 
-	// Log message
-	newMessage := &Message{
-		From:      user,
-		Timestamp: uuidNow,
-		Text:      msg.Text,
-	}
-	meeting.Logs = append(meeting.Logs, newMessage)
+		// Log message
+		newMessage := &Message{
+			From:      user,
+			Timestamp: uuidNow,
+			Text:      msg.Text,
+		}
+		meeting.Logs = append(meeting.Logs, newMessage)
+	})
 }
 
+// FindAvailableRoom returns the conf room where to start a meeting: the
+// room `fromRoom` when it is a free conf room, the first free one
+// otherwise.
 func (wicked *Wicked) FindAvailableRoom(fromRoom string) *slick.Channel {
+	wicked.lock.Lock()
+	defer wicked.lock.Unlock()
+	return wicked.findAvailableRoom(fromRoom)
+}
+
+func (wicked *Wicked) findAvailableRoom(fromRoom string) *slick.Channel {
 	nextFree := ""
 	for _, confRoom := range wicked.confRooms {
 		_, occupied := wicked.meetings[confRoom]
@@ -179,6 +260,8 @@ func (wicked *Wicked) FindAvailableRoom(fromRoom string) *slick.Channel {
 	return wicked.bot.GetChannelByName(nextFree)
 }
 
+// NextMeetingID returns the first meeting ID not taken. Callers hold
+// the lock.
 func (wicked *Wicked) NextMeetingID() string {
 	for i := 1; i < 10000; i++ {
 		strID := fmt.Sprintf("%d", i)
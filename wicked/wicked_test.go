@@ -15,10 +15,7 @@ func TestFindNextRoom(t *testing.T) {
 	c3.Name = "room3"
 
 	w := &Wicked{
-		bot: &slick.Bot{Channels: map[string]slick.Channel{
-			"room2": c2,
-			"room3": c3,
-		}},
+		bot: &slick.Bot{Team: &slick.Team{Channels: slick.NewChannelDirectory(c2, c3)}},
 		meetings: map[string]*Meeting{
 			"room1": &Meeting{},
 		},
@@ -53,9 +50,7 @@ func TestFindNextRoomNilFromRoom(t *testing.T) {
 	c1.ID = "room1"
 	c1.Name = "room1"
 	w := &Wicked{
-		bot: &slick.Bot{Channels: map[string]slick.Channel{
-			"room1": c1,
-		}},
+		bot:       &slick.Bot{Team: &slick.Team{Channels: slick.NewChannelDirectory(c1)}},
 		meetings:  map[string]*Meeting{},
 		confRooms: []string{"room1"},
 	}
@@ -72,7 +67,7 @@ func TestFindNextRoomNilFromRoom(t *testing.T) {
 
 func TestFindNextRoomAllTake(t *testing.T) {
 	w := &Wicked{
-		bot: &slick.Bot{Channels: map[string]slick.Channel{}},
+		bot: &slick.Bot{Team: &slick.Team{Channels: slick.NewChannelDirectory()}},
 		meetings: map[string]*Meeting{
 			"room1": &Meeting{},
 		},